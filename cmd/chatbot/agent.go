@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"proyecto-mcp-bolsa/internal/llm"
+	"proyecto-mcp-bolsa/internal/mcp"
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// maxAgentTurns bounds how many rounds of tool calls the model may chain for a
+// single user message.
+const maxAgentTurns = 10
+
+// agentTool maps a Claude tool name back to the server that implements it.
+type agentTool struct {
+	serverName string
+	toolName   string
+	client     *mcp.Client
+}
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// claudeToolName prefixes the server name so tools with the same name on
+// different servers stay distinct, within Claude's naming rules.
+func claudeToolName(serverName, toolName string) string {
+	name := invalidToolNameChars.ReplaceAllString(serverName+"__"+toolName, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// buildToolCatalog turns every connected server's tools/list into Claude tool
+// definitions.
+func (c *ChatbotHost) buildToolCatalog(ctx context.Context) ([]llm.Tool, map[string]agentTool) {
+	serverNames := make([]string, 0, len(c.mcpClients))
+	for serverName := range c.mcpClients {
+		serverNames = append(serverNames, serverName)
+	}
+	sort.Strings(serverNames)
+
+	var tools []llm.Tool
+	routes := make(map[string]agentTool)
+
+	for _, serverName := range serverNames {
+		client := c.mcpClients[serverName]
+		serverTools, err := client.ListTools(ctx)
+		if err != nil {
+			c.logger.Printf("Skipping tools of %s: %v", serverName, err)
+			continue
+		}
+
+		for _, tool := range serverTools {
+			name := claudeToolName(serverName, tool.Name)
+			if _, taken := routes[name]; taken {
+				c.logger.Printf("Skipping duplicate tool name %s", name)
+				continue
+			}
+
+			schema := tool.InputSchema
+			if len(schema) == 0 {
+				schema = json.RawMessage(`{"type": "object"}`)
+			}
+
+			tools = append(tools, llm.Tool{
+				Name:        name,
+				Description: fmt.Sprintf("[%s] %s", serverName, tool.Description),
+				InputSchema: schema,
+			})
+			routes[name] = agentTool{serverName: serverName, toolName: tool.Name, client: client}
+		}
+	}
+
+	return tools, routes
+}
+
+// runAgent lets the model answer the conversation, executing the MCP tools it
+// asks for and feeding the results back until it stops calling tools.
+func (c *ChatbotHost) runAgent(ctx context.Context) error {
+	tools, routes := c.buildToolCatalog(ctx)
+
+	for turn := 0; turn < maxAgentTurns; turn++ {
+		response, err := c.streamReply(ctx, tools)
+		if err != nil {
+			return err
+		}
+
+		uses := response.ToolUses()
+		if response.StopReason != llm.StopToolUse || len(uses) == 0 {
+			// Calls cut short, e.g. by max_tokens, are dropped: every
+			// tool_use kept in the conversation needs a tool_result.
+			if blocks := withoutToolUses(response.Content); len(blocks) > 0 {
+				c.conversation = append(c.conversation, llm.Message{Role: "assistant", Blocks: blocks})
+			}
+			if len(uses) > 0 {
+				c.logger.Printf("Dropped %d tool calls of a reply that stopped with %s", len(uses), response.StopReason)
+			}
+			return nil
+		}
+
+		c.conversation = append(c.conversation, llm.Message{Role: "assistant", Blocks: response.Content})
+
+		results := make([]llm.ContentBlock, 0, len(uses))
+		for _, use := range uses {
+			results = append(results, c.executeToolUse(ctx, routes, use))
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+
+		c.conversation = append(c.conversation, llm.Message{Role: "user", Blocks: results})
+	}
+
+	fmt.Printf("Stopped after %d rounds of tool calls\n", maxAgentTurns)
+	return nil
+}
+
+func withoutToolUses(blocks []llm.ContentBlock) []llm.ContentBlock {
+	kept := make([]llm.ContentBlock, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type != "tool_use" {
+			kept = append(kept, block)
+		}
+	}
+	return kept
+}
+
+// streamReply prints the model's answer token by token and returns the complete
+// message once the stream ends.
+func (c *ChatbotHost) streamReply(ctx context.Context, tools []llm.Tool) (*llm.Response, error) {
+	events, err := c.provider.Stream(ctx, c.conversation, tools, c.chatOptions)
+	if err != nil {
+		return nil, fmt.Errorf("%s API error: %w", c.provider.Name(), err)
+	}
+
+	started := false
+	for event := range events {
+		switch {
+		case event.Text != "":
+			if !started {
+				fmt.Print("Assistant: ")
+				started = true
+			}
+			fmt.Print(event.Text)
+
+		case event.Err != nil:
+			if started {
+				fmt.Println()
+			}
+			return nil, fmt.Errorf("%s API error: %w", c.provider.Name(), event.Err)
+
+		case event.Response != nil:
+			if started {
+				fmt.Println()
+			}
+			if text := event.Response.Text(); text != "" {
+				c.logInteraction("ASSISTANT", text)
+			}
+			usage := event.Response.Usage
+			c.logger.Printf("Tokens: %d in, %d out", usage.InputTokens, usage.OutputTokens)
+			return event.Response, nil
+		}
+	}
+
+	return nil, ctx.Err()
+}
+
+func (c *ChatbotHost) executeToolUse(ctx context.Context, routes map[string]agentTool, use llm.ContentBlock) llm.ContentBlock {
+	route, exists := routes[use.Name]
+	if !exists {
+		return llm.ToolResultBlock(use.ID, fmt.Sprintf("unknown tool: %s", use.Name), true)
+	}
+
+	var args map[string]interface{}
+	if len(use.Input) > 0 {
+		if err := json.Unmarshal(use.Input, &args); err != nil {
+			return llm.ToolResultBlock(use.ID, fmt.Sprintf("invalid tool input: %v", err), true)
+		}
+	}
+
+	fmt.Printf("🔧 %s.%s %s\n", route.serverName, route.toolName, string(use.Input))
+	c.logMCPInteraction("TOOL_CALL", route.toolName, string(use.Input))
+
+	result, err := route.client.CallTool(ctx, route.toolName, args)
+	if err != nil {
+		c.logMCPInteraction("TOOL_ERROR", route.toolName, err.Error())
+		return llm.ToolResultBlock(use.ID, err.Error(), true)
+	}
+
+	text := toolResultText(result)
+	c.logMCPInteraction("TOOL_RESPONSE", route.toolName, fmt.Sprintf("%d bytes", len(text)))
+	return llm.ToolResultBlock(use.ID, text, result.IsError)
+}
+
+func toolResultText(result *models.CallToolResponse) string {
+	parts := make([]string, 0, len(result.Content))
+	for _, content := range result.Content {
+		switch {
+		case content.Text != "":
+			parts = append(parts, content.Text)
+		case content.Resource != nil:
+			parts = append(parts, content.Resource.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/internal/llm"
+	"proyecto-mcp-bolsa/internal/mcp"
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// newAgentHost returns a host whose "stocks" server offers a quote tool and a
+// tool that always fails, with provider answering for the model.
+func newAgentHost(t *testing.T, provider llm.Provider) *ChatbotHost {
+	t.Helper()
+
+	server := mcp.NewServer("stocks", "test")
+	server.RegisterTool("quote", "Quote a symbol", nil, mcp.ToolHandlerFunc(func(ctx context.Context, args map[string]interface{}) (*models.CallToolResponse, error) {
+		return &models.CallToolResponse{Content: []models.Content{{Type: "text", Text: fmt.Sprintf("%v is at 190", args["symbol"])}}}, nil
+	}))
+	server.RegisterTool("broken", "Always fails", nil, mcp.ToolHandlerFunc(func(ctx context.Context, args map[string]interface{}) (*models.CallToolResponse, error) {
+		return nil, errors.New("upstream unavailable")
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				server.HandleRequest(context.Background(), conn, conn)
+			}()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := mcp.NewClient(nil, log.New(io.Discard, "", 0))
+	if err := client.ConnectTCP(ctx, listener.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	host := NewChatbotHost()
+	host.logger = log.New(io.Discard, "", 0)
+	host.provider = provider
+	host.mcpClients["stocks"] = client
+	host.conversation = []llm.Message{{Role: "user", Content: "How is AAPL doing?"}}
+	return host
+}
+
+func toolUse(id, name, input string) llm.Response {
+	return llm.Response{
+		Content:    []llm.ContentBlock{{Type: "tool_use", ID: id, Name: name, Input: json.RawMessage(input)}},
+		StopReason: llm.StopToolUse,
+	}
+}
+
+func textReply(text string) llm.Response {
+	return llm.Response{Content: []llm.ContentBlock{llm.TextBlock(text)}, StopReason: llm.StopEndTurn}
+}
+
+// lastToolResults returns the tool_result blocks of the last request sent to
+// the model.
+func lastToolResults(t *testing.T, requests []llm.MockRequest) []llm.ContentBlock {
+	t.Helper()
+	messages := requests[len(requests)-1].Messages
+	last := messages[len(messages)-1]
+	if last.Role != "user" {
+		t.Fatalf("last message role = %q, want user", last.Role)
+	}
+	return last.Blocks
+}
+
+func runAgent(t *testing.T, host *ChatbotHost) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return host.runAgent(ctx)
+}
+
+func TestRunAgentToolRoundTrip(t *testing.T) {
+	provider := llm.NewMockProvider([]llm.Response{
+		toolUse("t1", "stocks__quote", `{"symbol":"AAPL"}`),
+		textReply("AAPL is trading at $190."),
+	})
+	host := newAgentHost(t, provider)
+
+	if err := runAgent(t, host); err != nil {
+		t.Fatalf("runAgent: %v", err)
+	}
+
+	requests := provider.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("model was asked %d times, want 2", len(requests))
+	}
+	var offered []string
+	for _, tool := range requests[0].Tools {
+		offered = append(offered, tool.Name)
+	}
+	if strings.Join(offered, ",") != "stocks__quote,stocks__broken" {
+		t.Errorf("offered tools = %v", offered)
+	}
+
+	results := lastToolResults(t, requests)
+	if len(results) != 1 || results[0].ToolUseID != "t1" || results[0].Content != "AAPL is at 190" || results[0].IsError {
+		t.Errorf("tool results = %+v", results)
+	}
+
+	final := host.conversation[len(host.conversation)-1]
+	if final.Role != "assistant" || len(final.Blocks) != 1 || final.Blocks[0].Text != "AAPL is trading at $190." {
+		t.Errorf("final message = %+v", final)
+	}
+}
+
+func TestRunAgentReturnsToolErrorsToModel(t *testing.T) {
+	provider := llm.NewMockProvider([]llm.Response{
+		{
+			Content: []llm.ContentBlock{
+				{Type: "tool_use", ID: "t1", Name: "stocks__broken", Input: json.RawMessage(`{}`)},
+				{Type: "tool_use", ID: "t2", Name: "stocks__missing", Input: json.RawMessage(`{}`)},
+			},
+			StopReason: llm.StopToolUse,
+		},
+		textReply("The data is unavailable right now."),
+	})
+	host := newAgentHost(t, provider)
+
+	if err := runAgent(t, host); err != nil {
+		t.Fatalf("runAgent: %v", err)
+	}
+
+	results := lastToolResults(t, provider.Requests())
+	if len(results) != 2 {
+		t.Fatalf("tool results = %+v, want 2", results)
+	}
+	if !results[0].IsError || !strings.Contains(results[0].Content, "upstream unavailable") {
+		t.Errorf("failing tool result = %+v", results[0])
+	}
+	if !results[1].IsError || !strings.Contains(results[1].Content, "unknown tool") {
+		t.Errorf("unknown tool result = %+v", results[1])
+	}
+}
+
+func TestRunAgentDropsToolCallsCutShort(t *testing.T) {
+	provider := llm.NewMockProvider([]llm.Response{
+		{
+			Content: []llm.ContentBlock{
+				llm.TextBlock("Let me look that up."),
+				{Type: "tool_use", ID: "t1", Name: "stocks__quote", Input: json.RawMessage(`{"sym`)},
+			},
+			StopReason: llm.StopMaxTokens,
+		},
+	})
+	host := newAgentHost(t, provider)
+
+	if err := runAgent(t, host); err != nil {
+		t.Fatalf("runAgent: %v", err)
+	}
+
+	if got := len(provider.Requests()); got != 1 {
+		t.Errorf("model was asked %d times, want 1", got)
+	}
+	final := host.conversation[len(host.conversation)-1]
+	if final.Role != "assistant" || len(final.Blocks) != 1 || final.Blocks[0].Type != "text" {
+		t.Errorf("final message = %+v, want only the text", final)
+	}
+}
+
+func TestRunAgentStopsAfterMaxTurns(t *testing.T) {
+	script := make([]llm.Response, maxAgentTurns+1)
+	for i := range script {
+		script[i] = toolUse(fmt.Sprintf("t%d", i), "stocks__quote", `{"symbol":"AAPL"}`)
+	}
+	provider := llm.NewMockProvider(script)
+	host := newAgentHost(t, provider)
+
+	if err := runAgent(t, host); err != nil {
+		t.Fatalf("runAgent: %v", err)
+	}
+
+	if got := len(provider.Requests()); got != maxAgentTurns {
+		t.Errorf("model was asked %d times, want %d", got, maxAgentTurns)
+	}
+	// The question, then an assistant turn and its tool results per round.
+	if got, want := len(host.conversation), 1+2*maxAgentTurns; got != want {
+		t.Errorf("conversation has %d messages, want %d", got, want)
+	}
+}
+
+func TestRunAgentPropagatesProviderErrors(t *testing.T) {
+	// The script runs out after the tool call, so the follow-up request fails.
+	provider := llm.NewMockProvider([]llm.Response{
+		toolUse("t1", "stocks__quote", `{"symbol":"AAPL"}`),
+	})
+	host := newAgentHost(t, provider)
+
+	err := runAgent(t, host)
+	if err == nil || !strings.Contains(err.Error(), "mock API error") || !strings.Contains(err.Error(), "exhausted") {
+		t.Fatalf("runAgent error = %v, want the provider error", err)
+	}
+	if got := len(provider.Requests()); got != 2 {
+		t.Errorf("model was asked %d times, want 2", got)
+	}
+}
+
+func TestRunAgentStopsWhenCancelled(t *testing.T) {
+	provider := llm.NewMockProvider([]llm.Response{textReply("never sent")})
+	host := newAgentHost(t, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := host.runAgent(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("runAgent error = %v, want context.Canceled", err)
+	}
+}
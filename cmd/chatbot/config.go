@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// applyFlags layers the command-line flags that were given explicitly over
+// the loaded configuration.
+func applyFlags(cfg *models.Config) error {
+	set := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+
+	if value, ok := set["provider"]; ok {
+		cfg.Chatbot.Provider = value
+	}
+	if value, ok := set["mock-script"]; ok {
+		cfg.Chatbot.MockScript = value
+	}
+	if value, ok := set["system"]; ok {
+		cfg.Chatbot.SystemPrompt = value
+	}
+	if value, ok := set["max-tokens"]; ok {
+		maxTokens, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid -max-tokens: %w", err)
+		}
+		cfg.Chatbot.MaxTokens = maxTokens
+	}
+	if value, ok := set["temperature"]; ok {
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid -temperature: %w", err)
+		}
+		cfg.Chatbot.Temperature = &temperature
+	}
+
+	// -model and -llm-url apply to whichever provider ends up selected.
+	model, url := &cfg.Claude.Model, &cfg.Claude.BaseURL
+	if cfg.Chatbot.Provider == "openai" {
+		model, url = &cfg.OpenAI.Model, &cfg.OpenAI.BaseURL
+	}
+	if value, ok := set["model"]; ok {
+		*model = value
+	}
+	if value, ok := set["llm-url"]; ok {
+		*url = value
+	}
+	return nil
+}
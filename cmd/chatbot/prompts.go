@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"proyecto-mcp-bolsa/internal/llm"
+	"proyecto-mcp-bolsa/internal/mcp"
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+func (c *ChatbotHost) listAvailablePrompts(ctx context.Context) error {
+	if len(c.mcpClients) == 0 {
+		fmt.Println("No MCP servers connected")
+		return nil
+	}
+
+	fmt.Println("Available Prompts:")
+	for serverName, client := range c.mcpClients {
+		prompts, err := client.ListPrompts(ctx)
+		if err != nil {
+			fmt.Printf("\n%s: prompts not available (%v)\n", serverName, err)
+			continue
+		}
+
+		fmt.Printf("\n%s:\n", serverName)
+		for _, prompt := range prompts {
+			names := make([]string, len(prompt.Arguments))
+			for i, arg := range prompt.Arguments {
+				names[i] = arg.Name
+				if !arg.Required {
+					names[i] = "[" + arg.Name + "]"
+				}
+			}
+			fmt.Printf("  • %s %s: %s\n", prompt.Name, strings.Join(names, " "), prompt.Description)
+		}
+	}
+
+	return nil
+}
+
+// runPrompt fetches a server prompt and sends its messages to the model as the
+// next conversation turn. Arguments are given as name=value or positionally
+// in the order the prompt declares them.
+func (c *ChatbotHost) runPrompt(ctx context.Context, name string, rawArgs []string) error {
+	client, prompt, err := c.findPrompt(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	args, err := promptArguments(prompt, rawArgs)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.GetPrompt(ctx, name, args)
+	if err != nil {
+		return err
+	}
+	c.logMCPInteraction("GET_PROMPT", name, fmt.Sprintf("%v", args))
+
+	mark := len(c.conversation)
+	for _, message := range result.Messages {
+		c.appendConversation(message.Role, promptContentText(message.Content))
+	}
+
+	fmt.Printf("📝 %s\n", result.Description)
+	if err := c.runAgent(ctx); err != nil {
+		c.conversation = c.conversation[:mark]
+		return err
+	}
+	return nil
+}
+
+func (c *ChatbotHost) findPrompt(ctx context.Context, name string) (*mcp.Client, models.Prompt, error) {
+	for _, client := range c.mcpClients {
+		prompts, err := client.ListPrompts(ctx)
+		if err != nil {
+			continue
+		}
+		for _, prompt := range prompts {
+			if prompt.Name == name {
+				return client, prompt, nil
+			}
+		}
+	}
+	return nil, models.Prompt{}, fmt.Errorf("no connected server offers prompt %s (see /prompts)", name)
+}
+
+func promptArguments(prompt models.Prompt, rawArgs []string) (map[string]string, error) {
+	args := make(map[string]string)
+	position := 0
+
+	for _, raw := range rawArgs {
+		if key, value, named := strings.Cut(raw, "="); named {
+			args[key] = value
+			continue
+		}
+
+		if position >= len(prompt.Arguments) {
+			return nil, fmt.Errorf("too many arguments for prompt %s", prompt.Name)
+		}
+		args[prompt.Arguments[position].Name] = raw
+		position++
+	}
+
+	return args, nil
+}
+
+// promptContentText flattens prompt content into the plain text messages the
+// providers send.
+func promptContentText(content models.Content) string {
+	if content.Type == "resource" && content.Resource != nil {
+		return fmt.Sprintf("Resource %s (%s):\n%s", content.Resource.URI, content.Resource.MimeType, content.Resource.Text)
+	}
+	return content.Text
+}
+
+// appendConversation adds a text message, merging it into the previous one
+// when the role repeats so turns keep alternating.
+func (c *ChatbotHost) appendConversation(role, content string) {
+	if last := len(c.conversation) - 1; last >= 0 && c.conversation[last].Role == role && len(c.conversation[last].Blocks) == 0 {
+		c.conversation[last].Content += "\n\n" + content
+		return
+	}
+	c.conversation = append(c.conversation, llm.Message{Role: role, Content: content})
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"proyecto-mcp-bolsa/internal/llm"
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// newProvider builds the language model backend selected by
+// chatbot.provider.
+func newProvider(cfg *models.Config, logger *log.Logger) (llm.Provider, error) {
+	switch cfg.Chatbot.Provider {
+	case "claude":
+		if cfg.Claude.APIKey == "" {
+			logger.Println("Warning: ANTHROPIC_API_KEY not set, Claude integration will not work")
+		}
+		return llm.NewClaudeClient(cfg.Claude.APIKey, cfg.Claude.BaseURL, cfg.Claude.Model), nil
+
+	case "openai":
+		return llm.NewOpenAIClient(cfg.OpenAI.APIKey, cfg.OpenAI.BaseURL, cfg.OpenAI.Model), nil
+
+	case "mock":
+		return llm.LoadMockProvider(cfg.Chatbot.MockScript)
+
+	default:
+		return nil, fmt.Errorf("unknown provider %q (use claude, openai or mock)", cfg.Chatbot.Provider)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"proyecto-mcp-bolsa/internal/config"
+	"proyecto-mcp-bolsa/internal/mcp"
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// connect resolves target against the mcpServers registry first; anything
+// else is treated as an ad-hoc server path, .go file or tcp://, tls://,
+// http(s):// or ws(s):// URL.
+func (c *ChatbotHost) connect(ctx context.Context, target string) error {
+	if server, ok := c.servers[target]; ok {
+		return c.connectServer(ctx, server)
+	}
+
+	server := models.MCPServerConfig{
+		Name:      filepath.Base(target),
+		Transport: "stdio",
+		Command:   target,
+	}
+	switch {
+	case strings.HasPrefix(target, "tcp://"):
+		server.Transport = "tcp"
+	case strings.HasPrefix(target, "tls://"):
+		server.Transport = "tls"
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		server.Transport = "http"
+	case strings.HasPrefix(target, "ws://"), strings.HasPrefix(target, "wss://"):
+		server.Transport = "websocket"
+	case strings.HasSuffix(target, ".go"):
+		server.Command = "go"
+		server.Args = []string{"run", target}
+	}
+
+	if server.Transport != "stdio" {
+		server.Command = ""
+		server.URL = target
+		if u, err := url.Parse(target); err == nil && u.Host != "" {
+			server.Name = u.Host
+		}
+	}
+	return c.connectServer(ctx, server)
+}
+
+func (c *ChatbotHost) connectServer(ctx context.Context, server models.MCPServerConfig) error {
+	if _, exists := c.mcpClients[server.Name]; exists {
+		fmt.Printf("Already connected to %s\n", server.Name)
+		return nil
+	}
+
+	client, err := c.dialServer(ctx, server)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", server.Name, err)
+	}
+
+	initResponse, err := client.Initialize(ctx)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("failed to initialize %s: %w", server.Name, err)
+	}
+
+	c.addClient(server.Name, client)
+	c.logMCPInteraction("CONNECT", server.Name, fmt.Sprintf("Connected to %s v%s over %s", initResponse.ServerInfo.Name, initResponse.ServerInfo.Version, server.Transport))
+
+	fmt.Printf("✅ Connected to %s as %s\n", initResponse.ServerInfo.Name, server.Name)
+	return nil
+}
+
+// dialServer starts or reaches the server with the transport of its entry.
+func (c *ChatbotHost) dialServer(ctx context.Context, server models.MCPServerConfig) (*mcp.Client, error) {
+	if server.Transport == "stdio" || server.Transport == "" {
+		client := mcp.NewClient(append([]string{server.Command}, server.Args...), c.logger)
+		client.SetProcessEnv(server.Env)
+		client.SetWorkingDir(server.Cwd)
+		if err := client.Connect(ctx); err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+
+	client := mcp.NewClient(nil, c.logger) // nil command for network connections
+	client.SetAuthToken(server.Token)
+
+	var err error
+	switch server.Transport {
+	case "tcp":
+		err = client.ConnectTCP(ctx, strings.TrimPrefix(server.URL, "tcp://"))
+
+	case "tls":
+		var tlsConfig *tls.Config
+		tlsConfig, err = mcp.ClientTLSOptions{
+			CAFile:     server.TLS.CAFile,
+			CertFile:   server.TLS.CertFile,
+			KeyFile:    server.TLS.KeyFile,
+			ServerName: server.TLS.ServerName,
+			Pins:       server.TLS.Pins,
+		}.Config()
+		if err == nil {
+			err = client.ConnectTLS(ctx, strings.TrimPrefix(server.URL, "tls://"), tlsConfig)
+		}
+
+	case "http":
+		err = client.ConnectHTTP(ctx, server.URL)
+
+	case "websocket":
+		err = client.ConnectWebSocket(ctx, server.URL)
+
+	default:
+		err = fmt.Errorf("transport %q is not supported", server.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// autoConnect connects to every registry entry marked autoConnect, reporting
+// but not stopping on failures.
+func (c *ChatbotHost) autoConnect(ctx context.Context) {
+	for _, name := range config.ServerNames(c.servers) {
+		server := c.servers[name]
+		if !server.AutoConnect {
+			continue
+		}
+
+		if server.Transport == "stdio" && strings.Contains(server.Command, "/") {
+			path := server.Command
+			if server.Cwd != "" && !filepath.IsAbs(path) {
+				path = filepath.Join(server.Cwd, path)
+			}
+			if _, err := os.Stat(path); err != nil {
+				fmt.Printf("MCP server %s not found at %s\n", name, server.Command)
+				if server.Command == "./bin/stock-analyzer" {
+					fmt.Println("Run: go build -o bin/stock-analyzer ./servers/stock-analyzer/")
+				}
+				continue
+			}
+		}
+
+		fmt.Printf("Auto-connecting to %s...\n", name)
+		if err := c.connectServer(ctx, server); err != nil {
+			c.logger.Printf("Auto-connect to %s failed: %v", name, err)
+			fmt.Printf("Auto-connection failed: %v\n", err)
+			fmt.Printf("Use /connect %s to connect manually\n", name)
+		}
+	}
+}
+
+func (c *ChatbotHost) showConfiguredServers() {
+	if len(c.servers) == 0 {
+		fmt.Println("No servers configured in mcpServers")
+		return
+	}
+
+	fmt.Println("Configured servers (/connect <name>):")
+	for _, name := range config.ServerNames(c.servers) {
+		server := c.servers[name]
+		target := strings.TrimSpace(server.Command + " " + strings.Join(server.Args, " "))
+		if server.Transport != "stdio" {
+			target = server.URL
+		}
+		auto := ""
+		if server.AutoConnect {
+			auto = " (auto)"
+		}
+		fmt.Printf("  %-16s %-5s %s%s\n", name, server.Transport, target, auto)
+	}
+}
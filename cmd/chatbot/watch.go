@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"proyecto-mcp-bolsa/internal/mcp"
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+func quoteURI(symbol string) string {
+	return fmt.Sprintf("stock://%s/quote", strings.ToUpper(strings.TrimSpace(symbol)))
+}
+
+// watchSymbols subscribes to the quote resource of each symbol. Updates are
+// printed by onResourceUpdated as the server reports them.
+func (c *ChatbotHost) watchSymbols(ctx context.Context, symbols []string) error {
+	client := c.getStockAnalyzerClient()
+	if client == nil {
+		fmt.Println("No stock analyzer server connected. Use /connect ./bin/stock-analyzer first.")
+		return nil
+	}
+
+	for _, symbol := range symbols {
+		uri := quoteURI(symbol)
+		if err := client.SubscribeResource(ctx, uri); err != nil {
+			fmt.Printf("❌ Could not watch %s: %v\n", symbol, err)
+			continue
+		}
+
+		c.logMCPInteraction("SUBSCRIBE", "stock-analyzer", uri)
+		fmt.Printf("👀 Watching %s\n", strings.ToUpper(symbol))
+		go c.printQuote(client, uri, "")
+	}
+
+	return nil
+}
+
+func (c *ChatbotHost) unwatchSymbols(ctx context.Context, symbols []string) error {
+	client := c.getStockAnalyzerClient()
+	if client == nil {
+		fmt.Println("No stock analyzer server connected")
+		return nil
+	}
+
+	for _, symbol := range symbols {
+		uri := quoteURI(symbol)
+		if err := client.UnsubscribeResource(ctx, uri); err != nil {
+			fmt.Printf("❌ Could not stop watching %s: %v\n", symbol, err)
+			continue
+		}
+
+		c.logMCPInteraction("UNSUBSCRIBE", "stock-analyzer", uri)
+		fmt.Printf("Stopped watching %s\n", strings.ToUpper(symbol))
+	}
+
+	return nil
+}
+
+// onResourceUpdated runs on the client's reader goroutine, so the resource is
+// fetched from a separate goroutine to avoid waiting on our own reader.
+func (c *ChatbotHost) onResourceUpdated(serverName string, client *mcp.Client) mcp.NotificationHandler {
+	return func(method string, params json.RawMessage) {
+		var notification models.ResourceUpdatedNotification
+		if err := json.Unmarshal(params, &notification); err != nil {
+			c.logger.Printf("Ignoring malformed resource update from %s: %v", serverName, err)
+			return
+		}
+
+		go c.printQuote(client, notification.URI, "🔔 ")
+	}
+}
+
+func (c *ChatbotHost) printQuote(client *mcp.Client, uri, prefix string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	contents, err := client.ReadResource(ctx, uri)
+	if err != nil {
+		fmt.Printf("\r\033[K%s%s: %v\n", prefix, uri, err)
+		return
+	}
+
+	for _, content := range contents {
+		var quote models.Stock
+		if err := json.Unmarshal([]byte(content.Text), &quote); err != nil || quote.Symbol == "" {
+			fmt.Printf("\r\033[K%s%s updated\n", prefix, uri)
+			continue
+		}
+
+		fmt.Printf("\r\033[K%s%s $%.2f (%+.2f, %+.2f%%) at %s\n", prefix, quote.Symbol, quote.Price, quote.Change, quote.ChangePerc, time.Now().Format("15:04:05"))
+	}
+}
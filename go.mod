@@ -1,3 +1,11 @@
 module proyecto-mcp-bolsa
 
 go 1.21
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	go.etcd.io/bbolt v1.3.10
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.10.0 // indirect
@@ -0,0 +1,501 @@
+// Package config loads config.yaml into models.Config. Values are layered:
+// built-in defaults, then the file, then environment variables; binaries
+// apply their command-line flags on top and call ValidateServer or
+// ValidateChatbot for the settings they use.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// DefaultPath is read when -config is not given; unlike an explicit path it
+// may be missing.
+const DefaultPath = "config.yaml"
+
+// Default returns the configuration used when nothing else is set.
+func Default() *models.Config {
+	return &models.Config{
+		Server: models.ServerConfig{
+			Host:            "localhost",
+			Port:            8080,
+			Transport:       "stdio",
+			WatchInterval:   time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+			Limits: models.LimitsConfig{
+				MaxConnections:    32,
+				RequestsPerMinute: 120,
+			},
+		},
+		APIs: models.APIConfig{
+			Providers: []string{"alphaVantage"},
+			AlphaVantage: models.AlphaVantageConfig{
+				BaseURL: "https://www.alphavantage.co/query",
+				// The free tier allows 5 calls a minute.
+				RequestsPerMinute: 5,
+				Burst:             5,
+				MaxRetries:        2,
+				RetryBackoff:      15 * time.Second,
+			},
+			TwelveData: models.TwelveDataConfig{
+				BaseURL: "https://api.twelvedata.com",
+			},
+			Cache: models.CacheConfig{
+				Enabled:     true,
+				Path:        ".cache/market-data.db",
+				QuoteTTL:    30 * time.Second,
+				IntradayTTL: time.Minute,
+				SearchTTL:   24 * time.Hour,
+				OverviewTTL: 24 * time.Hour,
+				MaxStale:    15 * time.Minute,
+			},
+		},
+		Claude: models.ClaudeConfig{
+			BaseURL: "https://api.anthropic.com/v1/messages",
+			Model:   "claude-3-haiku-20240307",
+		},
+		OpenAI: models.OpenAIConfig{
+			BaseURL: "https://api.openai.com/v1",
+		},
+		Chatbot: models.ChatbotConfig{
+			Provider:  "claude",
+			MaxTokens: 4000,
+		},
+		MCPServers: map[string]models.MCPServerConfig{
+			"stock-analyzer": {
+				Command:     "./bin/stock-analyzer",
+				AutoConnect: true,
+			},
+			"filesystem": {
+				Command: "./scripts/start-filesystem-mcp.sh",
+			},
+			"git": {
+				Command: "./scripts/start-git-mcp.sh",
+			},
+		},
+	}
+}
+
+// Load returns the defaults overlaid with the file at path and the
+// environment. A missing DefaultPath is not an error.
+func Load(path string) (*models.Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := parse(data, cfg); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+		case errors.Is(err, os.ErrNotExist) && path == DefaultPath:
+		default:
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	for name, server := range cfg.MCPServers {
+		server.Name = name
+		if server.Transport == "" {
+			server.Transport = "stdio"
+		}
+		cfg.MCPServers[name] = server
+	}
+	return cfg, nil
+}
+
+func parse(data []byte, cfg *models.Config) error {
+	// A file that declares mcpServers replaces the built-in registry rather
+	// than merging into it.
+	defaults := cfg.MCPServers
+	cfg.MCPServers = nil
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return err
+	}
+	if cfg.MCPServers == nil {
+		cfg.MCPServers = defaults
+	}
+
+	// Expanding the parsed strings rather than the raw file keeps values
+	// with YAML syntax in them, such as tokens with "#" or ": ", intact.
+	return expandEnvFields(reflect.ValueOf(cfg).Elem(), "")
+}
+
+// expandEnvFields runs ExpandEnv on every string reachable from v, naming
+// the setting in errors by its YAML path.
+func expandEnvFields(v reflect.Value, setting string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return expandEnvFields(v.Elem(), setting)
+		}
+	case reflect.String:
+		expanded, err := ExpandEnv(v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", setting, err)
+		}
+		v.SetString(expanded)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				name = field.Name
+			}
+			if err := expandEnvFields(v.Field(i), joinPath(setting, name)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := expandEnvFields(v.Index(i), fmt.Sprintf("%s[%d]", setting, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map values are not addressable; expand a copy and store it back.
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			if err := expandEnvFields(value, joinPath(setting, fmt.Sprint(iter.Key()))); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+	return nil
+}
+
+func joinPath(setting, name string) string {
+	if setting == "" {
+		return name
+	}
+	return setting + "." + name
+}
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces ${VAR} and ${VAR:-default} in a setting with
+// environment values. An unset ${VAR} without a default expands to the empty
+// string; a lone "$" is left alone so values such as passwords survive.
+func ExpandEnv(s string) (string, error) {
+	if rest := envPattern.ReplaceAllString(s, ""); strings.Contains(rest, "${") {
+		return "", fmt.Errorf("invalid variable reference %q", rest[strings.Index(rest, "${"):])
+	}
+
+	return envPattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := envPattern.FindStringSubmatch(match)
+		if value := os.Getenv(groups[1]); value != "" {
+			return value
+		}
+		return groups[3]
+	}), nil
+}
+
+// envOverrides maps environment variables onto string settings.
+var envOverrides = []struct {
+	name  string
+	field func(*models.Config) *string
+}{
+	{"ALPHA_VANTAGE_API_KEY", func(c *models.Config) *string { return &c.APIs.AlphaVantage.APIKey }},
+	{"ALPHA_VANTAGE_BASE_URL", func(c *models.Config) *string { return &c.APIs.AlphaVantage.BaseURL }},
+	{"TWELVE_DATA_API_KEY", func(c *models.Config) *string { return &c.APIs.TwelveData.APIKey }},
+	{"TWELVE_DATA_BASE_URL", func(c *models.Config) *string { return &c.APIs.TwelveData.BaseURL }},
+	{"MARKET_DATA_SOURCE", func(c *models.Config) *string { return &c.APIs.DataSource }},
+	{"MARKET_DATA_CACHE_PATH", func(c *models.Config) *string { return &c.APIs.Cache.Path }},
+	{"MARKET_DATA_CSV_DIR", func(c *models.Config) *string { return &c.APIs.CSV.Dir }},
+	{"ANTHROPIC_API_KEY", func(c *models.Config) *string { return &c.Claude.APIKey }},
+	{"ANTHROPIC_BASE_URL", func(c *models.Config) *string { return &c.Claude.BaseURL }},
+	{"CLAUDE_MODEL", func(c *models.Config) *string { return &c.Claude.Model }},
+	{"OPENAI_API_KEY", func(c *models.Config) *string { return &c.OpenAI.APIKey }},
+	{"OPENAI_BASE_URL", func(c *models.Config) *string { return &c.OpenAI.BaseURL }},
+	{"OPENAI_MODEL", func(c *models.Config) *string { return &c.OpenAI.Model }},
+	{"MCP_SERVER_HOST", func(c *models.Config) *string { return &c.Server.Host }},
+	{"MCP_SERVER_TRANSPORT", func(c *models.Config) *string { return &c.Server.Transport }},
+	{"MCP_TLS_CERT_FILE", func(c *models.Config) *string { return &c.Server.TLS.CertFile }},
+	{"MCP_TLS_KEY_FILE", func(c *models.Config) *string { return &c.Server.TLS.KeyFile }},
+	{"MCP_TLS_CLIENT_CA_FILE", func(c *models.Config) *string { return &c.Server.TLS.ClientCAFile }},
+	{"CHATBOT_PROVIDER", func(c *models.Config) *string { return &c.Chatbot.Provider }},
+}
+
+func applyEnv(cfg *models.Config) error {
+	for _, override := range envOverrides {
+		if value := os.Getenv(override.name); value != "" {
+			*override.field(cfg) = value
+		}
+	}
+
+	if value := os.Getenv("MARKET_DATA_PROVIDERS"); value != "" {
+		cfg.APIs.Providers = SplitList(value)
+	}
+
+	if value := os.Getenv("MCP_SERVER_PORT"); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid MCP_SERVER_PORT %q: must be a number", value)
+		}
+		cfg.Server.Port = port
+	}
+	return nil
+}
+
+// ValidateServer checks the settings the stock-analyzer server uses: server
+// and apis. It reports every invalid one at once so a broken config can be
+// fixed in a single pass.
+func ValidateServer(cfg *models.Config) error {
+	var p problems
+
+	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
+		p.addf("server.port must be between 1 and 65535, got %d", cfg.Server.Port)
+	}
+	if !containsString([]string{"stdio", "tcp", "tls", "http"}, cfg.Server.Transport) {
+		p.addf("server.transport must be stdio, tcp, tls or http, got %q", cfg.Server.Transport)
+	}
+	if cfg.Server.Transport == "tls" && (cfg.Server.TLS.CertFile == "" || cfg.Server.TLS.KeyFile == "") {
+		p.addf("server.tls.certFile and server.tls.keyFile are required for the tls transport")
+	}
+	seenTokens := make(map[string]bool)
+	for i, token := range cfg.Server.Auth.Tokens {
+		if token.Name == "" {
+			p.addf("server.auth.tokens[%d].name is required", i)
+		}
+		if token.Token == "" {
+			p.addf("server.auth.tokens[%d].token is empty (is its environment variable set?)", i)
+		} else if seenTokens[token.Token] {
+			p.addf("server.auth.tokens[%d] reuses the token of another entry", i)
+		}
+		seenTokens[token.Token] = true
+		for _, pattern := range token.Resources {
+			if _, err := path.Match(pattern, ""); err != nil {
+				p.addf("server.auth.tokens[%d].resources has a bad pattern %q", i, pattern)
+			}
+		}
+	}
+	limits := cfg.Server.Limits
+	if limits.MaxConnections < 0 || limits.RequestsPerMinute < 0 || limits.Burst < 0 {
+		p.addf("server.limits values must not be negative")
+	}
+	for tool, quota := range limits.ToolQuotas {
+		if quota.Calls <= 0 || quota.Per <= 0 {
+			p.addf("server.limits.toolQuotas.%s needs positive calls and per, got %d per %s", tool, quota.Calls, quota.Per)
+		}
+	}
+	if cfg.Server.WatchInterval <= 0 {
+		p.addf("server.watchInterval must be positive, got %s", cfg.Server.WatchInterval)
+	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		p.addf("server.shutdownTimeout must be positive, got %s", cfg.Server.ShutdownTimeout)
+	}
+	if err := checkURL(cfg.APIs.AlphaVantage.BaseURL); err != nil {
+		p.addf("apis.alphaVantage.baseURL: %v", err)
+	}
+	alphaVantage := cfg.APIs.AlphaVantage
+	if alphaVantage.RequestsPerMinute < 0 || alphaVantage.Burst < 0 || alphaVantage.MaxRetries < 0 || alphaVantage.RetryBackoff < 0 {
+		p.addf("apis.alphaVantage request limits must not be negative")
+	}
+	if _, err := ParseDataSource(cfg.APIs.DataSource); err != nil {
+		p.addf("apis.dataSource: %v", err)
+	}
+	cache := cfg.APIs.Cache
+	if cache.QuoteTTL < 0 || cache.IntradayTTL < 0 || cache.SearchTTL < 0 || cache.OverviewTTL < 0 || cache.MaxStale < 0 {
+		p.addf("apis.cache durations must not be negative")
+	}
+	if len(cfg.APIs.Providers) == 0 {
+		p.addf("apis.providers must list at least one of alphaVantage, twelveData or csv")
+	}
+	seenProviders := make(map[string]bool)
+	for _, provider := range cfg.APIs.Providers {
+		switch {
+		case seenProviders[provider]:
+			p.addf("apis.providers lists %s twice", provider)
+		case provider == "twelveData":
+			if err := checkURL(cfg.APIs.TwelveData.BaseURL); err != nil {
+				p.addf("apis.twelveData.baseURL: %v", err)
+			}
+		case provider == "csv":
+			if cfg.APIs.CSV.Dir == "" {
+				p.addf("apis.csv.dir is required for the csv provider")
+			}
+		case provider != "alphaVantage":
+			p.addf("apis.providers: unknown provider %q (use alphaVantage, twelveData or csv)", provider)
+		}
+		seenProviders[provider] = true
+	}
+
+	return p.err()
+}
+
+// ValidateChatbot is ValidateServer for the chatbot's settings: claude,
+// openai, chatbot and mcpServers.
+func ValidateChatbot(cfg *models.Config) error {
+	var p problems
+
+	switch cfg.Chatbot.Provider {
+	case "claude":
+		if err := checkURL(cfg.Claude.BaseURL); err != nil {
+			p.addf("claude.baseURL: %v", err)
+		}
+		if cfg.Claude.Model == "" {
+			p.addf("claude.model is required")
+		}
+	case "openai":
+		if err := checkURL(cfg.OpenAI.BaseURL); err != nil {
+			p.addf("openai.baseURL: %v", err)
+		}
+		if cfg.OpenAI.Model == "" {
+			p.addf("openai.model is required for the openai provider (e.g. gpt-4o-mini or llama3.1)")
+		}
+	case "mock":
+		if cfg.Chatbot.MockScript == "" {
+			p.addf("chatbot.mockScript is required for the mock provider")
+		}
+	default:
+		p.addf("chatbot.provider must be claude, openai or mock, got %q", cfg.Chatbot.Provider)
+	}
+
+	if cfg.Chatbot.MaxTokens <= 0 {
+		p.addf("chatbot.maxTokens must be positive, got %d", cfg.Chatbot.MaxTokens)
+	}
+	if t := cfg.Chatbot.Temperature; t != nil && (*t < 0 || *t > 2) {
+		p.addf("chatbot.temperature must be between 0 and 2, got %g", *t)
+	}
+	for _, name := range ServerNames(cfg.MCPServers) {
+		server := cfg.MCPServers[name]
+		switch server.Transport {
+		case "stdio":
+			if server.Command == "" {
+				p.addf("mcpServers.%s.command is required for stdio servers", name)
+			}
+		case "tcp", "tls", "http", "websocket":
+			if server.URL == "" {
+				p.addf("mcpServers.%s.url is required for %s servers", name, server.Transport)
+			}
+		default:
+			p.addf("mcpServers.%s.transport must be stdio, tcp, tls, http or websocket, got %q", name, server.Transport)
+		}
+		if (server.TLS.CertFile == "") != (server.TLS.KeyFile == "") {
+			p.addf("mcpServers.%s.tls needs both certFile and keyFile for a client certificate", name)
+		}
+	}
+
+	return p.err()
+}
+
+// problems collects the invalid settings found by a validation.
+type problems []string
+
+func (p *problems) addf(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p problems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(p, "\n  - "))
+}
+
+// ServerNames returns the mcpServers entries in a stable order.
+func ServerNames(servers map[string]models.MCPServerConfig) []string {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DataSource is a parsed apis.dataSource setting.
+type DataSource struct {
+	// Mode is live, record, replay or synthetic.
+	Mode string
+	// Dir holds the fixtures of record and replay.
+	Dir string
+	// Seed drives the synthetic generator.
+	Seed int64
+}
+
+// ParseDataSource reads "live", "record:DIR", "replay:DIR" or
+// "synthetic[:SEED]"; empty means live and the seed defaults to 1.
+func ParseDataSource(value string) (DataSource, error) {
+	mode, arg, _ := strings.Cut(value, ":")
+	source := DataSource{Mode: mode}
+
+	switch mode {
+	case "", "live":
+		source.Mode = "live"
+		if arg != "" {
+			return source, fmt.Errorf("live takes no argument, got %q", value)
+		}
+	case "record", "replay":
+		if arg == "" {
+			return source, fmt.Errorf("%s needs a fixtures directory, e.g. %s:./fixtures", mode, mode)
+		}
+		source.Dir = arg
+	case "synthetic":
+		source.Seed = 1
+		if arg != "" {
+			seed, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return source, fmt.Errorf("invalid synthetic seed %q: must be a number", arg)
+			}
+			source.Seed = seed
+		}
+	default:
+		return source, fmt.Errorf("unknown data source %q (use live, record:DIR, replay:DIR or synthetic[:SEED])", value)
+	}
+	return source, nil
+}
+
+// SplitList splits a comma-separated setting such as MARKET_DATA_PROVIDERS,
+// dropping blank entries.
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", raw)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// loadYAML loads content as the config file.
+func loadYAML(t *testing.T, content string) (*models.Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func loadDefaults(t *testing.T) *models.Config {
+	t.Helper()
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestLoadExpandsEnvInParsedValues(t *testing.T) {
+	t.Setenv("TEST_TOKEN", `s3cr#t: "quoted" value`)
+	t.Setenv("TEST_KEY", "abc")
+
+	cfg, err := loadYAML(t, `
+server:
+  auth:
+    tokens:
+      - name: "admin"
+        token: "${TEST_TOKEN}"
+apis:
+  alphaVantage:
+    apiKey: ${TEST_KEY}
+  csv:
+    dir: "${TEST_UNSET_DIR:-./data}"
+mcpServers:
+  stocks:
+    command: "./bin/stock-analyzer"
+    args: ["-data-source", "${TEST_UNSET_SOURCE:-synthetic:7}"]
+    env:
+      API_KEY: "${TEST_KEY}"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cfg.Server.Auth.Tokens[0].Token; got != `s3cr#t: "quoted" value` {
+		t.Errorf("token = %q, want the variable verbatim", got)
+	}
+	if got := cfg.APIs.AlphaVantage.APIKey; got != "abc" {
+		t.Errorf("apiKey = %q, want abc", got)
+	}
+	if got := cfg.APIs.CSV.Dir; got != "./data" {
+		t.Errorf("csv.dir = %q, want the default ./data", got)
+	}
+	server := cfg.MCPServers["stocks"]
+	if got := strings.Join(server.Args, " "); got != "-data-source synthetic:7" {
+		t.Errorf("args = %q", got)
+	}
+	if got := server.Env["API_KEY"]; got != "abc" {
+		t.Errorf("env.API_KEY = %q, want abc", got)
+	}
+}
+
+func TestLoadRejectsBadVariableReferences(t *testing.T) {
+	_, err := loadYAML(t, `
+apis:
+  alphaVantage:
+    apiKey: "${NOT CLOSED"
+`)
+	if err == nil || !strings.Contains(err.Error(), "apis.alphaVantage.apiKey") {
+		t.Errorf("got %v, want an error naming apis.alphaVantage.apiKey", err)
+	}
+}
+
+func TestValidationIsPerBinary(t *testing.T) {
+	cfg := loadDefaults(t)
+	// Settings only the chatbot uses, broken.
+	cfg.Chatbot.Provider = "mock"
+	cfg.OpenAI.Model = ""
+	cfg.MCPServers["broken"] = models.MCPServerConfig{Command: "./broken", Transport: "carrier-pigeon"}
+
+	if err := ValidateServer(cfg); err != nil {
+		t.Errorf("ValidateServer failed on chatbot settings: %v", err)
+	}
+	if err := ValidateChatbot(cfg); err == nil || !strings.Contains(err.Error(), "chatbot.mockScript") || !strings.Contains(err.Error(), "mcpServers.broken.transport") {
+		t.Errorf("ValidateChatbot = %v, want the mockScript and transport problems", err)
+	}
+
+	cfg = loadDefaults(t)
+	// Settings only the server uses, broken.
+	cfg.Server.Port = 0
+	cfg.APIs.Providers = []string{"carrier-pigeon"}
+
+	if err := ValidateChatbot(cfg); err != nil {
+		t.Errorf("ValidateChatbot failed on server settings: %v", err)
+	}
+	if err := ValidateServer(cfg); err == nil || !strings.Contains(err.Error(), "server.port") || !strings.Contains(err.Error(), "unknown provider") {
+		t.Errorf("ValidateServer = %v, want the port and provider problems", err)
+	}
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,50 +10,51 @@ import (
 	"time"
 )
 
+// ClaudeClient is the Provider for Anthropic's Messages API.
 type ClaudeClient struct {
 	apiKey     string
 	baseURL    string
 	model      string
 	httpClient *http.Client
-}
 
-type ClaudeRequest struct {
-	Model     string    `json:"model"`
-	MaxTokens int       `json:"max_tokens"`
-	Messages  []Message `json:"messages"`
+	// streamClient has no overall timeout; a stream lasts as long as the
+	// answer and is bounded by the caller's context instead.
+	streamClient *http.Client
 }
 
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
+var _ Provider = (*ClaudeClient)(nil)
 
-type ClaudeResponse struct {
-	Content []ContentBlock `json:"content"`
-	ID      string         `json:"id"`
-	Model   string         `json:"model"`
-	Role    string         `json:"role"`
-	Type    string         `json:"type"`
-	Usage   Usage          `json:"usage"`
+type ClaudeRequest struct {
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Messages    []Message `json:"messages"`
+	Tools       []Tool    `json:"tools,omitempty"`
+	System      string    `json:"system,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
 }
 
-type ContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+// ClaudeResponse is the Messages API response body.
+type ClaudeResponse struct {
+	Content    []ContentBlock `json:"content"`
+	ID         string         `json:"id"`
+	Model      string         `json:"model"`
+	Role       string         `json:"role"`
+	Type       string         `json:"type"`
+	StopReason string         `json:"stop_reason"`
+	Usage      Usage          `json:"usage"`
 }
 
-type Usage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+func (r *ClaudeResponse) toResponse() *Response {
+	return &Response{Content: r.Content, StopReason: r.StopReason, Usage: r.Usage}
 }
 
+// NewClaudeClient targets baseURL, the Messages API endpoint. model comes
+// from the configuration, which holds the default.
 func NewClaudeClient(apiKey, baseURL, model string) *ClaudeClient {
 	if baseURL == "" {
 		baseURL = "https://api.anthropic.com/v1/messages"
 	}
-	if model == "" {
-		model = "claude-3-haiku-20240307"
-	}
 
 	return &ClaudeClient{
 		apiKey:  apiKey,
@@ -61,30 +63,32 @@ func NewClaudeClient(apiKey, baseURL, model string) *ClaudeClient {
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
+		streamClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 60 * time.Second,
+			},
+		},
 	}
 }
 
-func (c *ClaudeClient) SendMessage(messages []Message) (*ClaudeResponse, error) {
-	request := ClaudeRequest{
-		Model:     c.model,
-		MaxTokens: 4000,
-		Messages:  messages,
-	}
+func (c *ClaudeClient) Name() string {
+	return "claude"
+}
 
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
+func (c *ClaudeClient) SendMessage(messages []Message) (*Response, error) {
+	return c.Send(context.Background(), messages, nil, RequestOptions{})
+}
 
-	req, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(jsonData))
+// Send sends the conversation together with tool definitions. When the
+// response stops with StopToolUse, run the requested tools and send their
+// results back as tool_result blocks in a user turn.
+func (c *ClaudeClient) Send(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (*Response, error) {
+	req, err := c.newRequest(ctx, messages, tools, opts, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", c.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -105,7 +109,43 @@ func (c *ClaudeClient) SendMessage(messages []Message) (*ClaudeResponse, error)
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return &claudeResponse, nil
+	return claudeResponse.toResponse(), nil
+}
+
+func (c *ClaudeClient) newRequest(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions, stream bool) (*http.Request, error) {
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTokens
+	}
+
+	request := ClaudeRequest{
+		Model:       c.model,
+		MaxTokens:   maxTokens,
+		Messages:    messages,
+		Tools:       tools,
+		System:      opts.System,
+		Temperature: opts.Temperature,
+		Stream:      stream,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	return req, nil
 }
 
 func (c *ClaudeClient) Chat(userMessage string) (string, error) {
@@ -125,7 +165,7 @@ func (c *ClaudeClient) Chat(userMessage string) (string, error) {
 		return "", fmt.Errorf("empty response from Claude")
 	}
 
-	return response.Content[0].Text, nil
+	return response.Text(), nil
 }
 
 func (c *ClaudeClient) IsAvailable() bool {
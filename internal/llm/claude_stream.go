@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Stream sends the conversation with stream: true and returns the events as
+// they arrive. The channel is closed after a Response or Err event.
+// Cancelling ctx aborts the stream.
+func (c *ClaudeClient) Stream(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (<-chan StreamEvent, error) {
+	req, err := c.newRequest(ctx, messages, tools, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		send := streamSender(ctx, events)
+
+		response, err := readStream(resp.Body, send)
+		if err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			send(StreamEvent{Err: err})
+			return
+		}
+		send(StreamEvent{Response: response})
+	}()
+
+	return events, nil
+}
+
+// streamPayload covers the fields of every Messages API stream event type.
+type streamPayload struct {
+	Type    string          `json:"type"`
+	Index   int             `json:"index"`
+	Message *ClaudeResponse `json:"message"`
+	Block   *ContentBlock   `json:"content_block"`
+	Delta   struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *Usage `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readStream parses the server-sent events of a streamed message and
+// assembles the final response. send returns false when the consumer went
+// away.
+func readStream(body io.Reader, send func(StreamEvent) bool) (*Response, error) {
+	var response Response
+	var usage Usage
+	partialInputs := make(map[int]*strings.Builder)
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			// event: lines repeat the type already present in the data.
+			continue
+		}
+
+		var payload streamPayload
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &payload); err != nil {
+			return nil, fmt.Errorf("failed to parse stream event: %w", err)
+		}
+
+		switch payload.Type {
+		case "message_start":
+			if payload.Message != nil {
+				usage = payload.Message.Usage
+				snapshot := usage
+				if !send(StreamEvent{Usage: &snapshot}) {
+					return nil, context.Canceled
+				}
+			}
+
+		case "content_block_start":
+			if payload.Block == nil {
+				continue
+			}
+			for len(response.Content) <= payload.Index {
+				response.Content = append(response.Content, ContentBlock{})
+			}
+			response.Content[payload.Index] = *payload.Block
+			if payload.Block.Type == "tool_use" {
+				partialInputs[payload.Index] = &strings.Builder{}
+			}
+
+		case "content_block_delta":
+			if payload.Index >= len(response.Content) {
+				continue
+			}
+			switch payload.Delta.Type {
+			case "text_delta":
+				response.Content[payload.Index].Text += payload.Delta.Text
+				if !send(StreamEvent{Text: payload.Delta.Text}) {
+					return nil, context.Canceled
+				}
+			case "input_json_delta":
+				if partial, ok := partialInputs[payload.Index]; ok {
+					partial.WriteString(payload.Delta.PartialJSON)
+				}
+			}
+
+		case "content_block_stop":
+			if partial, ok := partialInputs[payload.Index]; ok {
+				input := partial.String()
+				if input == "" {
+					input = "{}"
+				}
+				response.Content[payload.Index].Input = json.RawMessage(input)
+				delete(partialInputs, payload.Index)
+			}
+
+		case "message_delta":
+			if payload.Delta.StopReason != "" {
+				response.StopReason = payload.Delta.StopReason
+			}
+			if payload.Usage != nil {
+				usage.OutputTokens = payload.Usage.OutputTokens
+				snapshot := usage
+				if !send(StreamEvent{Usage: &snapshot}) {
+					return nil, context.Canceled
+				}
+			}
+
+		case "message_stop":
+			response.Usage = usage
+			return &response, nil
+
+		case "error":
+			if payload.Error != nil {
+				return nil, fmt.Errorf("stream error (%s): %s", payload.Error.Type, payload.Error.Message)
+			}
+			return nil, fmt.Errorf("stream error")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil, fmt.Errorf("stream ended before message_stop")
+}
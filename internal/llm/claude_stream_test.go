@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordedToolStream is a Messages API stream in which the model writes a
+// sentence, then calls one tool with streamed input and one without input.
+const recordedToolStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_01","type":"message","role":"assistant","content":[],"model":"claude-3-haiku-20240307","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type": "ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_01","name":"stocks__quote","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"symbol\": "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"AAPL\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: content_block_start
+data: {"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_02","name":"stocks__market_status","input":{}}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":2}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":42}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+// streamClaude streams the recorded body through a ClaudeClient and returns
+// the events it produced.
+func streamClaude(t *testing.T, body string) []StreamEvent {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "test-key" {
+			http.Error(w, "missing key", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := NewClaudeClient("test-key", server.URL, "claude-3-haiku-20240307")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := client.Stream(ctx, []Message{{Role: "user", Content: "How is AAPL?"}}, nil, RequestOptions{MaxTokens: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []StreamEvent
+	for event := range events {
+		received = append(received, event)
+	}
+	return received
+}
+
+func TestClaudeStreamAssemblesResponse(t *testing.T) {
+	events := streamClaude(t, recordedToolStream)
+
+	var text strings.Builder
+	var usage []Usage
+	for _, event := range events {
+		if event.Err != nil {
+			t.Fatal(event.Err)
+		}
+		text.WriteString(event.Text)
+		if event.Usage != nil {
+			usage = append(usage, *event.Usage)
+		}
+	}
+	response := events[len(events)-1].Response
+	if response == nil {
+		t.Fatalf("last event = %+v, want the response", events[len(events)-1])
+	}
+
+	if text.String() != "Let me check." || response.Text() != "Let me check." {
+		t.Errorf("streamed %q, response text %q", text.String(), response.Text())
+	}
+	if response.StopReason != StopToolUse {
+		t.Errorf("stop reason = %q, want %q", response.StopReason, StopToolUse)
+	}
+	uses := response.ToolUses()
+	if len(uses) != 2 {
+		t.Fatalf("tool uses = %+v, want 2", uses)
+	}
+	if uses[0].ID != "toolu_01" || uses[0].Name != "stocks__quote" || string(uses[0].Input) != `{"symbol": "AAPL"}` {
+		t.Errorf("first tool use = %+v", uses[0])
+	}
+	if uses[1].ID != "toolu_02" || string(uses[1].Input) != `{}` {
+		t.Errorf("tool use without input = %+v, want input {}", uses[1])
+	}
+
+	if len(usage) != 2 || usage[0] != (Usage{InputTokens: 25, OutputTokens: 1}) || usage[1] != (Usage{InputTokens: 25, OutputTokens: 42}) {
+		t.Errorf("usage events = %+v, want 25/1 then 25/42", usage)
+	}
+	if response.Usage != (Usage{InputTokens: 25, OutputTokens: 42}) {
+		t.Errorf("response usage = %+v", response.Usage)
+	}
+}
+
+func TestClaudeStreamReportsErrors(t *testing.T) {
+	for name, body := range map[string]string{
+		"error event": "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n",
+		"cut short":   strings.Split(recordedToolStream, "event: message_delta")[0],
+	} {
+		events := streamClaude(t, body)
+		last := events[len(events)-1]
+		if last.Err == nil || last.Response != nil {
+			t.Errorf("%s: last event = %+v, want an error", name, last)
+		}
+		if name == "error event" && !strings.Contains(last.Err.Error(), "overloaded_error") {
+			t.Errorf("%s: error = %v, want the stream's error type", name, last.Err)
+		}
+	}
+}
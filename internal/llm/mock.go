@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MockProvider replays canned responses in order, so conversations and the
+// tool loop can be exercised without network access. It records every
+// request it receives.
+type MockProvider struct {
+	mu        sync.Mutex
+	responses []Response
+	next      int
+	requests  []MockRequest
+}
+
+var _ Provider = (*MockProvider)(nil)
+
+// MockRequest is what the mock was asked, for later inspection.
+type MockRequest struct {
+	Messages []Message
+	Tools    []Tool
+	Options  RequestOptions
+}
+
+func NewMockProvider(responses []Response) *MockProvider {
+	return &MockProvider{responses: responses}
+}
+
+// LoadMockProvider reads a JSON array of responses. Each entry is either a
+// plain string, replayed as a text reply, or a Response object in Messages
+// API shape, e.g.
+//
+//	[
+//	  {"content": [{"type": "tool_use", "id": "t1", "name": "stock-analyzer__get_stock_price", "input": {"symbol": "AAPL"}}],
+//	   "stop_reason": "tool_use"},
+//	  "AAPL is trading at $190."
+//	]
+func LoadMockProvider(path string) (*MockProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock script: %w", err)
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse mock script %s: %w", path, err)
+	}
+
+	responses := make([]Response, 0, len(entries))
+	for i, entry := range entries {
+		var text string
+		if err := json.Unmarshal(entry, &text); err == nil {
+			responses = append(responses, Response{Content: []ContentBlock{TextBlock(text)}, StopReason: StopEndTurn})
+			continue
+		}
+
+		var response Response
+		if err := json.Unmarshal(entry, &response); err != nil {
+			return nil, fmt.Errorf("mock script %s, entry %d: %w", path, i, err)
+		}
+		if response.StopReason == "" {
+			response.StopReason = StopEndTurn
+			if len(response.ToolUses()) > 0 {
+				response.StopReason = StopToolUse
+			}
+		}
+		responses = append(responses, response)
+	}
+
+	return NewMockProvider(responses), nil
+}
+
+func (m *MockProvider) Name() string {
+	return "mock"
+}
+
+func (m *MockProvider) IsAvailable() bool {
+	return true
+}
+
+func (m *MockProvider) Send(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, MockRequest{
+		Messages: append([]Message(nil), messages...),
+		Tools:    tools,
+		Options:  opts,
+	})
+
+	if m.next >= len(m.responses) {
+		return nil, fmt.Errorf("mock script exhausted after %d responses", len(m.responses))
+	}
+
+	response := m.responses[m.next]
+	m.next++
+	return &response, nil
+}
+
+// Stream replays the next response, delivering its text word by word.
+func (m *MockProvider) Stream(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (<-chan StreamEvent, error) {
+	response, err := m.Send(ctx, messages, tools, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		send := streamSender(ctx, events)
+
+		for _, word := range strings.SplitAfter(response.Text(), " ") {
+			if word != "" && !send(StreamEvent{Text: word}) {
+				return
+			}
+		}
+		send(StreamEvent{Response: response})
+	}()
+
+	return events, nil
+}
+
+// Requests returns the requests received so far.
+func (m *MockProvider) Requests() []MockRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockRequest(nil), m.requests...)
+}
@@ -0,0 +1,379 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIClient is a Provider for OpenAI-compatible chat completion APIs,
+// including local servers such as llama.cpp and Ollama.
+type OpenAIClient struct {
+	apiKey       string
+	baseURL      string
+	model        string
+	httpClient   *http.Client
+	streamClient *http.Client
+}
+
+var _ Provider = (*OpenAIClient)(nil)
+
+// NewOpenAIClient targets baseURL, the API root such as
+// http://localhost:11434/v1. apiKey may be empty for local servers.
+func NewOpenAIClient(apiKey, baseURL, model string) *OpenAIClient {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+
+	return &OpenAIClient{
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: 120 * time.Second,
+		},
+		streamClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 120 * time.Second,
+			},
+		},
+	}
+}
+
+func (c *OpenAIClient) Name() string {
+	return "openai"
+}
+
+// IsAvailable only requires a model; local servers usually need no key.
+func (c *OpenAIClient) IsAvailable() bool {
+	return c.model != ""
+}
+
+type openAIRequest struct {
+	Model         string          `json:"model"`
+	Messages      []openAIMessage `json:"messages"`
+	Tools         []openAITool    `json:"tools,omitempty"`
+	MaxTokens     int             `json:"max_tokens,omitempty"`
+	Temperature   *float64        `json:"temperature,omitempty"`
+	Stream        bool            `json:"stream,omitempty"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+type openAIToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (c *OpenAIClient) Send(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (*Response, error) {
+	req, err := c.newRequest(ctx, messages, tools, opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var completion openAIResponse
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("empty response from %s", c.baseURL)
+	}
+
+	choice := completion.Choices[0]
+	response := &Response{StopReason: openAIStopReason(choice.FinishReason, len(choice.Message.ToolCalls))}
+	if choice.Message.Content != "" {
+		response.Content = append(response.Content, TextBlock(choice.Message.Content))
+	}
+	for i, call := range choice.Message.ToolCalls {
+		response.Content = append(response.Content, toolUseFromCall(call, i))
+	}
+	if completion.Usage != nil {
+		response.Usage = Usage{InputTokens: completion.Usage.PromptTokens, OutputTokens: completion.Usage.CompletionTokens}
+	}
+
+	return response, nil
+}
+
+func (c *OpenAIClient) Stream(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (<-chan StreamEvent, error) {
+	req, err := c.newRequest(ctx, messages, tools, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		send := streamSender(ctx, events)
+		response, err := readOpenAIStream(resp.Body, send)
+		if err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			send(StreamEvent{Err: err})
+			return
+		}
+		send(StreamEvent{Response: response})
+	}()
+
+	return events, nil
+}
+
+func readOpenAIStream(body io.Reader, send func(StreamEvent) bool) (*Response, error) {
+	var text strings.Builder
+	var calls []openAIToolCall
+	var finishReason string
+	response := &Response{}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+
+		if chunk.Usage != nil {
+			response.Usage = Usage{InputTokens: chunk.Usage.PromptTokens, OutputTokens: chunk.Usage.CompletionTokens}
+			usage := response.Usage
+			if !send(StreamEvent{Usage: &usage}) {
+				return nil, context.Canceled
+			}
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.Delta.Content != "" {
+			text.WriteString(choice.Delta.Content)
+			if !send(StreamEvent{Text: choice.Delta.Content}) {
+				return nil, context.Canceled
+			}
+		}
+
+		// Tool calls arrive in fragments keyed by index: the first carries
+		// the id and name, later ones append to the arguments.
+		for _, fragment := range choice.Delta.ToolCalls {
+			for len(calls) <= fragment.Index {
+				calls = append(calls, openAIToolCall{})
+			}
+			call := &calls[fragment.Index]
+			if fragment.ID != "" {
+				call.ID = fragment.ID
+			}
+			if fragment.Function.Name != "" {
+				call.Function.Name = fragment.Function.Name
+			}
+			call.Function.Arguments += fragment.Function.Arguments
+		}
+
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	response.StopReason = openAIStopReason(finishReason, len(calls))
+
+	if text.Len() > 0 {
+		response.Content = append(response.Content, TextBlock(text.String()))
+	}
+	for i, call := range calls {
+		response.Content = append(response.Content, toolUseFromCall(call, i))
+	}
+
+	return response, nil
+}
+
+func (c *OpenAIClient) newRequest(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions, stream bool) (*http.Request, error) {
+	request := openAIRequest{
+		Model:       c.model,
+		Messages:    toOpenAIMessages(opts.System, messages),
+		MaxTokens:   opts.MaxTokens,
+		Temperature: opts.Temperature,
+		Stream:      stream,
+	}
+	if stream {
+		request.StreamOptions = &struct {
+			IncludeUsage bool `json:"include_usage"`
+		}{IncludeUsage: true}
+	}
+
+	for _, tool := range tools {
+		var def openAITool
+		def.Type = "function"
+		def.Function.Name = tool.Name
+		def.Function.Description = tool.Description
+		def.Function.Parameters = tool.InputSchema
+		request.Tools = append(request.Tools, def)
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	return req, nil
+}
+
+// toOpenAIMessages translates Messages API turns: the system prompt becomes
+// a system message, tool_use blocks become tool_calls and every tool_result
+// block becomes its own "tool" message.
+func toOpenAIMessages(system string, messages []Message) []openAIMessage {
+	var converted []openAIMessage
+	if system != "" {
+		converted = append(converted, openAIMessage{Role: "system", Content: system})
+	}
+
+	for _, message := range messages {
+		if len(message.Blocks) == 0 {
+			converted = append(converted, openAIMessage{Role: message.Role, Content: message.Content})
+			continue
+		}
+
+		turn := openAIMessage{Role: message.Role}
+		var texts []string
+		for _, block := range message.Blocks {
+			switch block.Type {
+			case "text":
+				texts = append(texts, block.Text)
+			case "tool_use":
+				call := openAIToolCall{ID: block.ID, Type: "function"}
+				call.Function.Name = block.Name
+				call.Function.Arguments = string(block.Input)
+				turn.ToolCalls = append(turn.ToolCalls, call)
+			case "tool_result":
+				converted = append(converted, openAIMessage{Role: "tool", ToolCallID: block.ToolUseID, Content: block.Content})
+			}
+		}
+
+		if len(texts) > 0 || len(turn.ToolCalls) > 0 {
+			turn.Content = strings.Join(texts, "\n")
+			converted = append(converted, turn)
+		}
+	}
+
+	return converted
+}
+
+// toolUseFromCall converts a tool call; some local servers omit call ids, so
+// one is made up from the position to keep results matched.
+func toolUseFromCall(call openAIToolCall, position int) ContentBlock {
+	input := json.RawMessage(call.Function.Arguments)
+	if len(input) == 0 || !json.Valid(input) {
+		input = json.RawMessage(`{}`)
+	}
+
+	id := call.ID
+	if id == "" {
+		id = fmt.Sprintf("call_%d", position)
+	}
+	return ContentBlock{Type: "tool_use", ID: id, Name: call.Function.Name, Input: input}
+}
+
+// openAIStopReason maps finish_reason to a StopReason. Replies with tool calls
+// always stop for tool use: llama.cpp and Ollama report "stop" for them.
+func openAIStopReason(finishReason string, toolCalls int) string {
+	if toolCalls > 0 {
+		return StopToolUse
+	}
+	switch finishReason {
+	case "tool_calls", "function_call":
+		return StopToolUse
+	case "length":
+		return StopMaxTokens
+	default:
+		return StopEndTurn
+	}
+}
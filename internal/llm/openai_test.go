@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openAIStub answers chat completions with body, as an event stream when
+// stream is set.
+func openAIStub(t *testing.T, body string, stream bool) *OpenAIClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return NewOpenAIClient("", server.URL+"/v1", "llama3")
+}
+
+func TestOpenAISendTreatsToolCallsAsToolUse(t *testing.T) {
+	// llama.cpp and Ollama finish tool calls with "stop".
+	client := openAIStub(t, `{
+		"choices": [{
+			"message": {"role": "assistant", "content": "", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "stocks__quote", "arguments": "{\"symbol\":\"AAPL\"}"}}
+			]},
+			"finish_reason": "stop"
+		}],
+		"usage": {"prompt_tokens": 12, "completion_tokens": 7}
+	}`, false)
+
+	response, err := client.Send(context.Background(), []Message{{Role: "user", Content: "AAPL?"}}, nil, RequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StopReason != StopToolUse {
+		t.Errorf("stop reason = %q, want %q", response.StopReason, StopToolUse)
+	}
+	uses := response.ToolUses()
+	if len(uses) != 1 || uses[0].ID != "call_1" || uses[0].Name != "stocks__quote" || string(uses[0].Input) != `{"symbol":"AAPL"}` {
+		t.Errorf("tool uses = %+v", uses)
+	}
+	if response.Usage.InputTokens != 12 || response.Usage.OutputTokens != 7 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+}
+
+func TestOpenAIStreamAssemblesToolCallFragments(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"delta":{"role":"assistant","content":"Checking "}}]}`,
+		`{"choices":[{"delta":{"content":"both."}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_a","function":{"name":"stocks__quote","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"symbol\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_b","function":{"name":"stocks__quote","arguments":"{\"symbol\":\"MSFT\"}"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"AAPL\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":20,"completion_tokens":9}}`,
+	}
+	var body strings.Builder
+	for _, chunk := range chunks {
+		body.WriteString("data: " + chunk + "\n\n")
+	}
+	body.WriteString("data: [DONE]\n\n")
+	client := openAIStub(t, body.String(), true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := client.Stream(ctx, []Message{{Role: "user", Content: "AAPL and MSFT?"}}, nil, RequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text strings.Builder
+	var response *Response
+	for event := range events {
+		if event.Err != nil {
+			t.Fatal(event.Err)
+		}
+		text.WriteString(event.Text)
+		if event.Response != nil {
+			response = event.Response
+		}
+	}
+	if response == nil {
+		t.Fatal("stream ended without a response")
+	}
+
+	if text.String() != "Checking both." || response.Text() != "Checking both." {
+		t.Errorf("streamed %q, response text %q", text.String(), response.Text())
+	}
+	if response.StopReason != StopToolUse {
+		t.Errorf("stop reason = %q, want %q", response.StopReason, StopToolUse)
+	}
+	uses := response.ToolUses()
+	if len(uses) != 2 {
+		t.Fatalf("tool uses = %+v, want 2", uses)
+	}
+	if uses[0].ID != "call_a" || string(uses[0].Input) != `{"symbol":"AAPL"}` {
+		t.Errorf("first call = %+v", uses[0])
+	}
+	if uses[1].ID != "call_b" || string(uses[1].Input) != `{"symbol":"MSFT"}` {
+		t.Errorf("second call = %+v", uses[1])
+	}
+	if response.Usage.InputTokens != 20 || response.Usage.OutputTokens != 9 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// Provider is a chat model backend. Conversations, tool definitions and
+// responses use the Messages API shapes below; providers with a different
+// wire format translate at the edge.
+type Provider interface {
+	Name() string
+	IsAvailable() bool
+	Send(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (*Response, error)
+	Stream(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (<-chan StreamEvent, error)
+}
+
+// Stop reasons reported in Response.StopReason.
+const (
+	StopEndTurn   = "end_turn"
+	StopToolUse   = "tool_use"
+	StopMaxTokens = "max_tokens"
+)
+
+// DefaultMaxTokens is used when RequestOptions.MaxTokens is zero.
+const DefaultMaxTokens = 4000
+
+// RequestOptions tunes a single call. Zero values fall back to the defaults.
+type RequestOptions struct {
+	MaxTokens   int
+	Temperature *float64
+	System      string
+}
+
+// Message is a conversation turn. Plain turns only set Content; turns that
+// carry tool_use or tool_result blocks set Blocks instead.
+type Message struct {
+	Role    string         `json:"role"`
+	Content string         `json:"-"`
+	Blocks  []ContentBlock `json:"-"`
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	var content interface{} = m.Content
+	if len(m.Blocks) > 0 {
+		content = m.Blocks
+	}
+
+	return json.Marshal(struct {
+		Role    string      `json:"role"`
+		Content interface{} `json:"content"`
+	}{m.Role, content})
+}
+
+// Tool is a tool definition offered to the model.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// ContentBlock is a text, tool_use or tool_result block. Only the fields of
+// the block's type are set.
+type ContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+func TextBlock(text string) ContentBlock {
+	return ContentBlock{Type: "text", Text: text}
+}
+
+func ToolResultBlock(toolUseID, content string, isError bool) ContentBlock {
+	return ContentBlock{Type: "tool_result", ToolUseID: toolUseID, Content: content, IsError: isError}
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Response is a complete model turn.
+type Response struct {
+	Content    []ContentBlock `json:"content"`
+	StopReason string         `json:"stop_reason,omitempty"`
+	Usage      Usage          `json:"usage"`
+}
+
+// Text joins the text blocks of the response.
+func (r *Response) Text() string {
+	var parts []string
+	for _, block := range r.Content {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ToolUses returns the tool_use blocks the model wants executed.
+func (r *Response) ToolUses() []ContentBlock {
+	var uses []ContentBlock
+	for _, block := range r.Content {
+		if block.Type == "tool_use" {
+			uses = append(uses, block)
+		}
+	}
+	return uses
+}
+
+// StreamEvent is one item of a streamed response. Exactly one of the event
+// kinds is set:
+//
+//	Text      a text delta to print as it arrives
+//	Usage     token usage, reported at the start and again at the end
+//	Response  the complete message once the stream finished, including any
+//	          tool_use blocks
+//	Err       the stream failed; no further events follow
+type StreamEvent struct {
+	Text     string
+	Usage    *Usage
+	Response *Response
+	Err      error
+}
+
+// streamSender delivers events until ctx is done; it reports false once the
+// consumer has gone away.
+func streamSender(ctx context.Context, events chan<- StreamEvent) func(StreamEvent) bool {
+	return func(event StreamEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
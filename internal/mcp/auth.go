@@ -0,0 +1,337 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// JSON-RPC error codes for requests refused by the auth layer.
+const (
+	codeUnauthorized = -32010
+	codeForbidden    = -32011
+)
+
+// Identity is an authenticated client and the tools, resources and prompts
+// it may use. A "*" entry allows all of a kind; resource entries are URI
+// patterns in path.Match syntax, e.g. "stock://*/quote".
+type Identity struct {
+	Name      string
+	Tools     []string
+	Resources []string
+	Prompts   []string
+}
+
+// Allows reports whether the identity may call tool.
+func (id *Identity) Allows(tool string) bool {
+	return allowListed(id.Tools, tool)
+}
+
+// AllowsResource reports whether the identity may read or subscribe to uri.
+func (id *Identity) AllowsResource(uri string) bool {
+	for _, pattern := range id.Resources {
+		if pattern == "*" || pattern == uri {
+			return true
+		}
+		if matched, _ := path.Match(pattern, uri); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsTemplate reports whether the identity may read some URI of the
+// resource template, so that the template is worth listing.
+func (id *Identity) AllowsTemplate(uriTemplate string) bool {
+	template, err := ParseURITemplate(uriTemplate)
+	if err != nil {
+		return false
+	}
+	glob := template.Glob()
+	for _, pattern := range id.Resources {
+		if pattern == "*" {
+			return true
+		}
+		// Either pattern covers the template or names some of its URIs.
+		if matched, _ := path.Match(pattern, glob); matched {
+			return true
+		}
+		if matched, _ := path.Match(glob, pattern); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsPrompt reports whether the identity may get prompt.
+func (id *Identity) AllowsPrompt(prompt string) bool {
+	return allowListed(id.Prompts, prompt)
+}
+
+func allowListed(allowed []string, name string) bool {
+	for _, entry := range allowed {
+		if entry == "*" || entry == name {
+			return true
+		}
+	}
+	return false
+}
+
+// localIdentity is given to stdio sessions: the client started the server
+// process itself, so there is nothing to authenticate.
+var localIdentity = &Identity{Name: "local", Tools: []string{"*"}, Resources: []string{"*"}, Prompts: []string{"*"}}
+
+// Authenticator maps a bearer token to the identity it was issued to.
+type Authenticator interface {
+	Authenticate(token string) (*Identity, bool)
+}
+
+// TokenAuthenticator checks tokens against a fixed table.
+type TokenAuthenticator struct {
+	tokens []tokenEntry
+}
+
+type tokenEntry struct {
+	token    []byte
+	identity *Identity
+}
+
+// NewTokenAuthenticator creates an authenticator from a token → identity
+// table.
+func NewTokenAuthenticator(tokens map[string]Identity) *TokenAuthenticator {
+	auth := &TokenAuthenticator{}
+	for token, identity := range tokens {
+		identity := identity
+		auth.tokens = append(auth.tokens, tokenEntry{token: []byte(token), identity: &identity})
+	}
+	return auth
+}
+
+// Authenticate compares token with every known token in constant time so the
+// answer does not leak how much of a guess was right.
+func (a *TokenAuthenticator) Authenticate(token string) (*Identity, bool) {
+	var found *Identity
+	for _, entry := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), entry.token) == 1 {
+			found = entry.identity
+		}
+	}
+	return found, found != nil && token != ""
+}
+
+// SetAuthenticator requires network clients to present a bearer token: in
+// the _meta.authorization of initialize on TCP, TLS and WebSocket
+// connections, and in the Authorization header of every HTTP request. Stdio
+// sessions are local and not authenticated.
+func (s *Server) SetAuthenticator(auth Authenticator) {
+	s.auth = auth
+}
+
+// SetAuditLog writes one JSON line per authentication and tool call decision
+// to w. Without it the decisions go to the server log.
+func (s *Server) SetAuditLog(w io.Writer) {
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+	s.auditLog = w
+}
+
+// auditEvent is one line of the audit log.
+type auditEvent struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	Identity string    `json:"identity,omitempty"`
+	Peer     string    `json:"peer,omitempty"`
+	Tool     string    `json:"tool,omitempty"`
+	Resource string    `json:"resource,omitempty"`
+	Prompt   string    `json:"prompt,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+func (s *Server) audit(sess *session, event, tool, reason string) {
+	s.auditAccess(sess, event, accessTool, tool, reason)
+}
+
+// auditAccess records a decision about the tool, resource or prompt name.
+func (s *Server) auditAccess(sess *session, event string, kind accessKind, name, reason string) {
+	entry := auditEvent{
+		Event:  event,
+		Peer:   sess.peer,
+		Reason: reason,
+	}
+	switch kind {
+	case accessTool:
+		entry.Tool = name
+	case accessResource:
+		entry.Resource = name
+	case accessPrompt:
+		entry.Prompt = name
+	}
+	if identity := sess.currentIdentity(); identity != nil {
+		entry.Identity = identity.Name
+	}
+	s.writeAudit(entry)
+}
+
+func (s *Server) writeAudit(entry auditEvent) {
+	entry.Time = time.Now().UTC()
+
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+
+	if s.auditLog == nil {
+		s.logger.Printf("Audit: %s identity=%q peer=%s tool=%s resource=%s prompt=%s %s", entry.Event, entry.Identity, entry.Peer, entry.Tool, entry.Resource, entry.Prompt, entry.Reason)
+		return
+	}
+	if err := json.NewEncoder(s.auditLog).Encode(entry); err != nil {
+		s.logger.Printf("Failed to write audit log: %v", err)
+	}
+}
+
+// authenticate checks the token a client presented and binds the identity to
+// the session. It reports whether the client was accepted.
+func (s *Server) authenticate(sess *session, authorization string) bool {
+	token, ok := bearerToken(authorization)
+	if !ok {
+		s.audit(sess, "auth_failed", "", "missing bearer token")
+		return false
+	}
+
+	identity, ok := s.auth.Authenticate(token)
+	if !ok {
+		s.audit(sess, "auth_failed", "", "unknown token")
+		return false
+	}
+
+	sess.setIdentity(identity)
+	s.audit(sess, "authenticated", "", "")
+	return true
+}
+
+// accessKind is what a request asks to use: a tool, a resource or a prompt.
+type accessKind string
+
+const (
+	accessTool     accessKind = "tool"
+	accessResource accessKind = "resource"
+	accessPrompt   accessKind = "prompt"
+	accessTemplate accessKind = "resource template"
+)
+
+// authorize reports whether the session may use the tool, resource or
+// prompt name, answering the request itself when it may not. Every decision
+// is audited.
+func (s *Server) authorize(sess *session, id interface{}, kind accessKind, name string) (bool, error) {
+	if s.auth == nil {
+		return true, nil
+	}
+
+	identity := sess.currentIdentity()
+	if identity == nil {
+		s.auditAccess(sess, "denied", kind, name, "not authenticated")
+		return false, s.sendError(sess, id, codeUnauthorized, "Unauthorized", "initialize with a bearer token first")
+	}
+	if !identity.allowsAccess(kind, name) {
+		s.auditAccess(sess, "denied", kind, name, string(kind)+" not allowed")
+		return false, s.sendError(sess, id, codeForbidden, "Forbidden", fmt.Sprintf("%s may not use %s %s", identity.Name, kind, name))
+	}
+
+	s.auditAccess(sess, "allowed", kind, name, "")
+	return true, nil
+}
+
+// visible reports whether lists shown to the session include name.
+func (s *Server) visible(sess *session, kind accessKind, name string) bool {
+	if s.auth == nil {
+		return true
+	}
+	identity := sess.currentIdentity()
+	return identity == nil || identity.allowsAccess(kind, name)
+}
+
+func (id *Identity) allowsAccess(kind accessKind, name string) bool {
+	switch kind {
+	case accessResource:
+		return id.AllowsResource(name)
+	case accessPrompt:
+		return id.AllowsPrompt(name)
+	case accessTemplate:
+		return id.AllowsTemplate(name)
+	default:
+		return id.Allows(name)
+	}
+}
+
+// authenticateRequest checks the bearer token in the Authorization header of
+// an HTTP request, answering 401 itself when it is missing or unknown.
+func (s *Server) authenticateRequest(w http.ResponseWriter, r *http.Request) (*Identity, bool) {
+	var identity *Identity
+	token, ok := bearerToken(r.Header.Get("Authorization"))
+	if ok {
+		identity, ok = s.auth.Authenticate(token)
+	}
+	if !ok {
+		s.writeAudit(auditEvent{Event: "auth_failed", Peer: r.RemoteAddr, Reason: "missing or unknown bearer token"})
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+		writeHTTPError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return nil, false
+	}
+	return identity, true
+}
+
+// requiresIdentity reports whether method may only be used after
+// authenticating. Handshake and keepalive messages never do.
+func requiresIdentity(method string) bool {
+	return method != "initialize" && method != "ping" && !strings.HasPrefix(method, "notifications/")
+}
+
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+type identityKey struct{}
+type peerKey struct{}
+
+// withIdentity authenticates the session HandleRequest starts for ctx up
+// front, for transports that authenticate outside the MCP handshake.
+func withIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// withPeer records the remote address a session is served to, for the audit
+// log.
+func withPeer(ctx context.Context, peer string) context.Context {
+	return context.WithValue(ctx, peerKey{}, peer)
+}
+
+func identityFrom(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+func peerFrom(ctx context.Context) string {
+	peer, _ := ctx.Value(peerKey{}).(string)
+	return peer
+}
+
+func (ss *session) setIdentity(identity *Identity) {
+	ss.mu.Lock()
+	ss.identity = identity
+	ss.mu.Unlock()
+}
+
+func (ss *session) currentIdentity() *Identity {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.identity
+}
@@ -0,0 +1,264 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes of the
+// audit log.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAuthorizeResourcesAndPrompts(t *testing.T) {
+	s := newTestServer()
+	s.EnableSubscriptions(time.Hour)
+	for _, uri := range []string{"stock://AAPL/quote", "stock://AAPL/indicators"} {
+		uri := uri
+		s.RegisterResource(models.Resource{URI: uri, Name: uri}, ResourceHandlerFunc(func(ctx context.Context, _ string, _ map[string]string) ([]models.ResourceContents, error) {
+			return []models.ResourceContents{{URI: uri, Text: "contents"}}, nil
+		}))
+	}
+	for _, uriTemplate := range []string{"stock://{symbol}/quote", "stock://{symbol}/news"} {
+		if err := s.RegisterResourceTemplate(models.ResourceTemplate{URITemplate: uriTemplate, Name: uriTemplate}, ResourceHandlerFunc(func(ctx context.Context, uri string, _ map[string]string) ([]models.ResourceContents, error) {
+			return []models.ResourceContents{{URI: uri, Text: "contents"}}, nil
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Like the stock analyzer's prompts, "embedding" reports the resources it
+	// could not embed instead of failing.
+	s.RegisterPrompt(models.Prompt{Name: "embedding"}, PromptHandlerFunc(func(ctx context.Context, args map[string]string) (*models.GetPromptResponse, error) {
+		var messages []models.PromptMessage
+		for _, uri := range []string{"stock://AAPL/quote", "stock://AAPL/indicators"} {
+			content, err := s.EmbedResource(ctx, uri)
+			if err != nil {
+				content = models.Content{Type: "text", Text: "missing: " + err.Error()}
+			}
+			messages = append(messages, models.PromptMessage{Role: "user", Content: content})
+		}
+		return &models.GetPromptResponse{Messages: messages}, nil
+	}))
+	for _, name := range []string{"public", "internal"} {
+		s.RegisterPrompt(models.Prompt{Name: name}, PromptHandlerFunc(func(ctx context.Context, args map[string]string) (*models.GetPromptResponse, error) {
+			return &models.GetPromptResponse{Messages: []models.PromptMessage{{Role: "user", Content: models.Content{Type: "text", Text: "hi"}}}}, nil
+		}))
+	}
+	s.SetAuthenticator(NewTokenAuthenticator(map[string]Identity{
+		"dashboard-token": {Name: "dashboard", Tools: []string{"echo"}, Resources: []string{"stock://*/quote"}, Prompts: []string{"public", "embedding"}},
+	}))
+	var audit lockedBuffer
+	s.SetAuditLog(&audit)
+
+	client := NewClient(nil, log.New(io.Discard, "", 0))
+	client.SetAuthToken("dashboard-token")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.ConnectTCP(ctx, serveTCP(t, s)); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.ReadResource(ctx, "stock://AAPL/quote"); err != nil {
+		t.Errorf("reading an allowed resource: %v", err)
+	}
+	if err := client.SubscribeResource(ctx, "stock://AAPL/quote"); err != nil {
+		t.Errorf("subscribing to an allowed resource: %v", err)
+	}
+	if _, err := client.GetPrompt(ctx, "public", nil); err != nil {
+		t.Errorf("getting an allowed prompt: %v", err)
+	}
+
+	if _, err := client.ReadResource(ctx, "stock://AAPL/indicators"); err == nil || !strings.Contains(err.Error(), "Forbidden") {
+		t.Errorf("reading a resource outside the allow-list: got %v, want Forbidden", err)
+	}
+	if err := client.SubscribeResource(ctx, "stock://AAPL/indicators"); err == nil || !strings.Contains(err.Error(), "Forbidden") {
+		t.Errorf("subscribing to a resource outside the allow-list: got %v, want Forbidden", err)
+	}
+	if _, err := client.GetPrompt(ctx, "internal", nil); err == nil || !strings.Contains(err.Error(), "Forbidden") {
+		t.Errorf("getting a prompt outside the allow-list: got %v, want Forbidden", err)
+	}
+
+	embedded, err := client.GetPrompt(ctx, "embedding", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := embedded.Messages[0].Content; content.Resource == nil || content.Resource.URI != "stock://AAPL/quote" {
+		t.Errorf("allowed resource not embedded: %+v", content)
+	}
+	if content := embedded.Messages[1].Content; content.Resource != nil || !strings.Contains(content.Text, "resource not allowed") {
+		t.Errorf("resource outside the allow-list embedded: %+v", content)
+	}
+
+	resources, err := client.ListResources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 1 || resources[0].URI != "stock://AAPL/quote" {
+		t.Errorf("resources/list = %+v, want only the allowed resource", resources)
+	}
+	templates, err := client.ListResourceTemplates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates[0].URITemplate != "stock://{symbol}/quote" {
+		t.Errorf("resources/templates/list = %+v, want only the allowed template", templates)
+	}
+	prompts, err := client.ListPrompts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 2 || prompts[0].Name != "embedding" || prompts[1].Name != "public" {
+		t.Errorf("prompts/list = %+v, want only the allowed prompts", prompts)
+	}
+
+	entries := audit.String()
+	for _, want := range []string{
+		`"event":"allowed","identity":"dashboard","peer":`,
+		`"resource":"stock://AAPL/quote"`,
+		`"event":"denied"`,
+		`"resource":"stock://AAPL/indicators","reason":"resource not allowed"`,
+		`"prompt":"internal","reason":"prompt not allowed"`,
+		`"event":"denied","identity":"dashboard","peer":`,
+	} {
+		if !strings.Contains(entries, want) {
+			t.Errorf("audit log lacks %s:\n%s", want, entries)
+		}
+	}
+}
+
+// newToolAuthServer offers get_stock_price and export_analysis, of which the
+// dashboard token may only call the first.
+func newToolAuthServer() *Server {
+	s := newTestServer()
+	for _, name := range []string{"get_stock_price", "export_analysis"} {
+		name := name
+		s.RegisterTool(name, name, nil, ToolHandlerFunc(func(ctx context.Context, args map[string]interface{}) (*models.CallToolResponse, error) {
+			return &models.CallToolResponse{Content: []models.Content{{Type: "text", Text: name}}}, nil
+		}))
+	}
+	s.SetAuthenticator(NewTokenAuthenticator(map[string]Identity{
+		"dashboard-token": {Name: "dashboard", Tools: []string{"get_stock_price"}},
+		"admin-token":     {Name: "admin", Tools: []string{"*"}},
+	}))
+	s.SetAuditLog(io.Discard)
+	return s
+}
+
+func TestAuthenticateAndAuthorizeToolCalls(t *testing.T) {
+	address := serveTCP(t, newToolAuthServer())
+
+	conn := dialRaw(t, address)
+	if code := errorCode(conn.call("tools/list", nil)); code != codeUnauthorized {
+		t.Errorf("tools/list before initialize: code %d, want %d", code, codeUnauthorized)
+	}
+	if code := errorCode(conn.initialize("")); code != codeUnauthorized {
+		t.Errorf("initialize without a token: code %d, want %d", code, codeUnauthorized)
+	}
+	if code := errorCode(conn.initialize("guessed-token")); code != codeUnauthorized {
+		t.Errorf("initialize with an unknown token: code %d, want %d", code, codeUnauthorized)
+	}
+	if code := errorCode(conn.call("tools/call", models.CallToolRequest{Name: "get_stock_price"})); code != codeUnauthorized {
+		t.Errorf("tools/call after failed initializes: code %d, want %d", code, codeUnauthorized)
+	}
+
+	conn = dialRaw(t, address)
+	if response := conn.initialize("dashboard-token"); response.Error != nil {
+		t.Fatalf("initialize: %+v", response.Error)
+	}
+	if response := conn.call("tools/call", models.CallToolRequest{Name: "get_stock_price"}); response.Error != nil {
+		t.Errorf("calling an allowed tool: %+v", response.Error)
+	}
+	if code := errorCode(conn.call("tools/call", models.CallToolRequest{Name: "export_analysis"})); code != codeForbidden {
+		t.Errorf("calling a tool outside the allow-list: code %d, want %d", code, codeForbidden)
+	}
+
+	var list models.ListToolsResponse
+	if err := json.Unmarshal(conn.call("tools/list", nil).Result, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tools) != 1 || list.Tools[0].Name != "get_stock_price" {
+		t.Errorf("tools/list = %+v, want only get_stock_price", list.Tools)
+	}
+}
+
+// postMCP posts body to the Streamable HTTP endpoint with token and session,
+// when given, and asks for a JSON answer.
+func postMCP(t *testing.T, endpoint, token, session, body string) *http.Response {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	if session != "" {
+		request.Header.Set(SessionHeader, session)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+const initializeBody = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"curl","version":"1.0.0"}}}`
+
+func TestHTTPAuthentication(t *testing.T) {
+	httpServer := httptest.NewServer(newToolAuthServer().HTTPHandler())
+	defer httpServer.Close()
+
+	response := postMCP(t, httpServer.URL, "", "", initializeBody)
+	if response.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(response.Header.Get("WWW-Authenticate"), "Bearer") {
+		t.Errorf("without Authorization: status %d, WWW-Authenticate %q, want 401 and a Bearer challenge", response.StatusCode, response.Header.Get("WWW-Authenticate"))
+	}
+	if response := postMCP(t, httpServer.URL, "guessed-token", "", initializeBody); response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("with an unknown token: status %d, want 401", response.StatusCode)
+	}
+
+	response = postMCP(t, httpServer.URL, "dashboard-token", "", initializeBody)
+	session := response.Header.Get(SessionHeader)
+	if response.StatusCode != http.StatusOK || session == "" {
+		t.Fatalf("initialize: status %d, session %q", response.StatusCode, session)
+	}
+
+	call := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_stock_price"}}`
+	if response := postMCP(t, httpServer.URL, "admin-token", session, call); response.StatusCode != http.StatusForbidden {
+		t.Errorf("session used with another identity's token: status %d, want 403", response.StatusCode)
+	}
+	if response := postMCP(t, httpServer.URL, "dashboard-token", session, call); response.StatusCode != http.StatusOK {
+		t.Errorf("session used with its own token: status %d, want 200", response.StatusCode)
+	}
+}
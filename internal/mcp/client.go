@@ -1,12 +1,19 @@
 package mcp
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,28 +22,92 @@ import (
 
 type Client struct {
 	serverCommand []string
+	serverEnv     []string
+	serverDir     string
 	serverCmd     *exec.Cmd
 	stdin         io.WriteCloser
 	stdout        io.ReadCloser
 	stderr        io.ReadCloser
 	conn          net.Conn // for TCP connections
+	transport     io.Closer // for HTTP and WebSocket connections
 	encoder       *json.Encoder
 	decoder       *json.Decoder
 	nextID        int
 	mu            sync.Mutex
+	writeMu       sync.Mutex
 	logger        *log.Logger
 	isNetworkConn bool
+	authToken     string
+
+	// pending maps the JSON-RPC id of every in-flight request to the channel
+	// its response is delivered on by readLoop.
+	pendingMu sync.Mutex
+	pending   map[string]chan *models.JSONRPCResponse
+	done      chan struct{}
+	readErr   error
+
+	handlersMu sync.RWMutex
+	handlers   map[string][]NotificationHandler
+
+	progressMu       sync.Mutex
+	progressHandlers map[string]ProgressHandler
 }
 
+// NotificationHandler receives server notifications registered with
+// OnNotification. It runs on the reader goroutine and must not block.
+type NotificationHandler func(method string, params json.RawMessage)
+
+// ProgressHandler receives progress updates for a single tool call.
+type ProgressHandler func(progress models.ProgressNotification)
+
+var ErrClientClosed = errors.New("client connection closed")
+
 func NewClient(serverCommand []string, logger *log.Logger) *Client {
 	return &Client{
-		serverCommand: serverCommand,
-		nextID:        1,
-		logger:        logger,
+		serverCommand:    serverCommand,
+		nextID:           1,
+		logger:           logger,
+		handlers:         make(map[string][]NotificationHandler),
+		progressHandlers: make(map[string]ProgressHandler),
 	}
 }
 
-func (c *Client) Connect() error {
+// OnNotification registers handler for server notifications with the given
+// method, e.g. "notifications/progress". Handlers can be registered at any
+// time, including before Connect.
+func (c *Client) OnNotification(method string, handler NotificationHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.handlers[method] = append(c.handlers[method], handler)
+}
+
+// SetProcessEnv adds KEY=value entries to the environment the server process
+// inherits. Call it before Connect.
+func (c *Client) SetProcessEnv(env map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, value := range env {
+		c.serverEnv = append(c.serverEnv, key+"="+value)
+	}
+}
+
+// SetWorkingDir sets the directory the server process is started in. Call it
+// before Connect.
+func (c *Client) SetWorkingDir(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.serverDir = dir
+}
+
+// SetAuthToken sets the bearer token presented to servers that require
+// authentication. Call it before connecting.
+func (c *Client) SetAuthToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.authToken = token
+}
+
+func (c *Client) Connect(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -50,6 +121,11 @@ func (c *Client) Connect() error {
 	} else {
 		cmd = exec.Command(c.serverCommand[0], c.serverCommand[1:]...)
 	}
+	if len(c.serverEnv) > 0 {
+		cmd.Env = append(os.Environ(), c.serverEnv...)
+	}
+	cmd.Dir = c.serverDir
+	detachProcessGroup(cmd)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -77,8 +153,14 @@ func (c *Client) Connect() error {
 	}
 
 
-	time.Sleep(100 * time.Millisecond)
-	
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		cmd.Wait()
+		return ctx.Err()
+	}
+
 	if cmd.ProcessState != nil && cmd.ProcessState.Exited() {
 		stdin.Close()
 		stdout.Close()
@@ -92,22 +174,48 @@ func (c *Client) Connect() error {
 	c.stderr = stderr
 	c.encoder = json.NewEncoder(stdin)
 	c.decoder = json.NewDecoder(stdout)
+	c.startReader()
+
+	// Server logs reach the host through notifications/message; stderr is only
+	// drained so a chatty server never blocks on a full pipe.
+	go io.Copy(io.Discard, stderr)
 
 	c.logger.Printf("Connected to MCP server: %s", c.serverCommand[0])
 	return nil
 }
 
 // ConnectTCP connects to a remote MCP server via TCP
-func (c *Client) ConnectTCP(address string) error {
+func (c *Client) ConnectTCP(ctx context.Context, address string) error {
+	return c.dial(ctx, address, nil)
+}
+
+// ConnectTLS is ConnectTCP over TLS; see ClientTLSOptions for verification
+// and pinning.
+func (c *Client) ConnectTLS(ctx context.Context, address string, config *tls.Config) error {
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return c.dial(ctx, address, config)
+}
+
+func (c *Client) dial(ctx context.Context, address string, tlsConfig *tls.Config) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.serverCmd != nil || c.conn != nil {
+	if c.serverCmd != nil || c.conn != nil || c.transport != nil {
 		return fmt.Errorf("client already connected")
 	}
 
 	// Set connection timeout
-	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		tlsDialer := tls.Dialer{NetDialer: &dialer, Config: tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to connect to %s: %w", address, err)
 	}
@@ -116,12 +224,17 @@ func (c *Client) ConnectTCP(address string) error {
 	c.encoder = json.NewEncoder(conn)
 	c.decoder = json.NewDecoder(conn)
 	c.isNetworkConn = true
+	c.startReader()
 
-	c.logger.Printf("TCP connection established to: %s", address)
+	if tlsConfig != nil {
+		c.logger.Printf("TLS connection established to: %s", address)
+	} else {
+		c.logger.Printf("TCP connection established to: %s", address)
+	}
 	return nil
 }
 
-func (c *Client) Initialize() (*models.InitializeResponse, error) {
+func (c *Client) Initialize(ctx context.Context) (*models.InitializeResponse, error) {
 	request := models.JSONRPCRequest{
 		JSONRPC: "2.0",
 		ID:      c.getNextID(),
@@ -137,14 +250,19 @@ func (c *Client) Initialize() (*models.InitializeResponse, error) {
 			},
 		},
 	}
+	if c.authToken != "" {
+		params := request.Params.(models.InitializeRequest)
+		params.Meta = &models.InitializeMeta{Authorization: "Bearer " + c.authToken}
+		request.Params = params
+	}
 
-	response, err := c.sendRequest(request)
+	response, err := c.sendRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
 	if response.Error != nil {
-		return nil, fmt.Errorf("initialize error: %s", response.Error.Message)
+		return nil, fmt.Errorf("initialize error: %s", describeError(response.Error))
 	}
 
 	var initResponse models.InitializeResponse
@@ -153,12 +271,7 @@ func (c *Client) Initialize() (*models.InitializeResponse, error) {
 		return nil, fmt.Errorf("failed to parse initialize response: %w", err)
 	}
 
-	notification := models.JSONRPCRequest{
-		JSONRPC: "2.0",
-		Method:  "notifications/initialized",
-	}
-
-	if err := c.encoder.Encode(notification); err != nil {
+	if err := c.notify("notifications/initialized", nil); err != nil {
 		return nil, fmt.Errorf("failed to send initialized notification: %w", err)
 	}
 
@@ -166,20 +279,20 @@ func (c *Client) Initialize() (*models.InitializeResponse, error) {
 	return &initResponse, nil
 }
 
-func (c *Client) ListTools() ([]models.Tool, error) {
+func (c *Client) ListTools(ctx context.Context) ([]models.Tool, error) {
 	request := models.JSONRPCRequest{
 		JSONRPC: "2.0",
 		ID:      c.getNextID(),
 		Method:  "tools/list",
 	}
 
-	response, err := c.sendRequest(request)
+	response, err := c.sendRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
 	if response.Error != nil {
-		return nil, fmt.Errorf("list tools error: %s", response.Error.Message)
+		return nil, fmt.Errorf("list tools error: %s", describeError(response.Error))
 	}
 
 	var listResponse models.ListToolsResponse
@@ -191,24 +304,175 @@ func (c *Client) ListTools() ([]models.Tool, error) {
 	return listResponse.Tools, nil
 }
 
-func (c *Client) CallTool(name string, arguments map[string]interface{}) (*models.CallToolResponse, error) {
+func (c *Client) ListResources(ctx context.Context) ([]models.Resource, error) {
+	var listResponse models.ListResourcesResponse
+	if err := c.call(ctx, "resources/list", nil, &listResponse); err != nil {
+		return nil, fmt.Errorf("list resources error: %w", err)
+	}
+	return listResponse.Resources, nil
+}
+
+func (c *Client) ListResourceTemplates(ctx context.Context) ([]models.ResourceTemplate, error) {
+	var listResponse models.ListResourceTemplatesResponse
+	if err := c.call(ctx, "resources/templates/list", nil, &listResponse); err != nil {
+		return nil, fmt.Errorf("list resource templates error: %w", err)
+	}
+	return listResponse.ResourceTemplates, nil
+}
+
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]models.ResourceContents, error) {
+	var readResponse models.ReadResourceResponse
+	if err := c.call(ctx, "resources/read", models.ReadResourceRequest{URI: uri}, &readResponse); err != nil {
+		return nil, fmt.Errorf("read resource error: %w", err)
+	}
+	return readResponse.Contents, nil
+}
+
+// SubscribeResource asks the server to send notifications/resources/updated
+// whenever uri changes. Register a handler with OnNotification to see them.
+func (c *Client) SubscribeResource(ctx context.Context, uri string) error {
+	if err := c.call(ctx, "resources/subscribe", models.SubscribeRequest{URI: uri}, &struct{}{}); err != nil {
+		return fmt.Errorf("subscribe error: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) UnsubscribeResource(ctx context.Context, uri string) error {
+	if err := c.call(ctx, "resources/unsubscribe", models.UnsubscribeRequest{URI: uri}, &struct{}{}); err != nil {
+		return fmt.Errorf("unsubscribe error: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) ListPrompts(ctx context.Context) ([]models.Prompt, error) {
+	var listResponse models.ListPromptsResponse
+	if err := c.call(ctx, "prompts/list", nil, &listResponse); err != nil {
+		return nil, fmt.Errorf("list prompts error: %w", err)
+	}
+	return listResponse.Prompts, nil
+}
+
+func (c *Client) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*models.GetPromptResponse, error) {
+	var getResponse models.GetPromptResponse
+	if err := c.call(ctx, "prompts/get", models.GetPromptRequest{Name: name, Arguments: arguments}, &getResponse); err != nil {
+		return nil, fmt.Errorf("get prompt error: %w", err)
+	}
+	return &getResponse, nil
+}
+
+// describeError folds string or string-list error data into the message so
+// validation details reach the user.
+func describeError(rpcErr *models.JSONRPCError) string {
+	switch data := rpcErr.Data.(type) {
+	case string:
+		if data != "" {
+			return fmt.Sprintf("%s: %s", rpcErr.Message, data)
+		}
+	case []interface{}:
+		details := make([]string, 0, len(data))
+		for _, item := range data {
+			if text, ok := item.(string); ok {
+				details = append(details, text)
+			}
+		}
+		if len(details) > 0 {
+			return fmt.Sprintf("%s: %s", rpcErr.Message, strings.Join(details, "; "))
+		}
+	}
+	return rpcErr.Message
+}
+
+// call sends a request and decodes its result into result.
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
 	request := models.JSONRPCRequest{
 		JSONRPC: "2.0",
 		ID:      c.getNextID(),
+		Method:  method,
+		Params:  params,
+	}
+
+	response, err := c.sendRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	if response.Error != nil {
+		return errors.New(describeError(response.Error))
+	}
+
+	resultBytes, _ := json.Marshal(response.Result)
+	if err := json.Unmarshal(resultBytes, result); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", method, err)
+	}
+	return nil
+}
+
+// SetLogLevel asks the server to forward log messages at level or above as
+// notifications/message. Register a handler with OnNotification to see them.
+func (c *Client) SetLogLevel(ctx context.Context, level string) error {
+	request := models.JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      c.getNextID(),
+		Method:  "logging/setLevel",
+		Params:  models.SetLevelRequest{Level: level},
+	}
+
+	response, err := c.sendRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	if response.Error != nil {
+		return fmt.Errorf("set log level error: %s", describeError(response.Error))
+	}
+
+	return nil
+}
+
+// CallTool invokes a tool and waits for its result. Cancelling ctx stops the
+// wait and tells the server to abort the call.
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*models.CallToolResponse, error) {
+	return c.CallToolWithProgress(ctx, name, arguments, nil)
+}
+
+// CallToolWithProgress is like CallTool but asks the server for progress
+// notifications and passes them to onProgress while the call runs.
+func (c *Client) CallToolWithProgress(ctx context.Context, name string, arguments map[string]interface{}, onProgress ProgressHandler) (*models.CallToolResponse, error) {
+	id := c.getNextID()
+	params := models.CallToolRequest{
+		Name:      name,
+		Arguments: arguments,
+	}
+
+	if onProgress != nil {
+		token := fmt.Sprintf("progress-%d", id)
+		params.Meta = &models.RequestMeta{ProgressToken: token}
+
+		c.progressMu.Lock()
+		c.progressHandlers[token] = onProgress
+		c.progressMu.Unlock()
+
+		defer func() {
+			c.progressMu.Lock()
+			delete(c.progressHandlers, token)
+			c.progressMu.Unlock()
+		}()
+	}
+
+	request := models.JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      id,
 		Method:  "tools/call",
-		Params: models.CallToolRequest{
-			Name:      name,
-			Arguments: arguments,
-		},
+		Params:  params,
 	}
 
-	response, err := c.sendRequest(request)
+	response, err := c.sendRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
 	if response.Error != nil {
-		return nil, fmt.Errorf("call tool error: %s", response.Error.Message)
+		return nil, fmt.Errorf("call tool error: %s", describeError(response.Error))
 	}
 
 	var callResponse models.CallToolResponse
@@ -220,27 +484,226 @@ func (c *Client) CallTool(name string, arguments map[string]interface{}) (*model
 	return &callResponse, nil
 }
 
-func (c *Client) sendRequest(request models.JSONRPCRequest) (*models.JSONRPCResponse, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Client) sendRequest(ctx context.Context, request models.JSONRPCRequest) (*models.JSONRPCResponse, error) {
+	key := fmt.Sprint(request.ID)
+	responseCh := make(chan *models.JSONRPCResponse, 1)
 
-	if c.encoder == nil {
+	c.pendingMu.Lock()
+	if c.pending == nil {
+		c.pendingMu.Unlock()
 		return nil, fmt.Errorf("client not connected")
 	}
+	if c.readErr != nil {
+		err := c.readErr
+		c.pendingMu.Unlock()
+		return nil, err
+	}
+	c.pending[key] = responseCh
+	done := c.done
+	c.pendingMu.Unlock()
 
-	if err := c.encoder.Encode(request); err != nil {
+	if err := c.write(request); err != nil {
+		c.removePending(key)
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	var response models.JSONRPCResponse
-	if err := c.decoder.Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	select {
+	case response := <-responseCh:
+		return response, nil
+	case <-done:
+		// The reader may have delivered the response just before stopping.
+		select {
+		case response := <-responseCh:
+			return response, nil
+		default:
+		}
+		c.removePending(key)
+		return nil, fmt.Errorf("failed to decode response: %w", c.readError())
+	case <-ctx.Done():
+		c.removePending(key)
+		// The protocol forbids cancelling initialize.
+		if request.Method != "initialize" {
+			c.cancelRequest(request.ID, ctx.Err())
+		}
+		return nil, fmt.Errorf("%s request aborted: %w", request.Method, ctx.Err())
+	}
+}
+
+// cancelRequest tells the server we are no longer waiting for request id.
+func (c *Client) cancelRequest(id interface{}, reason error) {
+	err := c.notify("notifications/cancelled", models.CancelledNotification{
+		RequestID: id,
+		Reason:    reason.Error(),
+	})
+	if err != nil {
+		c.logger.Printf("Failed to send cancellation for request %v: %v", id, err)
+	}
+}
+
+func (c *Client) notify(method string, params interface{}) error {
+	return c.write(models.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func (c *Client) write(message interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.encoder == nil {
+		return fmt.Errorf("client not connected")
+	}
+	return c.encoder.Encode(message)
+}
+
+func (c *Client) startReader() {
+	c.pendingMu.Lock()
+	c.pending = make(map[string]chan *models.JSONRPCResponse)
+	c.done = make(chan struct{})
+	c.readErr = nil
+	c.pendingMu.Unlock()
+
+	go c.readLoop(c.decoder, c.done)
+}
+
+// readLoop decodes every message from the server, delivering responses to
+// the request waiting on the same id and notifications to their handlers.
+func (c *Client) readLoop(decoder *json.Decoder, done chan struct{}) {
+	var err error
+	for {
+		var message models.JSONRPCMessage
+		if err = decoder.Decode(&message); err != nil {
+			break
+		}
+		c.dispatch(&message)
+	}
+
+	if err == io.EOF {
+		err = ErrClientClosed
+	}
+
+	c.pendingMu.Lock()
+	c.readErr = err
+	c.pendingMu.Unlock()
+	close(done)
+}
+
+func (c *Client) dispatch(message *models.JSONRPCMessage) {
+	switch {
+	case message.IsResponse():
+		key := idKey(message.ID)
+
+		c.pendingMu.Lock()
+		responseCh, exists := c.pending[key]
+		delete(c.pending, key)
+		c.pendingMu.Unlock()
+
+		if !exists {
+			c.logger.Printf("Dropping response for unknown request id %s", key)
+			return
+		}
+
+		responseCh <- &models.JSONRPCResponse{
+			JSONRPC: message.JSONRPC,
+			ID:      message.ID,
+			Result:  message.Result,
+			Error:   message.Error,
+		}
+
+	case message.IsNotification():
+		if message.Method == "notifications/progress" {
+			c.dispatchProgress(message.Params)
+		}
+
+		c.handlersMu.RLock()
+		handlers := c.handlers[message.Method]
+		c.handlersMu.RUnlock()
+
+		for _, handler := range handlers {
+			handler(message.Method, message.Params)
+		}
+
+	default:
+		c.handleServerRequest(message)
+	}
+}
+
+func (c *Client) dispatchProgress(params json.RawMessage) {
+	var progress models.ProgressNotification
+	if err := json.Unmarshal(params, &progress); err != nil {
+		c.logger.Printf("Ignoring malformed progress notification: %v", err)
+		return
 	}
 
-	return &response, nil
+	c.progressMu.Lock()
+	handler, exists := c.progressHandlers[fmt.Sprint(progress.ProgressToken)]
+	c.progressMu.Unlock()
+
+	if exists {
+		handler(progress)
+	}
+}
+
+// handleServerRequest answers requests initiated by the server. Only ping is
+// supported; everything else is rejected so the server does not wait forever.
+func (c *Client) handleServerRequest(message *models.JSONRPCMessage) {
+	response := models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      message.ID,
+	}
+
+	if message.Method == "ping" {
+		response.Result = struct{}{}
+	} else {
+		response.Error = &models.JSONRPCError{
+			Code:    -32601,
+			Message: "Method not found",
+			Data:    message.Method,
+		}
+	}
+
+	go func() {
+		if err := c.write(response); err != nil {
+			c.logger.Printf("Failed to answer server request %s: %v", message.Method, err)
+		}
+	}()
+}
+
+func (c *Client) removePending(key string) {
+	c.pendingMu.Lock()
+	delete(c.pending, key)
+	c.pendingMu.Unlock()
+}
+
+func (c *Client) readError() error {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if c.readErr == nil {
+		return ErrClientClosed
+	}
+	return c.readErr
+}
+
+// idKey normalizes a raw JSON-RPC id so that it matches fmt.Sprint of the id
+// we sent: numbers lose any fractional zeros and strings keep their quotes.
+func idKey(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if n, err := strconv.ParseFloat(string(raw), 64); err == nil {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str
+	}
+	return string(raw)
 }
 
 func (c *Client) getNextID() int {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
 	id := c.nextID
 	c.nextID++
 	return id
@@ -250,6 +713,14 @@ func (c *Client) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.transport != nil {
+		c.logger.Printf("Closing connection to MCP server")
+		err := c.transport.Close()
+		c.transport = nil
+		c.clearEncoder()
+		return err
+	}
+
 	if c.isNetworkConn {
 		// Close TCP connection
 		if c.conn != nil {
@@ -257,6 +728,7 @@ func (c *Client) Close() error {
 			err := c.conn.Close()
 			c.conn = nil
 			c.isNetworkConn = false
+			c.clearEncoder()
 			return err
 		}
 		return nil
@@ -286,6 +758,13 @@ func (c *Client) Close() error {
 	}
 
 	c.serverCmd = nil
+	c.clearEncoder()
 	c.logger.Printf("Disconnected from MCP server: %s", c.serverCommand[0])
 	return nil
 }
+
+func (c *Client) clearEncoder() {
+	c.writeMu.Lock()
+	c.encoder = nil
+	c.writeMu.Unlock()
+}
@@ -0,0 +1,223 @@
+package mcp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// newTestServer returns a server with an "echo" tool that answers with its
+// required "text" argument after "delayMs" milliseconds.
+func newTestServer() *Server {
+	s := NewServer("test", "1.0.0")
+	s.logger = log.New(io.Discard, "", 0)
+	schema := json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"},"delayMs":{"type":"number"}},"required":["text"]}`)
+	s.RegisterTool("echo", "Echo the text", schema, ToolHandlerFunc(func(ctx context.Context, args map[string]interface{}) (*models.CallToolResponse, error) {
+		if delay, ok := args["delayMs"].(float64); ok {
+			select {
+			case <-time.After(time.Duration(delay) * time.Millisecond):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		text, _ := args["text"].(string)
+		return &models.CallToolResponse{Content: []models.Content{{Type: "text", Text: text}}}, nil
+	}))
+	return s
+}
+
+// listenAndServe runs s.ListenAndServe on a loopback port and returns the
+// address it listens on and the channel its result arrives on.
+func listenAndServe(t *testing.T, s *Server, tlsConfig *tls.Config) (string, <-chan error) {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() { result <- s.ListenAndServe("127.0.0.1:0", tlsConfig) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.shutdownMu.Lock()
+		for listener := range s.listeners {
+			s.shutdownMu.Unlock()
+			return listener.Addr().String(), result
+		}
+		s.shutdownMu.Unlock()
+		select {
+		case err := <-result:
+			t.Fatalf("ListenAndServe: %v", err)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	t.Fatal("server did not start listening")
+	return "", nil
+}
+
+// serveTCP serves s with ListenAndServe on a loopback port until the test
+// ends and returns its address.
+func serveTCP(t *testing.T, s *Server) string {
+	t.Helper()
+	return serveUntilCleanup(t, s, nil)
+}
+
+func serveUntilCleanup(t *testing.T, s *Server, tlsConfig *tls.Config) string {
+	t.Helper()
+	address, _ := listenAndServe(t, s, tlsConfig)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	return address
+}
+
+func connectTCP(t *testing.T, address string) *Client {
+	t.Helper()
+	client := NewClient(nil, log.New(io.Discard, "", 0))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.ConnectTCP(ctx, address); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// rawConn speaks JSON-RPC to a server directly, for tests that look at
+// error codes the Client folds into messages.
+type rawConn struct {
+	t       *testing.T
+	conn    net.Conn
+	encoder *json.Encoder
+	decoder *json.Decoder
+	nextID  int
+}
+
+func dialRaw(t *testing.T, address string) *rawConn {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &rawConn{t: t, conn: conn, encoder: json.NewEncoder(conn), decoder: json.NewDecoder(conn)}
+}
+
+// call sends a request and returns its response, skipping notifications.
+func (c *rawConn) call(method string, params interface{}) *models.JSONRPCMessage {
+	c.t.Helper()
+	c.nextID++
+	if err := c.encoder.Encode(models.JSONRPCRequest{JSONRPC: "2.0", ID: c.nextID, Method: method, Params: params}); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		var message models.JSONRPCMessage
+		if err := c.decoder.Decode(&message); err != nil {
+			c.t.Fatalf("%s: %v", method, err)
+		}
+		if message.IsResponse() && string(message.ID) == fmt.Sprint(c.nextID) {
+			return &message
+		}
+	}
+}
+
+// initialize sends initialize with token in _meta.authorization, if any.
+func (c *rawConn) initialize(token string) *models.JSONRPCMessage {
+	c.t.Helper()
+	params := models.InitializeRequest{ProtocolVersion: "2025-03-26", ClientInfo: models.ClientInfo{Name: "raw", Version: "1.0.0"}}
+	if token != "" {
+		params.Meta = &models.InitializeMeta{Authorization: "Bearer " + token}
+	}
+	return c.call("initialize", params)
+}
+
+// errorCode is the code of response's error, or 0 for a result.
+func errorCode(response *models.JSONRPCMessage) int {
+	if response.Error == nil {
+		return 0
+	}
+	return response.Error.Code
+}
+
+func TestClientMultiplexesConcurrentCalls(t *testing.T) {
+	client := connectTCP(t, serveTCP(t, newTestServer()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Earlier calls take longer, so the responses come back out of order.
+	const calls = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			want := fmt.Sprintf("call-%d", i)
+			result, err := client.CallTool(ctx, "echo", map[string]interface{}{
+				"text":    want,
+				"delayMs": (calls - i) * 20,
+			})
+			if err != nil {
+				errs <- fmt.Errorf("%s: %w", want, err)
+				return
+			}
+			if len(result.Content) != 1 || result.Content[0].Text != want {
+				errs <- fmt.Errorf("%s: got %+v", want, result.Content)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestClientReturnsResponseDeliveredBeforeClose(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	client := NewClient(nil, log.New(io.Discard, "", 0))
+	client.conn = clientConn
+	client.encoder = json.NewEncoder(clientConn)
+	client.decoder = json.NewDecoder(clientConn)
+	client.isNetworkConn = true
+	client.startReader()
+	defer client.Close()
+
+	// The server answers and hangs up straight away, so the reader stops
+	// right after delivering the response.
+	go func() {
+		defer serverConn.Close()
+		var request models.JSONRPCMessage
+		if err := json.NewDecoder(serverConn).Decode(&request); err != nil {
+			return
+		}
+		json.NewEncoder(serverConn).Encode(models.JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      request.ID,
+			Result:  models.CallToolResponse{Content: []models.Content{{Type: "text", Text: "done"}}},
+		})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := client.CallTool(ctx, "echo", nil)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "done" {
+		t.Fatalf("got %+v", result.Content)
+	}
+}
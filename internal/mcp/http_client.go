@@ -0,0 +1,302 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSSELine bounds a single SSE line; tool results arrive as one data line.
+const maxSSELine = 16 << 20
+
+// httpTransport carries client messages over Streamable HTTP. Each message
+// written by the client's encoder is POSTed; whatever the server answers,
+// inline JSON or an SSE stream, is fed to the client's decoder through a
+// pipe together with the messages of the session's GET stream.
+type httpTransport struct {
+	endpoint string
+	token    string
+	client   *http.Client
+	logger   *log.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	sessionID string
+	listening bool
+
+	reader  *io.PipeReader
+	writer  *io.PipeWriter
+	writeMu sync.Mutex
+	wg      sync.WaitGroup
+}
+
+func newHTTPTransport(endpoint, token string, logger *log.Logger) *httpTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, writer := io.Pipe()
+
+	return &httpTransport{
+		endpoint: endpoint,
+		token:    token,
+		client: &http.Client{
+			// Streams last as long as the calls they carry, so only the wait
+			// for response headers is bounded.
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 60 * time.Second,
+			},
+		},
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+		reader: reader,
+		writer: writer,
+	}
+}
+
+// ConnectHTTP connects to a server speaking the Streamable HTTP transport at
+// endpoint, e.g. http://localhost:8080/mcp. The session starts with
+// Initialize.
+func (c *Client) ConnectHTTP(ctx context.Context, endpoint string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.serverCmd != nil || c.conn != nil || c.transport != nil {
+		return fmt.Errorf("client already connected")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	transport := newHTTPTransport(endpoint, c.authToken, c.logger)
+	c.transport = transport
+	c.encoder = json.NewEncoder(transport)
+	c.decoder = json.NewDecoder(transport.reader)
+	c.startReader()
+
+	c.logger.Printf("Using Streamable HTTP transport: %s", endpoint)
+	return nil
+}
+
+// Write POSTs one encoded message. It returns once the server has accepted
+// it; the answer is read in the background.
+func (t *httpTransport) Write(p []byte) (int, error) {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.endpoint, bytes.NewReader(p))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	if id := resp.Header.Get(SessionHeader); id != "" {
+		t.startListening(id)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		resp.Body.Close()
+		return len(p), nil
+	case resp.StatusCode == http.StatusNotFound && t.session() != "":
+		resp.Body.Close()
+		return 0, fmt.Errorf("session %s expired on the server", t.session())
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return 0, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer resp.Body.Close()
+		if err := t.readBody(resp); err != nil && t.ctx.Err() == nil {
+			t.logger.Printf("Failed to read HTTP response: %v", err)
+		}
+	}()
+	return len(p), nil
+}
+
+func (t *httpTransport) session() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID
+}
+
+// setHeaders adds the session id and credentials every request carries.
+func (t *httpTransport) setHeaders(req *http.Request) {
+	if id := t.session(); id != "" {
+		req.Header.Set(SessionHeader, id)
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+}
+
+// startListening records the session id and opens the GET stream once.
+func (t *httpTransport) startListening(id string) {
+	t.mu.Lock()
+	t.sessionID = id
+	start := !t.listening
+	t.listening = true
+	t.mu.Unlock()
+
+	if start {
+		t.wg.Add(1)
+		go t.listen()
+	}
+}
+
+// listen keeps the GET stream for server-initiated messages open,
+// reconnecting with backoff until the transport is closed. Servers that do
+// not offer one answer 405.
+func (t *httpTransport) listen() {
+	defer t.wg.Done()
+
+	delay := time.Second
+	for t.ctx.Err() == nil {
+		req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.endpoint, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		t.setHeaders(req)
+
+		resp, err := t.client.Do(req)
+		switch {
+		case err != nil:
+		case resp.StatusCode == http.StatusMethodNotAllowed:
+			resp.Body.Close()
+			t.logger.Printf("Server offers no GET stream; only responses will be received")
+			return
+		case resp.StatusCode == http.StatusNotFound:
+			resp.Body.Close()
+			return
+		case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+			resp.Body.Close()
+			t.logger.Printf("GET stream refused: HTTP %d", resp.StatusCode)
+			return
+		case resp.StatusCode != http.StatusOK:
+			resp.Body.Close()
+		default:
+			delay = time.Second
+			err = readEventStream(resp.Body, t.deliver)
+			resp.Body.Close()
+		}
+
+		if t.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			t.logger.Printf("GET stream interrupted: %v", err)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-t.ctx.Done():
+			return
+		}
+		if delay < 30*time.Second {
+			delay *= 2
+		}
+	}
+}
+
+func (t *httpTransport) readBody(resp *http.Response) error {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readEventStream(resp.Body, t.deliver)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+
+	if body[0] != '[' {
+		t.deliver(body)
+		return nil
+	}
+
+	var messages []json.RawMessage
+	if err := json.Unmarshal(body, &messages); err != nil {
+		return fmt.Errorf("failed to parse batch response: %w", err)
+	}
+	for _, message := range messages {
+		t.deliver(message)
+	}
+	return nil
+}
+
+// deliver hands one message to the client's decoder.
+func (t *httpTransport) deliver(message []byte) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	t.writer.Write(append(message, '\n'))
+}
+
+// readEventStream calls onMessage with the data of every "message" event.
+func readEventStream(body io.Reader, onMessage func([]byte)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELine)
+
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 && (event == "" || event == "message") {
+				onMessage([]byte(strings.Join(data, "\n")))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return scanner.Err()
+}
+
+// Close ends the session on the server and stops every stream.
+func (t *httpTransport) Close() error {
+	var err error
+	if id := t.session(); id != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		req, reqErr := http.NewRequestWithContext(ctx, http.MethodDelete, t.endpoint, nil)
+		if reqErr == nil {
+			t.setHeaders(req)
+			resp, doErr := t.client.Do(req)
+			if doErr != nil {
+				err = fmt.Errorf("failed to end session: %w", doErr)
+			} else {
+				resp.Body.Close()
+			}
+		}
+		cancel()
+	}
+
+	t.cancel()
+	t.writer.Close()
+	t.wg.Wait()
+	return err
+}
@@ -0,0 +1,648 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// SessionHeader carries the Streamable HTTP session id assigned at
+// initialize.
+const SessionHeader = "Mcp-Session-Id"
+
+// errTooManySessions is returned by newHTTPSession when the server is at its
+// connection limit.
+var errTooManySessions = errors.New("too many sessions")
+
+const (
+	// httpSessionIdle is how long a session without open streams survives
+	// without requests before it is dropped.
+	httpSessionIdle = 30 * time.Minute
+	// sseKeepAlive keeps idle streams open through proxies that time out
+	// silent connections.
+	sseKeepAlive   = 25 * time.Second
+	maxHTTPMessage = 4 << 20
+)
+
+// httpSession is one Streamable HTTP client. Its messages are routed to the
+// stream of the POST that carried the request they answer, progress goes to
+// the stream of the request that asked for it and everything else to the GET
+// stream.
+type httpSession struct {
+	id     string
+	sess   *session
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	lastSeen   time.Time
+	streams    []*sseStream
+	byID       map[string]*sseStream
+	byToken    map[string]*sseStream
+	standalone *sseStream
+}
+
+// sseStream is the server side of one open response body.
+type sseStream struct {
+	messages chan streamMessage
+	done     chan struct{}
+}
+
+// streamMessage is a message for a stream; response marks the answers the
+// stream is waiting for. A response without data stands for a request the
+// client cancelled, which gets no answer.
+type streamMessage struct {
+	data     json.RawMessage
+	response bool
+}
+
+func newSSEStream(size int) *sseStream {
+	return &sseStream{
+		messages: make(chan streamMessage, size),
+		done:     make(chan struct{}),
+	}
+}
+
+// deliver queues message unless the stream has gone away. Responses wait for
+// room; notifications are dropped when the reader falls behind.
+func (st *sseStream) deliver(message streamMessage) {
+	if message.response {
+		select {
+		case st.messages <- message:
+		case <-st.done:
+		}
+		return
+	}
+
+	select {
+	case st.messages <- message:
+	case <-st.done:
+	default:
+	}
+}
+
+// HTTPHandler serves the MCP Streamable HTTP transport: POST carries client
+// messages, GET opens a stream for server-initiated messages and DELETE ends
+// the session.
+func (s *Server) HTTPHandler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+// RunHTTP serves HTTPHandler on address at /mcp and WebSocketHandler at /ws.
+func (s *Server) RunHTTP(address string) error {
+	s.logger.Printf("Starting %s server version %s on http://%s/mcp and ws://%s/ws", s.name, s.version, address, address)
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", s.HTTPHandler())
+	mux.Handle("/ws", s.WebSocketHandler())
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if !s.trackHTTPServer(server) {
+		return ErrServerClosed
+	}
+	defer s.untrackHTTPServer(server)
+
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return ErrServerClosed
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// Browsers send Origin; rejecting foreign ones prevents DNS rebinding
+	// attacks against servers on localhost.
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			writeHTTPError(w, http.StatusForbidden, -32600, "Origin not allowed")
+			return
+		}
+	}
+
+	if s.auth != nil {
+		identity, ok := s.authenticateRequest(w, r)
+		if !ok {
+			return
+		}
+		r = r.WithContext(withIdentity(r.Context(), identity))
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.handleHTTPPost(w, r)
+	case http.MethodGet:
+		s.handleHTTPGet(w, r)
+	case http.MethodDelete:
+		s.handleHTTPDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeHTTPError(w, http.StatusMethodNotAllowed, -32600, "Method not allowed")
+	}
+}
+
+func (s *Server) handleHTTPPost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxHTTPMessage))
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, -32700, "Parse error")
+		return
+	}
+
+	messages, batch, err := decodeHTTPMessages(body)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, -32700, "Parse error")
+		return
+	}
+
+	var requests []*models.JSONRPCMessage
+	initialize := false
+	for _, message := range messages {
+		if !message.IsResponse() && !message.IsNotification() {
+			requests = append(requests, message)
+		}
+		if message.Method == "initialize" {
+			initialize = true
+		}
+	}
+
+	var hs *httpSession
+	if r.Header.Get(SessionHeader) == "" {
+		if !initialize {
+			writeHTTPError(w, http.StatusBadRequest, -32600, "Missing "+SessionHeader+" header")
+			return
+		}
+		if s.isShuttingDown() {
+			writeHTTPError(w, http.StatusServiceUnavailable, codeShuttingDown, "Server is shutting down")
+			return
+		}
+		hs, err = s.newHTTPSession(identityFrom(r.Context()), r.RemoteAddr)
+		if err == errTooManySessions {
+			w.Header().Set("Retry-After", "30")
+			writeHTTPError(w, http.StatusServiceUnavailable, codeRateLimited, "Too many sessions, try again later")
+			return
+		}
+		if err != nil {
+			writeHTTPError(w, http.StatusInternalServerError, -32603, "Failed to create session")
+			return
+		}
+		w.Header().Set(SessionHeader, hs.id)
+		s.logger.Printf("New HTTP session %s from %s", hs.id, r.RemoteAddr)
+	} else if hs = s.lookupHTTPSession(w, r); hs == nil {
+		return
+	}
+
+	if len(requests) == 0 {
+		for _, message := range messages {
+			s.handleHTTPMessage(hs, message)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	stream := newSSEStream(len(requests) + 16)
+	hs.open(stream, requests)
+	defer hs.close(stream)
+
+	useSSE := acceptsEventStream(r)
+	if useSSE {
+		startEventStream(w)
+	}
+
+	for _, message := range messages {
+		s.handleHTTPMessage(hs, message)
+	}
+
+	var responses []json.RawMessage
+	remaining := len(requests)
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for remaining > 0 {
+		select {
+		case message := <-stream.messages:
+			if message.response {
+				remaining--
+			}
+			if message.data == nil {
+				continue
+			}
+			if !useSSE {
+				if message.response {
+					responses = append(responses, message.data)
+				}
+				continue
+			}
+			if err := writeEvent(w, message.data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if useSSE {
+				if err := writeComment(w, "keepalive"); err != nil {
+					return
+				}
+			}
+		case <-r.Context().Done():
+			// A dropped connection is not a cancellation; the requests keep
+			// running and their answers are discarded.
+			return
+		case <-hs.ctx.Done():
+			// Pass on what is already queued, such as the errors of calls
+			// interrupted by Shutdown.
+			for useSSE {
+				select {
+				case message := <-stream.messages:
+					if message.data != nil && writeEvent(w, message.data) != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+			return
+		}
+	}
+
+	if !useSSE {
+		w.Header().Set("Content-Type", "application/json")
+		if !batch && len(responses) == 1 {
+			w.Write(responses[0])
+			return
+		}
+		json.NewEncoder(w).Encode(responses)
+	}
+}
+
+// handleHTTPMessage passes a client message to the session. Cancellations
+// also release the stream waiting for the cancelled request.
+func (s *Server) handleHTTPMessage(hs *httpSession, message *models.JSONRPCMessage) {
+	if err := s.handleMessage(hs.ctx, hs.sess, message); err != nil {
+		s.logfTo(hs.sess, LevelError, "Error handling request %s: %v", message.Method, err)
+	}
+
+	if message.Method == "notifications/cancelled" {
+		var notification struct {
+			RequestID json.RawMessage `json:"requestId"`
+		}
+		if json.Unmarshal(message.Params, &notification) == nil {
+			hs.abandon(idKey(notification.RequestID))
+		}
+	}
+}
+
+func (s *Server) handleHTTPGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		writeHTTPError(w, http.StatusNotAcceptable, -32600, "GET requires Accept: text/event-stream")
+		return
+	}
+
+	hs := s.lookupHTTPSession(w, r)
+	if hs == nil {
+		return
+	}
+
+	stream := newSSEStream(64)
+	hs.mu.Lock()
+	if hs.standalone != nil {
+		hs.mu.Unlock()
+		writeHTTPError(w, http.StatusConflict, -32600, "Session already has an open GET stream")
+		return
+	}
+	hs.standalone = stream
+	hs.mu.Unlock()
+
+	defer func() {
+		hs.mu.Lock()
+		if hs.standalone == stream {
+			hs.standalone = nil
+		}
+		hs.lastSeen = time.Now()
+		hs.mu.Unlock()
+		close(stream.done)
+	}()
+
+	startEventStream(w)
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case message := <-stream.messages:
+			if err := writeEvent(w, message.data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if err := writeComment(w, "keepalive"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-hs.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleHTTPDelete(w http.ResponseWriter, r *http.Request) {
+	hs := s.lookupHTTPSession(w, r)
+	if hs == nil {
+		return
+	}
+
+	s.closeHTTPSession(hs)
+	s.logger.Printf("HTTP session %s terminated by client", hs.id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) newHTTPSession(identity *Identity, peer string) (*httpSession, error) {
+	s.expireHTTPSessions()
+	if !s.acquireConnection() {
+		return nil, errTooManySessions
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		s.releaseConnection()
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hs := &httpSession{
+		id:       hex.EncodeToString(id),
+		ctx:      ctx,
+		cancel:   cancel,
+		lastSeen: time.Now(),
+		byID:     make(map[string]*sseStream),
+		byToken:  make(map[string]*sseStream),
+	}
+	hs.sess = newSessionFunc(hs.route)
+	hs.sess.identity = identity
+	hs.sess.peer = peer
+	if s.auth != nil {
+		s.audit(hs.sess, "authenticated", "", "")
+	}
+
+	s.addSession(hs.sess)
+
+	s.httpMu.Lock()
+	s.httpSessions[hs.id] = hs
+	s.httpMu.Unlock()
+	return hs, nil
+}
+
+// lookupHTTPSession finds the session named by the request header, writing
+// the error response itself when there is none.
+func (s *Server) lookupHTTPSession(w http.ResponseWriter, r *http.Request) *httpSession {
+	id := r.Header.Get(SessionHeader)
+	if id == "" {
+		writeHTTPError(w, http.StatusBadRequest, -32600, "Missing "+SessionHeader+" header")
+		return nil
+	}
+
+	s.httpMu.Lock()
+	hs := s.httpSessions[id]
+	s.httpMu.Unlock()
+
+	if hs == nil {
+		writeHTTPError(w, http.StatusNotFound, -32001, "Session not found")
+		return nil
+	}
+
+	// A token only opens the sessions created with it.
+	if identity := identityFrom(r.Context()); s.auth != nil && (identity == nil || identity.Name != hs.sess.currentIdentity().Name) {
+		s.audit(hs.sess, "denied", "", "session used with another identity's token from "+r.RemoteAddr)
+		writeHTTPError(w, http.StatusForbidden, codeForbidden, "Session belongs to another client")
+		return nil
+	}
+
+	hs.mu.Lock()
+	hs.lastSeen = time.Now()
+	hs.mu.Unlock()
+	return hs
+}
+
+func (s *Server) closeHTTPSession(hs *httpSession) {
+	s.httpMu.Lock()
+	_, open := s.httpSessions[hs.id]
+	delete(s.httpSessions, hs.id)
+	s.httpMu.Unlock()
+
+	// A DELETE can race the idle expiry; only the first close counts.
+	if !open {
+		return
+	}
+
+	hs.cancel()
+	hs.sess.wait()
+	s.removeSession(hs.sess)
+	s.releaseConnection()
+}
+
+// expireHTTPSessions drops sessions whose clients went away without DELETE.
+func (s *Server) expireHTTPSessions() {
+	var expired []*httpSession
+
+	s.httpMu.Lock()
+	for _, hs := range s.httpSessions {
+		if hs.idle() > httpSessionIdle {
+			expired = append(expired, hs)
+		}
+	}
+	s.httpMu.Unlock()
+
+	for _, hs := range expired {
+		s.logger.Printf("HTTP session %s expired", hs.id)
+		s.closeHTTPSession(hs)
+	}
+}
+
+// idle reports how long the session has gone without requests or streams.
+func (hs *httpSession) idle() time.Duration {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if len(hs.streams) > 0 || hs.standalone != nil {
+		return 0
+	}
+	return time.Since(hs.lastSeen)
+}
+
+// open makes stream the destination of the answers and progress of requests.
+func (hs *httpSession) open(stream *sseStream, requests []*models.JSONRPCMessage) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.streams = append(hs.streams, stream)
+	for _, request := range requests {
+		hs.byID[idKey(request.ID)] = stream
+
+		var params struct {
+			Meta *models.RequestMeta `json:"_meta"`
+		}
+		if json.Unmarshal(request.Params, &params) == nil && params.Meta != nil && params.Meta.ProgressToken != nil {
+			hs.byToken[fmt.Sprint(params.Meta.ProgressToken)] = stream
+		}
+	}
+}
+
+func (hs *httpSession) close(stream *sseStream) {
+	hs.mu.Lock()
+	for i, open := range hs.streams {
+		if open == stream {
+			hs.streams = append(hs.streams[:i], hs.streams[i+1:]...)
+			break
+		}
+	}
+	for key, target := range hs.byID {
+		if target == stream {
+			delete(hs.byID, key)
+		}
+	}
+	for token, target := range hs.byToken {
+		if target == stream {
+			delete(hs.byToken, token)
+		}
+	}
+	hs.lastSeen = time.Now()
+	hs.mu.Unlock()
+
+	close(stream.done)
+}
+
+// abandon stops a stream waiting for a request that will not be answered.
+func (hs *httpSession) abandon(key string) {
+	hs.mu.Lock()
+	stream := hs.byID[key]
+	delete(hs.byID, key)
+	hs.mu.Unlock()
+
+	if stream != nil {
+		stream.deliver(streamMessage{response: true})
+	}
+}
+
+// route is the session's write function.
+func (hs *httpSession) route(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	var envelope models.JSONRPCMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("failed to inspect message: %w", err)
+	}
+
+	hs.mu.Lock()
+	var target *sseStream
+	switch {
+	case envelope.IsResponse():
+		key := idKey(envelope.ID)
+		target = hs.byID[key]
+		delete(hs.byID, key)
+	case envelope.Method == "notifications/progress":
+		var progress models.ProgressNotification
+		if json.Unmarshal(envelope.Params, &progress) == nil {
+			target = hs.byToken[fmt.Sprint(progress.ProgressToken)]
+		}
+	}
+	if target == nil && !envelope.IsResponse() {
+		if hs.standalone != nil {
+			target = hs.standalone
+		} else if len(hs.streams) > 0 {
+			target = hs.streams[0]
+		}
+	}
+	hs.mu.Unlock()
+
+	// Nobody is listening: the POST that asked went away or the client never
+	// opened a GET stream.
+	if target == nil {
+		return nil
+	}
+
+	target.deliver(streamMessage{data: data, response: envelope.IsResponse()})
+	return nil
+}
+
+// decodeHTTPMessages accepts a single JSON-RPC message or a batch.
+func decodeHTTPMessages(body []byte) ([]*models.JSONRPCMessage, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var messages []*models.JSONRPCMessage
+		if err := json.Unmarshal(body, &messages); err != nil {
+			return nil, true, err
+		}
+		if len(messages) == 0 {
+			return nil, true, fmt.Errorf("empty batch")
+		}
+		return messages, true, nil
+	}
+
+	var message models.JSONRPCMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return nil, false, err
+	}
+	return []*models.JSONRPCMessage{&message}, false, nil
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func startEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stops nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+}
+
+func writeEvent(w http.ResponseWriter, data []byte) error {
+	if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	flush(w)
+	return nil
+}
+
+func writeComment(w http.ResponseWriter, comment string) error {
+	if _, err := fmt.Fprintf(w, ": %s\n\n", comment); err != nil {
+		return err
+	}
+	flush(w)
+	return nil
+}
+
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func writeHTTPError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		Error: &models.JSONRPCError{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
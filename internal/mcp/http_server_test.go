@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// newProgressServer is newTestServer with a "count" tool that reports
+// progress 1, 2 and 3 before answering.
+func newProgressServer() *Server {
+	s := newTestServer()
+	s.RegisterTool("count", "Count to three", nil, ToolHandlerFunc(func(ctx context.Context, args map[string]interface{}) (*models.CallToolResponse, error) {
+		for i := 1; i <= 3; i++ {
+			ReportProgress(ctx, float64(i), 3, "")
+		}
+		return &models.CallToolResponse{Content: []models.Content{{Type: "text", Text: "done"}}}, nil
+	}))
+	return s
+}
+
+// sendHTTP sends body to endpoint with header and returns the response,
+// closed when the test ends.
+func sendHTTP(t *testing.T, method, endpoint string, header http.Header, body string) *http.Response {
+	t.Helper()
+	request, err := http.NewRequest(method, endpoint, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header = header
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func mcpHeader(session, accept string) http.Header {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Accept", accept)
+	if session != "" {
+		header.Set(SessionHeader, session)
+	}
+	return header
+}
+
+func TestHTTPTransportRequests(t *testing.T) {
+	s := newProgressServer()
+	httpServer := httptest.NewServer(s.HTTPHandler())
+	defer httpServer.Close()
+	endpoint := httpServer.URL
+
+	response := sendHTTP(t, http.MethodPost, endpoint, mcpHeader("", "application/json"), initializeBody)
+	session := response.Header.Get(SessionHeader)
+	if response.StatusCode != http.StatusOK || session == "" {
+		t.Fatalf("initialize: status %d, session %q", response.StatusCode, session)
+	}
+	var initialized models.JSONRPCMessage
+	if err := json.NewDecoder(response.Body).Decode(&initialized); err != nil || initialized.Error != nil {
+		t.Fatalf("initialize answer: %v %+v", err, initialized.Error)
+	}
+
+	notification := `{"jsonrpc":"2.0","method":"notifications/initialized"}`
+	if response := sendHTTP(t, http.MethodPost, endpoint, mcpHeader(session, "application/json"), notification); response.StatusCode != http.StatusAccepted {
+		t.Errorf("notification: status %d, want 202", response.StatusCode)
+	}
+
+	// Answered as JSON.
+	echo := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"as json"}}}`
+	response = sendHTTP(t, http.MethodPost, endpoint, mcpHeader(session, "application/json"), echo)
+	if contentType := response.Header.Get("Content-Type"); response.StatusCode != http.StatusOK || contentType != "application/json" {
+		t.Fatalf("JSON call: status %d, content type %q", response.StatusCode, contentType)
+	}
+	var answer struct {
+		ID     int                     `json:"id"`
+		Result models.CallToolResponse `json:"result"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&answer); err != nil {
+		t.Fatal(err)
+	}
+	if answer.ID != 2 || len(answer.Result.Content) != 1 || answer.Result.Content[0].Text != "as json" {
+		t.Errorf("JSON answer = %+v", answer)
+	}
+
+	// Answered as an event stream carrying the request's progress first.
+	count := `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"count","_meta":{"progressToken":"p3"}}}`
+	response = sendHTTP(t, http.MethodPost, endpoint, mcpHeader(session, "application/json, text/event-stream"), count)
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("SSE call: content type %q", contentType)
+	}
+	var events []models.JSONRPCMessage
+	if err := readEventStream(response.Body, func(data []byte) {
+		var message models.JSONRPCMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Errorf("event %s: %v", data, err)
+		}
+		events = append(events, message)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var progress []float64
+	for _, event := range events[:len(events)-1] {
+		if event.Method != "notifications/progress" {
+			continue
+		}
+		var update models.ProgressNotification
+		json.Unmarshal(event.Params, &update)
+		if update.ProgressToken != "p3" {
+			t.Errorf("progress for token %v on the stream of p3", update.ProgressToken)
+		}
+		progress = append(progress, update.Progress)
+	}
+	if len(progress) != 3 || progress[2] != 3 {
+		t.Errorf("progress on the stream = %v, want 1, 2, 3", progress)
+	}
+	if last := events[len(events)-1]; !last.IsResponse() || string(last.ID) != "3" {
+		t.Errorf("stream ended with %+v, want the response to request 3", last)
+	}
+
+	if response := sendHTTP(t, http.MethodPost, endpoint, mcpHeader("unknown-session", "application/json"), echo); response.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown session: status %d, want 404", response.StatusCode)
+	}
+	foreign := mcpHeader(session, "application/json")
+	foreign.Set("Origin", "http://attacker.example")
+	if response := sendHTTP(t, http.MethodPost, endpoint, foreign, echo); response.StatusCode != http.StatusForbidden {
+		t.Errorf("foreign Origin: status %d, want 403", response.StatusCode)
+	}
+
+	if response := sendHTTP(t, http.MethodDelete, endpoint, mcpHeader(session, ""), ""); response.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE: status %d, want 204", response.StatusCode)
+	}
+	if response := sendHTTP(t, http.MethodPost, endpoint, mcpHeader(session, "application/json"), echo); response.StatusCode != http.StatusNotFound {
+		t.Errorf("request after DELETE: status %d, want 404", response.StatusCode)
+	}
+}
+
+func TestHTTPClientRoundTrip(t *testing.T) {
+	s := newProgressServer()
+	httpServer := httptest.NewServer(s.HTTPHandler())
+	defer httpServer.Close()
+
+	client := NewClient(nil, log.New(io.Discard, "", 0))
+	var logs logRecorder
+	client.OnNotification("notifications/message", logs.handle)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.ConnectHTTP(ctx, httpServer.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetLogLevel(ctx, LevelInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "over http"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "over http" {
+		t.Errorf("echo result = %+v", result.Content)
+	}
+	if !logs.contains("Calling tool: echo") {
+		t.Errorf("no log notification for the call: %v", logs.messages)
+	}
+
+	var mu sync.Mutex
+	var progress []float64
+	if _, err := client.CallToolWithProgress(ctx, "count", nil, func(update models.ProgressNotification) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress = append(progress, update.Progress)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if len(progress) != 3 {
+		t.Errorf("progress = %v, want 1, 2, 3", progress)
+	}
+	mu.Unlock()
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	s.httpMu.Lock()
+	open := len(s.httpSessions)
+	s.httpMu.Unlock()
+	if open != 0 {
+		t.Errorf("%d HTTP sessions open after Close, want 0", open)
+	}
+}
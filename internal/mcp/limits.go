@@ -0,0 +1,311 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// codeRateLimited answers requests over a rate limit or quota.
+const codeRateLimited = -32012
+
+// clientIdle is how long a client's counters are kept after its last request.
+const clientIdle = time.Hour
+
+// Limits bounds what clients of a network server may use. Zero values mean
+// no limit. Clients are told apart by authenticated identity, or by remote
+// host when the server does not authenticate.
+type Limits struct {
+	MaxConnections    int
+	RequestsPerMinute float64
+	Burst             int
+	ToolQuotas        map[string]Quota
+}
+
+// Quota allows Calls calls of a tool per client in every Per interval.
+type Quota struct {
+	Calls int
+	Per   time.Duration
+}
+
+// RateLimitError is the data of a rate limited response.
+type RateLimitError struct {
+	RetryAfter int    `json:"retryAfter"`
+	Limit      string `json:"limit"`
+}
+
+// Usage is a snapshot of the connections and per-client counters.
+type Usage struct {
+	Connections int
+	Limits      Limits
+	Clients     []ClientUsage
+}
+
+// ClientUsage is the activity of one client. Tokens is what is left of its
+// request bucket and ToolTokens of its tool quotas; ToolCalls counts calls
+// per tool since the client appeared.
+type ClientUsage struct {
+	Client      string
+	Requests    int
+	RateLimited int
+	Tokens      float64
+	ToolCalls   map[string]int
+	ToolTokens  map[string]float64
+	LastSeen    time.Time
+}
+
+// tokenBucket holds up to capacity tokens and regains rate tokens a second.
+type tokenBucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64
+	last     time.Time
+}
+
+func newTokenBucket(capacity, rate float64, now time.Time) *tokenBucket {
+	return &tokenBucket{tokens: capacity, capacity: capacity, rate: rate, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// take spends a token, or reports how long until one is available.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+type clientState struct {
+	requests *tokenBucket
+	tools    map[string]*tokenBucket
+	usage    ClientUsage
+}
+
+// limiter enforces Limits for one server.
+type limiter struct {
+	limits Limits
+
+	mu          sync.Mutex
+	connections int
+	clients     map[string]*clientState
+}
+
+func newLimiter(limits Limits) *limiter {
+	return &limiter{
+		limits:  limits,
+		clients: make(map[string]*clientState),
+	}
+}
+
+// SetLimits applies limits to the network transports. Call it before the
+// server starts listening.
+func (s *Server) SetLimits(limits Limits) {
+	s.limiter = newLimiter(limits)
+}
+
+// Usage reports current connections and per-client counters, most recently
+// active client first.
+func (s *Server) Usage() Usage {
+	if s.limiter == nil {
+		return Usage{}
+	}
+	return s.limiter.usage()
+}
+
+// acquireConnection reserves a connection slot; false means the server is
+// full.
+func (s *Server) acquireConnection() bool {
+	if s.limiter == nil {
+		return true
+	}
+
+	l := s.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limits.MaxConnections > 0 && l.connections >= l.limits.MaxConnections {
+		return false
+	}
+	l.connections++
+	return true
+}
+
+func (s *Server) releaseConnection() {
+	if s.limiter == nil {
+		return
+	}
+
+	s.limiter.mu.Lock()
+	s.limiter.connections--
+	s.limiter.mu.Unlock()
+}
+
+// allowRequest charges a request to the session's client, answering it with
+// a rate limit error itself when the client is over its rate.
+func (s *Server) allowRequest(sess *session, id interface{}) (bool, error) {
+	if s.limiter == nil {
+		return true, nil
+	}
+
+	retryAfter, ok := s.limiter.take(clientKey(sess), "")
+	if ok {
+		return true, nil
+	}
+	return false, s.sendRateLimited(sess, id, retryAfter, "requests")
+}
+
+// allowToolCall charges a call against the tool's quota for the session's
+// client.
+func (s *Server) allowToolCall(sess *session, id interface{}, tool string) (bool, error) {
+	if s.limiter == nil {
+		return true, nil
+	}
+
+	retryAfter, ok := s.limiter.take(clientKey(sess), tool)
+	if ok {
+		return true, nil
+	}
+	return false, s.sendRateLimited(sess, id, retryAfter, "tool "+tool)
+}
+
+func (s *Server) sendRateLimited(sess *session, id interface{}, retryAfter time.Duration, limit string) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	s.logfTo(sess, LevelWarning, "Rate limited %s (%s), retry after %ds", clientKey(sess), limit, seconds)
+	return s.sendError(sess, id, codeRateLimited, fmt.Sprintf("Rate limited, retry after %d s", seconds), RateLimitError{
+		RetryAfter: seconds,
+		Limit:      limit,
+	})
+}
+
+// take charges a request, or a call of tool when tool is set, to client.
+func (l *limiter) take(client, tool string) (time.Duration, bool) {
+	return l.takeAt(client, tool, time.Now())
+}
+
+func (l *limiter) takeAt(client, tool string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.client(client, now)
+	state.usage.LastSeen = now
+
+	bucket := state.requests
+	if tool != "" {
+		bucket = state.tools[tool]
+		if quota := l.limits.ToolQuotas[tool]; bucket == nil && quota.Calls > 0 && quota.Per > 0 {
+			bucket = newTokenBucket(float64(quota.Calls), float64(quota.Calls)/quota.Per.Seconds(), now)
+			state.tools[tool] = bucket
+		}
+	}
+
+	if bucket != nil {
+		if ok, retryAfter := bucket.take(now); !ok {
+			state.usage.RateLimited++
+			return retryAfter, false
+		}
+	}
+
+	if tool == "" {
+		state.usage.Requests++
+	} else {
+		state.usage.ToolCalls[tool]++
+	}
+	return 0, true
+}
+
+// client returns the state of client, creating it and dropping idle clients
+// when it is new. Called with l.mu held.
+func (l *limiter) client(client string, now time.Time) *clientState {
+	if state, exists := l.clients[client]; exists {
+		return state
+	}
+
+	for key, state := range l.clients {
+		if now.Sub(state.usage.LastSeen) > clientIdle {
+			delete(l.clients, key)
+		}
+	}
+
+	state := &clientState{
+		tools: make(map[string]*tokenBucket),
+		usage: ClientUsage{
+			Client:    client,
+			ToolCalls: make(map[string]int),
+		},
+	}
+	if rate := l.limits.RequestsPerMinute; rate > 0 {
+		// Without a configured burst a client may use ten seconds' worth of
+		// requests at once.
+		burst := float64(l.limits.Burst)
+		if burst < 1 {
+			burst = math.Max(1, rate/6)
+		}
+		state.requests = newTokenBucket(burst, rate/60, now)
+	}
+	l.clients[client] = state
+	return state
+}
+
+func (l *limiter) usage() Usage {
+	return l.usageAt(time.Now())
+}
+
+func (l *limiter) usageAt(now time.Time) Usage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usage := Usage{
+		Connections: l.connections,
+		Limits:      l.limits,
+	}
+	for _, state := range l.clients {
+		client := state.usage
+		if state.requests != nil {
+			state.requests.refill(now)
+			client.Tokens = state.requests.tokens
+		}
+
+		client.ToolCalls = make(map[string]int, len(state.usage.ToolCalls))
+		for tool, calls := range state.usage.ToolCalls {
+			client.ToolCalls[tool] = calls
+		}
+		client.ToolTokens = make(map[string]float64, len(state.tools))
+		for tool, bucket := range state.tools {
+			bucket.refill(now)
+			client.ToolTokens[tool] = bucket.tokens
+		}
+		usage.Clients = append(usage.Clients, client)
+	}
+
+	sort.Slice(usage.Clients, func(i, j int) bool {
+		return usage.Clients[i].LastSeen.After(usage.Clients[j].LastSeen)
+	})
+	return usage
+}
+
+// clientKey names the client a session belongs to for accounting.
+func clientKey(sess *session) string {
+	if identity := sess.currentIdentity(); identity != nil {
+		return identity.Name
+	}
+	if host, _, err := net.SplitHostPort(sess.peer); err == nil {
+		return host
+	}
+	if sess.peer != "" {
+		return sess.peer
+	}
+	return "local"
+}
@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+func TestLimiterRequestRate(t *testing.T) {
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name       string
+		limits     Limits
+		burst      int
+		retryAfter time.Duration
+	}{
+		// Without a burst, ten seconds' worth of requests.
+		{"default burst", Limits{RequestsPerMinute: 60}, 10, time.Second},
+		{"configured burst", Limits{RequestsPerMinute: 30, Burst: 2}, 2, 2 * time.Second},
+		{"at least one", Limits{RequestsPerMinute: 3}, 1, 20 * time.Second},
+	} {
+		l := newLimiter(tc.limits)
+		for i := 0; i < tc.burst; i++ {
+			if _, ok := l.takeAt("alice", "", start); !ok {
+				t.Fatalf("%s: request %d of the burst refused", tc.name, i+1)
+			}
+		}
+		retryAfter, ok := l.takeAt("alice", "", start)
+		if ok || retryAfter != tc.retryAfter {
+			t.Errorf("%s: request past the burst = %v, %v; want refused, retry after %s", tc.name, ok, retryAfter, tc.retryAfter)
+		}
+		if _, ok := l.takeAt("bob", "", start); !ok {
+			t.Errorf("%s: another client was charged for alice's requests", tc.name)
+		}
+		if _, ok := l.takeAt("alice", "", start.Add(tc.retryAfter)); !ok {
+			t.Errorf("%s: request refused after waiting %s", tc.name, tc.retryAfter)
+		}
+	}
+}
+
+func TestLimiterToolQuotas(t *testing.T) {
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	l := newLimiter(Limits{ToolQuotas: map[string]Quota{"export_analysis": {Calls: 2, Per: time.Minute}}})
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.takeAt("alice", "export_analysis", start); !ok {
+			t.Fatalf("call %d within the quota refused", i+1)
+		}
+	}
+	if retryAfter, ok := l.takeAt("alice", "export_analysis", start); ok || retryAfter != 30*time.Second {
+		t.Errorf("call over the quota = %v, %v; want refused, retry after 30s", ok, retryAfter)
+	}
+	for i := 0; i < 5; i++ {
+		if _, ok := l.takeAt("alice", "get_stock_price", start); !ok {
+			t.Fatal("tool without a quota refused")
+		}
+	}
+	if _, ok := l.takeAt("alice", "export_analysis", start.Add(30*time.Second)); !ok {
+		t.Error("call refused once the quota refilled")
+	}
+
+	usage := l.usageAt(start.Add(30 * time.Second))
+	if len(usage.Clients) != 1 {
+		t.Fatalf("usage clients = %+v", usage.Clients)
+	}
+	alice := usage.Clients[0]
+	if alice.Client != "alice" || alice.RateLimited != 1 || alice.ToolCalls["export_analysis"] != 3 || alice.ToolCalls["get_stock_price"] != 5 {
+		t.Errorf("usage = %+v", alice)
+	}
+	if tokens := alice.ToolTokens["export_analysis"]; tokens != 0 {
+		t.Errorf("export_analysis tokens = %v, want 0", tokens)
+	}
+}
+
+func TestLimiterUsageSnapshot(t *testing.T) {
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	l := newLimiter(Limits{RequestsPerMinute: 60, Burst: 5})
+
+	l.takeAt("alice", "", start)
+	l.takeAt("bob", "", start.Add(time.Second))
+	l.takeAt("bob", "", start.Add(time.Second))
+
+	usage := l.usageAt(start.Add(2 * time.Second))
+	if len(usage.Clients) != 2 || usage.Clients[0].Client != "bob" || usage.Clients[1].Client != "alice" {
+		t.Fatalf("clients = %+v, want bob then alice", usage.Clients)
+	}
+	if bob := usage.Clients[0]; bob.Requests != 2 || bob.Tokens != 4 {
+		t.Errorf("bob = %+v, want 2 requests and 4 tokens left", bob)
+	}
+	if alice := usage.Clients[1]; alice.Requests != 1 || alice.Tokens != 5 {
+		t.Errorf("alice = %+v, want 1 request and a refilled bucket", alice)
+	}
+
+	// The snapshot is a copy.
+	usage.Clients[0].ToolCalls["injected"] = 1
+	if l.usageAt(start).Clients[0].ToolCalls["injected"] != 0 {
+		t.Error("snapshot shares the limiter's counters")
+	}
+}
+
+func TestToolCallOverQuota(t *testing.T) {
+	s := newTestServer()
+	s.SetLimits(Limits{ToolQuotas: map[string]Quota{"echo": {Calls: 1, Per: time.Hour}}})
+	conn := dialRaw(t, serveTCP(t, s))
+	conn.initialize("")
+
+	echo := models.CallToolRequest{Name: "echo", Arguments: map[string]interface{}{"text": "hi"}}
+	if response := conn.call("tools/call", echo); response.Error != nil {
+		t.Fatalf("call within the quota: %+v", response.Error)
+	}
+	response := conn.call("tools/call", echo)
+	if code := errorCode(response); code != codeRateLimited {
+		t.Fatalf("call over the quota: %+v, want code %d", response.Error, codeRateLimited)
+	}
+
+	data, _ := json.Marshal(response.Error.Data)
+	var limited RateLimitError
+	if err := json.Unmarshal(data, &limited); err != nil {
+		t.Fatal(err)
+	}
+	if limited.Limit != "tool echo" || limited.RetryAfter != 3600 {
+		t.Errorf("rate limit data = %+v, want tool echo, retry after 3600", limited)
+	}
+
+	usage := s.Usage()
+	if usage.Connections != 1 || len(usage.Clients) != 1 {
+		t.Fatalf("usage = %+v", usage)
+	}
+	if client := usage.Clients[0]; client.Client != "127.0.0.1" || client.ToolCalls["echo"] != 1 || client.RateLimited != 1 {
+		t.Errorf("client usage = %+v", client)
+	}
+}
+
+func TestConnectionLimit(t *testing.T) {
+	s := newTestServer()
+	s.SetLimits(Limits{MaxConnections: 1})
+	address := serveTCP(t, s)
+
+	first := dialRaw(t, address)
+	if response := first.initialize(""); response.Error != nil {
+		t.Fatalf("first connection: %+v", response.Error)
+	}
+
+	response := dialRaw(t, address).initialize("")
+	if code := errorCode(response); code != codeRateLimited || response.Error.Message != "Too many connections, try again later" {
+		t.Errorf("connection over the limit: %+v, want a rate limit error", response.Error)
+	}
+
+	first.conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Usage().Connections > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("connection slot not released")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if response := dialRaw(t, address).initialize(""); response.Error != nil {
+		t.Errorf("connection after a slot was freed: %+v", response.Error)
+	}
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// Log levels as defined by the MCP logging capability (RFC 5424 severities).
+const (
+	LevelDebug     = "debug"
+	LevelInfo      = "info"
+	LevelNotice    = "notice"
+	LevelWarning   = "warning"
+	LevelError     = "error"
+	LevelCritical  = "critical"
+	LevelAlert     = "alert"
+	LevelEmergency = "emergency"
+)
+
+var logSeverity = map[string]int{
+	LevelDebug:     0,
+	LevelInfo:      1,
+	LevelNotice:    2,
+	LevelWarning:   3,
+	LevelError:     4,
+	LevelCritical:  5,
+	LevelAlert:     6,
+	LevelEmergency: 7,
+}
+
+func ValidLogLevel(level string) bool {
+	_, ok := logSeverity[level]
+	return ok
+}
+
+type sessionKey struct{}
+
+// withSession marks ctx as serving a request of sess, so that LogContext
+// reaches only the client that sent it.
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sess)
+}
+
+func sessionFrom(ctx context.Context) *session {
+	sess, _ := ctx.Value(sessionKey{}).(*session)
+	return sess
+}
+
+// Logf writes a log line to stderr and forwards it as notifications/message
+// to every client that enabled logging at level or below via
+// logging/setLevel. It is meant for server-wide events; use LogContext for
+// anything about a single request.
+func (s *Server) Logf(level, format string, args ...interface{}) {
+	s.Log(level, fmt.Sprintf(format, args...), nil)
+}
+
+// Log is like Logf but attaches structured fields to the forwarded message.
+func (s *Server) Log(level, message string, fields map[string]interface{}) {
+	s.log(s.activeSessions(), level, message, fields)
+}
+
+// LogContext is like Log but forwards the message only to the client whose
+// request ctx belongs to, so one client's arguments never reach another.
+// Outside a request it only writes to stderr.
+func (s *Server) LogContext(ctx context.Context, level, message string, fields map[string]interface{}) {
+	s.logTo(sessionFrom(ctx), level, message, fields)
+}
+
+// logfTo is Logf for a message about a request of sess.
+func (s *Server) logfTo(sess *session, level, format string, args ...interface{}) {
+	s.logTo(sess, level, fmt.Sprintf(format, args...), nil)
+}
+
+func (s *Server) logTo(sess *session, level, message string, fields map[string]interface{}) {
+	var sessions []*session
+	if sess != nil {
+		sessions = []*session{sess}
+	}
+	s.log(sessions, level, message, fields)
+}
+
+func (s *Server) log(sessions []*session, level, message string, fields map[string]interface{}) {
+	if len(fields) > 0 {
+		s.logger.Printf("%s %s", message, formatFields(fields))
+	} else {
+		s.logger.Print(message)
+	}
+
+	data := map[string]interface{}{"message": message}
+	for key, value := range fields {
+		data[key] = value
+	}
+
+	notification := models.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/message",
+		Params: models.LoggingMessageNotification{
+			Level:  level,
+			Logger: s.name,
+			Data:   data,
+		},
+	}
+
+	for _, sess := range sessions {
+		if sess.wantsLog(level) {
+			sess.send(notification)
+		}
+	}
+}
+
+func formatFields(fields map[string]interface{}) string {
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Sprint(fields)
+	}
+	return string(encoded)
+}
+
+func (s *Server) handleSetLevel(sess *session, request *models.JSONRPCMessage) error {
+	var params models.SetLevelRequest
+	if err := json.Unmarshal(request.Params, &params); err != nil {
+		return s.sendError(sess, request.ID, -32602, "Invalid params", err.Error())
+	}
+
+	level := strings.ToLower(params.Level)
+	if !ValidLogLevel(level) {
+		return s.sendError(sess, request.ID, -32602, "Invalid params", fmt.Sprintf("unknown log level: %s", params.Level))
+	}
+
+	sess.setLogLevel(level)
+	s.logger.Printf("Client log level set to %s", level)
+
+	return sess.send(models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      request.ID,
+		Result:  struct{}{},
+	})
+}
@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logRecorder collects the notifications/message a client receives.
+type logRecorder struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (r *logRecorder) handle(method string, params json.RawMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, string(params))
+}
+
+func (r *logRecorder) contains(text string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range r.messages {
+		if strings.Contains(message, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRequestLogsReachOnlyTheirSession(t *testing.T) {
+	address := serveTCP(t, newTestServer())
+	caller := connectTCP(t, address)
+	observer := connectTCP(t, address)
+
+	var callerLogs, observerLogs logRecorder
+	caller.OnNotification("notifications/message", callerLogs.handle)
+	observer.OnNotification("notifications/message", observerLogs.handle)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, client := range []*Client{caller, observer} {
+		if err := client.SetLogLevel(ctx, LevelDebug); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := caller.CallTool(ctx, "echo", map[string]interface{}{"text": "secret-argument"}); err != nil {
+		t.Fatal(err)
+	}
+	// A round trip of the observer's own lets any stray broadcast arrive.
+	if _, err := observer.CallTool(ctx, "echo", map[string]interface{}{"text": "other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !callerLogs.contains("Calling tool: echo") || !callerLogs.contains("secret-argument") {
+		t.Errorf("caller did not get the log of its call: %v", callerLogs.messages)
+	}
+	if observerLogs.contains("secret-argument") {
+		t.Errorf("observer got another client's arguments: %v", observerLogs.messages)
+	}
+	if !observerLogs.contains("other") {
+		t.Errorf("observer did not get the log of its own call: %v", observerLogs.messages)
+	}
+}
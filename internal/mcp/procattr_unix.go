@@ -0,0 +1,14 @@
+//go:build unix
+
+package mcp
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcessGroup starts the server in its own process group so a Ctrl-C
+// aimed at the host (e.g. to cancel a request) does not kill the server too.
+func detachProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"context"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+type progressKey struct{}
+
+// progressReporter sends notifications/progress for the request that carried
+// the progress token.
+type progressReporter struct {
+	sess  *session
+	token interface{}
+}
+
+func withProgress(ctx context.Context, sess *session, token interface{}) context.Context {
+	return context.WithValue(ctx, progressKey{}, &progressReporter{sess: sess, token: token})
+}
+
+// ReportProgress notifies the client about the progress of the current tool
+// call. It does nothing when the client did not ask for progress updates, so
+// handlers can call it unconditionally.
+func ReportProgress(ctx context.Context, progress, total float64, message string) {
+	reporter, ok := ctx.Value(progressKey{}).(*progressReporter)
+	if !ok || ctx.Err() != nil {
+		return
+	}
+
+	reporter.sess.send(models.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/progress",
+		Params: models.ProgressNotification{
+			ProgressToken: reporter.token,
+			Progress:      progress,
+			Total:         total,
+			Message:       message,
+		},
+	})
+}
@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// PromptHandler renders a prompt from its arguments. Required arguments are
+// checked before the handler runs.
+type PromptHandler interface {
+	Get(ctx context.Context, args map[string]string) (*models.GetPromptResponse, error)
+}
+
+type PromptHandlerFunc func(ctx context.Context, args map[string]string) (*models.GetPromptResponse, error)
+
+func (f PromptHandlerFunc) Get(ctx context.Context, args map[string]string) (*models.GetPromptResponse, error) {
+	return f(ctx, args)
+}
+
+type registeredPrompt struct {
+	prompt  models.Prompt
+	handler PromptHandler
+}
+
+// RegisterPrompt publishes a prompt in prompts/list. Prompts are listed in
+// registration order.
+func (s *Server) RegisterPrompt(prompt models.Prompt, handler PromptHandler) {
+	if _, exists := s.prompts[prompt.Name]; !exists {
+		s.promptOrder = append(s.promptOrder, prompt.Name)
+	}
+	s.prompts[prompt.Name] = &registeredPrompt{prompt: prompt, handler: handler}
+
+	if s.capabilities.Prompts == nil {
+		s.capabilities.Prompts = &models.PromptsCapability{}
+	}
+	s.logger.Printf("Registered prompt: %s", prompt.Name)
+}
+
+// EmbedResource reads uri through the registered resource handlers and wraps
+// the first content block for use inside a prompt message. Inside a request,
+// the client must be allowed to read uri itself.
+func (s *Server) EmbedResource(ctx context.Context, uri string) (models.Content, error) {
+	if sess := sessionFrom(ctx); sess != nil && s.auth != nil {
+		identity := sess.currentIdentity()
+		if identity == nil || !identity.AllowsResource(uri) {
+			s.auditAccess(sess, "denied", accessResource, uri, "resource not allowed")
+			return models.Content{}, fmt.Errorf("%w: %s", ErrResourceForbidden, uri)
+		}
+	}
+
+	handler, params, exists := s.resolveResource(uri)
+	if !exists {
+		return models.Content{}, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+	}
+
+	contents, err := handler.Read(ctx, uri, params)
+	if err != nil {
+		return models.Content{}, fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+	if len(contents) == 0 {
+		return models.Content{}, fmt.Errorf("resource %s is empty", uri)
+	}
+
+	return models.Content{Type: "resource", Resource: &contents[0]}, nil
+}
+
+func (s *Server) handleListPrompts(sess *session, request *models.JSONRPCMessage) error {
+	prompts := make([]models.Prompt, 0, len(s.promptOrder))
+	for _, name := range s.promptOrder {
+		if !s.visible(sess, accessPrompt, name) {
+			continue
+		}
+		prompts = append(prompts, s.prompts[name].prompt)
+	}
+
+	return sess.send(models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      request.ID,
+		Result:  models.ListPromptsResponse{Prompts: prompts},
+	})
+}
+
+func (s *Server) handleGetPrompt(ctx context.Context, sess *session, request *models.JSONRPCMessage) error {
+	var getReq models.GetPromptRequest
+	if err := json.Unmarshal(request.Params, &getReq); err != nil {
+		return s.sendError(sess, request.ID, -32602, "Invalid params", err.Error())
+	}
+
+	if allowed, err := s.authorize(sess, request.ID, accessPrompt, getReq.Name); !allowed {
+		return err
+	}
+
+	entry, exists := s.prompts[getReq.Name]
+	if !exists {
+		return s.sendError(sess, request.ID, -32602, "Invalid params", fmt.Sprintf("unknown prompt: %s", getReq.Name))
+	}
+
+	var missing []string
+	for _, arg := range entry.prompt.Arguments {
+		if arg.Required && getReq.Arguments[arg.Name] == "" {
+			missing = append(missing, fmt.Sprintf("%s: required argument missing", arg.Name))
+		}
+	}
+	if len(missing) > 0 {
+		return s.sendError(sess, request.ID, -32602, "Invalid params", missing)
+	}
+
+	args := getReq.Arguments
+	if args == nil {
+		args = map[string]string{}
+	}
+
+	s.logfTo(sess, LevelInfo, "Rendering prompt: %s", getReq.Name)
+	result, err := entry.handler.Get(ctx, args)
+
+	if ctx.Err() != nil {
+		s.logfTo(sess, LevelNotice, "Prompt %s cancelled: %v", getReq.Name, ctx.Err())
+		return nil
+	}
+
+	if err != nil {
+		s.logfTo(sess, LevelError, "Prompt %s failed: %v", getReq.Name, err)
+		return s.sendError(sess, request.ID, -32603, "Prompt error", err.Error())
+	}
+
+	return sess.send(models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      request.ID,
+		Result:  result,
+	})
+}
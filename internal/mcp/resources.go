@@ -0,0 +1,233 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// ResourceHandler produces the contents of a resource. params holds the
+// variables matched from the URI template and is empty for fixed resources.
+type ResourceHandler interface {
+	Read(ctx context.Context, uri string, params map[string]string) ([]models.ResourceContents, error)
+}
+
+type ResourceHandlerFunc func(ctx context.Context, uri string, params map[string]string) ([]models.ResourceContents, error)
+
+func (f ResourceHandlerFunc) Read(ctx context.Context, uri string, params map[string]string) ([]models.ResourceContents, error) {
+	return f(ctx, uri, params)
+}
+
+// ErrResourceNotFound can be returned by a ResourceHandler when the URI
+// matched a template but names something that does not exist.
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ErrResourceForbidden is returned by EmbedResource when the client the
+// prompt is rendered for may not read the resource.
+var ErrResourceForbidden = errors.New("resource not allowed")
+
+type registeredResource struct {
+	resource models.Resource
+	handler  ResourceHandler
+}
+
+type registeredTemplate struct {
+	template models.ResourceTemplate
+	matcher  *URITemplate
+	handler  ResourceHandler
+}
+
+// URITemplate matches URIs against a level 1 RFC 6570 template, where each
+// {name} expands to a single path segment.
+type URITemplate struct {
+	raw     string
+	pattern *regexp.Regexp
+	names   []string
+}
+
+var templateVariable = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+func ParseURITemplate(template string) (*URITemplate, error) {
+	var pattern strings.Builder
+	var names []string
+
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range templateVariable.FindAllStringSubmatchIndex(template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		pattern.WriteString("([^/?#]+)")
+		names = append(names, template[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	if strings.ContainsAny(templateVariable.ReplaceAllString(template, ""), "{}") {
+		return nil, fmt.Errorf("unsupported URI template: %s", template)
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	pattern.WriteString("$")
+
+	compiled, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid URI template %s: %w", template, err)
+	}
+
+	return &URITemplate{raw: template, pattern: compiled, names: names}, nil
+}
+
+// Match reports whether uri is an expansion of the template and returns the
+// variable values.
+func (t *URITemplate) Match(uri string) (map[string]string, bool) {
+	groups := t.pattern.FindStringSubmatch(uri)
+	if groups == nil {
+		return nil, false
+	}
+
+	params := make(map[string]string, len(t.names))
+	for i, name := range t.names {
+		params[name] = groups[i+1]
+	}
+	return params, true
+}
+
+// Glob is the template in path.Match syntax, each variable matching any
+// segment.
+func (t *URITemplate) Glob() string {
+	return templateVariable.ReplaceAllString(t.raw, "*")
+}
+
+// Expand substitutes params into the template.
+func (t *URITemplate) Expand(params map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(t.raw, func(variable string) string {
+		return params[strings.Trim(variable, "{}")]
+	})
+}
+
+// RegisterResource publishes a fixed resource in resources/list.
+func (s *Server) RegisterResource(resource models.Resource, handler ResourceHandler) {
+	if _, exists := s.resources[resource.URI]; !exists {
+		s.resourceOrder = append(s.resourceOrder, resource.URI)
+	}
+	s.resources[resource.URI] = &registeredResource{resource: resource, handler: handler}
+	s.enableResources()
+	s.logger.Printf("Registered resource: %s", resource.URI)
+}
+
+// RegisterResourceTemplate publishes a parameterized resource in
+// resources/templates/list. Reads of matching URIs are routed to handler.
+func (s *Server) RegisterResourceTemplate(template models.ResourceTemplate, handler ResourceHandler) error {
+	matcher, err := ParseURITemplate(template.URITemplate)
+	if err != nil {
+		return err
+	}
+
+	s.templates = append(s.templates, &registeredTemplate{template: template, matcher: matcher, handler: handler})
+	s.enableResources()
+	s.logger.Printf("Registered resource template: %s", template.URITemplate)
+	return nil
+}
+
+func (s *Server) enableResources() {
+	if s.capabilities.Resources == nil {
+		s.capabilities.Resources = &models.ResourcesCapability{}
+	}
+}
+
+// resolveResource finds the handler for uri, preferring fixed resources over
+// templates.
+func (s *Server) resolveResource(uri string) (ResourceHandler, map[string]string, bool) {
+	if entry, exists := s.resources[uri]; exists {
+		return entry.handler, map[string]string{}, true
+	}
+
+	for _, entry := range s.templates {
+		if params, ok := entry.matcher.Match(uri); ok {
+			return entry.handler, params, true
+		}
+	}
+
+	return nil, nil, false
+}
+
+func (s *Server) handleListResources(sess *session, request *models.JSONRPCMessage) error {
+	resources := make([]models.Resource, 0, len(s.resourceOrder))
+	for _, uri := range s.resourceOrder {
+		if !s.visible(sess, accessResource, uri) {
+			continue
+		}
+		resources = append(resources, s.resources[uri].resource)
+	}
+
+	return sess.send(models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      request.ID,
+		Result:  models.ListResourcesResponse{Resources: resources},
+	})
+}
+
+func (s *Server) handleListResourceTemplates(sess *session, request *models.JSONRPCMessage) error {
+	templates := make([]models.ResourceTemplate, 0, len(s.templates))
+	for _, entry := range s.templates {
+		if !s.visible(sess, accessTemplate, entry.template.URITemplate) {
+			continue
+		}
+		templates = append(templates, entry.template)
+	}
+
+	return sess.send(models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      request.ID,
+		Result:  models.ListResourceTemplatesResponse{ResourceTemplates: templates},
+	})
+}
+
+func (s *Server) handleReadResource(ctx context.Context, sess *session, request *models.JSONRPCMessage) error {
+	var readReq models.ReadResourceRequest
+	if err := json.Unmarshal(request.Params, &readReq); err != nil || readReq.URI == "" {
+		return s.sendError(sess, request.ID, -32602, "Invalid params", "uri is required")
+	}
+
+	if allowed, err := s.authorize(sess, request.ID, accessResource, readReq.URI); !allowed {
+		return err
+	}
+
+	handler, params, exists := s.resolveResource(readReq.URI)
+	if !exists {
+		return s.sendError(sess, request.ID, -32002, "Resource not found", map[string]string{"uri": readReq.URI})
+	}
+
+	s.logfTo(sess, LevelInfo, "Reading resource: %s", readReq.URI)
+	contents, err := handler.Read(ctx, readReq.URI, params)
+
+	if ctx.Err() != nil {
+		s.logfTo(sess, LevelNotice, "Resource read %s cancelled: %v", readReq.URI, ctx.Err())
+		return nil
+	}
+
+	if errors.Is(err, ErrResourceNotFound) {
+		return s.sendError(sess, request.ID, -32002, "Resource not found", map[string]string{"uri": readReq.URI})
+	}
+	if err != nil {
+		s.logfTo(sess, LevelError, "Resource %s failed: %v", readReq.URI, err)
+		return s.sendError(sess, request.ID, -32603, "Resource read error", err.Error())
+	}
+
+	return sess.send(models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      request.ID,
+		Result:  models.ReadResourceResponse{Contents: contents},
+	})
+}
+
+// JSONResource encodes v as the single application/json content of uri.
+func JSONResource(uri string, v interface{}) ([]models.ResourceContents, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode resource %s: %w", uri, err)
+	}
+
+	return []models.ResourceContents{{URI: uri, MimeType: "application/json", Text: string(data)}}, nil
+}
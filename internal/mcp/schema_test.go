@@ -0,0 +1,38 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+func TestCallToolRejectsInvalidArguments(t *testing.T) {
+	conn := dialRaw(t, serveTCP(t, newTestServer()))
+	if response := conn.initialize(""); response.Error != nil {
+		t.Fatalf("initialize: %+v", response.Error)
+	}
+
+	response := conn.call("tools/call", models.CallToolRequest{Name: "echo", Arguments: map[string]interface{}{"delayMs": "soon"}})
+	if code := errorCode(response); code != -32602 || response.Error.Message != "Invalid params" {
+		t.Fatalf("got %+v, want -32602 Invalid params", response.Error)
+	}
+
+	problems, ok := response.Error.Data.([]interface{})
+	if !ok {
+		t.Fatalf("error data = %#v, want the list of problems", response.Error.Data)
+	}
+	var got []string
+	for _, problem := range problems {
+		got = append(got, fmt.Sprint(problem))
+	}
+	want := []string{"text: required property missing", "delayMs: expected number, got string"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems = %q, want %q", got, want)
+	}
+
+	if response := conn.call("tools/call", models.CallToolRequest{Name: "echo", Arguments: map[string]interface{}{"text": "valid"}}); response.Error != nil {
+		t.Errorf("valid arguments rejected: %+v", response.Error)
+	}
+}
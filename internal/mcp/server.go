@@ -1,12 +1,19 @@
 package mcp
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"proyecto-mcp-bolsa/pkg/models"
 )
@@ -15,18 +22,64 @@ type Server struct {
 	name         string
 	version      string
 	capabilities models.ServerCapabilities
-	tools        map[string]ToolHandler
+	tools        map[string]*registeredTool
+	toolOrder    []string
 	logger       *log.Logger
+
+	resources     map[string]*registeredResource
+	resourceOrder []string
+	templates     []*registeredTemplate
+
+	prompts     map[string]*registeredPrompt
+	promptOrder []string
+
+	pollInterval time.Duration
+	pollMu       sync.Mutex
+	polling      bool
+
+	sessionsMu sync.Mutex
+	sessions   map[*session]struct{}
+
+	httpMu       sync.Mutex
+	httpSessions map[string]*httpSession
+
+	auth     Authenticator
+	auditMu  sync.Mutex
+	auditLog io.Writer
+
+	limiter *limiter
+
+	shutdownMu   sync.Mutex
+	shuttingDown bool
+	done         chan struct{}
+	listeners    map[net.Listener]struct{}
+	httpServers  map[*http.Server]struct{}
+	conns        map[io.Closer]struct{}
+	onShutdown   []func(ctx context.Context) error
 }
 
+// supportedProtocolVersions lists the MCP revisions the server speaks, newest
+// first. A client asking for one of them gets it back; others get the newest.
+var supportedProtocolVersions = []string{"2025-03-26", "2024-11-05"}
+
+// registeredTool keeps everything tools/list advertises together with the
+// handler and the parsed schema used to validate incoming arguments.
+type registeredTool struct {
+	tool    models.Tool
+	schema  *Schema
+	handler ToolHandler
+}
+
+// ToolHandler executes a tool call. ctx is cancelled when the client cancels
+// the request or disconnects.
 type ToolHandler interface {
-	Handle(args map[string]interface{}) (*models.CallToolResponse, error)
+	Handle(ctx context.Context, args map[string]interface{}) (*models.CallToolResponse, error)
 }
 
-type ToolHandlerFunc func(args map[string]interface{}) (*models.CallToolResponse, error)
+type ToolHandlerFunc func(ctx context.Context, args map[string]interface{}) (*models.CallToolResponse, error)
 
-func (f ToolHandlerFunc) Handle(args map[string]interface{}) (*models.CallToolResponse, error) {
-	return f(args)
+func (f ToolHandlerFunc) Handle(ctx context.Context, args map[string]interface{}) (*models.CallToolResponse, error) {
+	return f(ctx, args)
 }
 
 func NewServer(name, version string) *Server {
@@ -39,67 +92,190 @@ func NewServer(name, version string) *Server {
 			},
 			Logging: &models.LoggingCapability{},
 		},
-		tools:  make(map[string]ToolHandler),
-		logger: log.New(os.Stderr, fmt.Sprintf("[%s] ", name), log.LstdFlags),
+		tools:     make(map[string]*registeredTool),
+		logger:    log.New(os.Stderr, fmt.Sprintf("[%s] ", name), log.LstdFlags),
+		resources: make(map[string]*registeredResource),
+		prompts:   make(map[string]*registeredPrompt),
+		sessions:  make(map[*session]struct{}),
+
+		httpSessions: make(map[string]*httpSession),
+
+		done:        make(chan struct{}),
+		listeners:   make(map[net.Listener]struct{}),
+		httpServers: make(map[*http.Server]struct{}),
+		conns:       make(map[io.Closer]struct{}),
 	}
 }
 
 func (s *Server) RegisterTool(name, description string, inputSchema json.RawMessage, handler ToolHandler) {
-	s.tools[name] = handler
+	s.RegisterToolWithAnnotations(name, description, inputSchema, nil, handler)
+}
+
+// RegisterToolWithAnnotations registers a tool together with its behaviour
+// hints. Tools are listed in registration order; registering an existing
+// name replaces it in place.
+func (s *Server) RegisterToolWithAnnotations(name, description string, inputSchema json.RawMessage, annotations *models.ToolAnnotations, handler ToolHandler) {
+	if len(inputSchema) == 0 {
+		inputSchema = json.RawMessage(`{"type": "object"}`)
+	}
+
+	schema, err := ParseSchema(inputSchema)
+	if err != nil {
+		s.logger.Printf("Ignoring input schema for tool %s: %v", name, err)
+		inputSchema = json.RawMessage(`{"type": "object"}`)
+		schema = nil
+	}
+
+	if _, exists := s.tools[name]; !exists {
+		s.toolOrder = append(s.toolOrder, name)
+	}
+
+	s.tools[name] = &registeredTool{
+		tool: models.Tool{
+			Name:        name,
+			Description: description,
+			InputSchema: inputSchema,
+			Annotations: annotations,
+		},
+		schema:  schema,
+		handler: handler,
+	}
 	s.logger.Printf("Registered tool: %s", name)
 }
 
-func (s *Server) HandleRequest(input io.Reader, output io.Writer) error {
+// HandleRequest serves one client session until input is exhausted or ctx is
+// cancelled. Tool calls run concurrently so that cancellation notifications
+// can reach them while they are in flight.
+func (s *Server) HandleRequest(ctx context.Context, input io.Reader, output io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	sess := newSession(output)
+	sess.identity = identityFrom(ctx)
+	sess.peer = peerFrom(ctx)
+	s.addSession(sess)
+	defer func() {
+		cancel()
+		sess.wait()
+		s.removeSession(sess)
+	}()
+
 	decoder := json.NewDecoder(input)
-	encoder := json.NewEncoder(output)
 
 	for {
-		var request models.JSONRPCRequest
+		var request models.JSONRPCMessage
 		if err := decoder.Decode(&request); err != nil {
 			if err == io.EOF {
-				s.logger.Println("Client disconnected")
+				s.logger.Print("Client disconnected")
 				return nil
 			}
-			return s.sendError(encoder, nil, -32700, "Parse error", err.Error())
+			if s.isShuttingDown() {
+				// Shutdown closed the connection.
+				return nil
+			}
+			return s.sendError(sess, nil, -32700, "Parse error", err.Error())
 		}
 
-		s.logger.Printf("Received request: %s", request.Method)
-
-		var err error
-		switch request.Method {
-		case "initialize":
-			err = s.handleInitialize(encoder, request)
-		case "tools/list":
-			err = s.handleListTools(encoder, request)
-		case "tools/call":
-			err = s.handleCallTool(encoder, request)
-		case "notifications/initialized":
-			err = s.handleInitialized(encoder, request)
-		default:
-			err = s.sendError(encoder, request.ID, -32601, "Method not found", request.Method)
+		if err := s.handleMessage(ctx, sess, &request); err != nil {
+			s.logger.Printf("Error handling request %s: %v", request.Method, err)
+			return err
 		}
+	}
+}
 
-		if err != nil {
-			s.logger.Printf("Error handling request %s: %v", request.Method, err)
+func (s *Server) handleMessage(ctx context.Context, sess *session, request *models.JSONRPCMessage) error {
+	if request.IsResponse() {
+		// Answers to server-initiated requests; nothing is waiting on them yet.
+		return nil
+	}
+
+	s.logfTo(sess, LevelDebug, "Received request: %s", request.Method)
+
+	if s.isShuttingDown() && !request.IsNotification() {
+		return s.sendError(sess, request.ID, codeShuttingDown, "Server is shutting down", nil)
+	}
+
+	if s.auth != nil && requiresIdentity(request.Method) && sess.currentIdentity() == nil {
+		if request.IsNotification() {
+			return nil
+		}
+		s.audit(sess, "denied", "", "not authenticated: "+request.Method)
+		return s.sendError(sess, request.ID, codeUnauthorized, "Unauthorized", "initialize with a bearer token first")
+	}
+
+	if !request.IsNotification() {
+		if allowed, err := s.allowRequest(sess, request.ID); !allowed {
 			return err
 		}
 	}
+
+	switch request.Method {
+	case "initialize":
+		return s.handleInitialize(sess, request)
+	case "ping":
+		return sess.send(models.JSONRPCResponse{JSONRPC: "2.0", ID: request.ID, Result: struct{}{}})
+	case "tools/list":
+		return s.handleListTools(sess, request)
+	case "logging/setLevel":
+		return s.handleSetLevel(sess, request)
+	case "tools/call":
+		s.startRequest(ctx, sess, request, s.handleCallTool)
+		return nil
+	case "resources/list":
+		return s.handleListResources(sess, request)
+	case "resources/templates/list":
+		return s.handleListResourceTemplates(sess, request)
+	case "resources/read":
+		s.startRequest(ctx, sess, request, s.handleReadResource)
+		return nil
+	case "prompts/list":
+		return s.handleListPrompts(sess, request)
+	case "prompts/get":
+		s.startRequest(ctx, sess, request, s.handleGetPrompt)
+		return nil
+	case "resources/subscribe":
+		return s.handleSubscribe(sess, request)
+	case "resources/unsubscribe":
+		return s.handleUnsubscribe(sess, request)
+	case "notifications/initialized":
+		return s.handleInitialized(sess, request)
+	case "notifications/cancelled":
+		return s.handleCancelled(sess, request)
+	default:
+		if request.IsNotification() {
+			s.logfTo(sess, LevelDebug, "Ignoring unknown notification: %s", request.Method)
+			return nil
+		}
+		return s.sendError(sess, request.ID, -32601, "Method not found", request.Method)
+	}
 }
 
-func (s *Server) handleInitialize(encoder *json.Encoder, request models.JSONRPCRequest) error {
+func (s *Server) handleInitialize(sess *session, request *models.JSONRPCMessage) error {
 	var initReq models.InitializeRequest
-	if request.Params != nil {
-		paramsBytes, _ := json.Marshal(request.Params)
-		if err := json.Unmarshal(paramsBytes, &initReq); err != nil {
-			return s.sendError(encoder, request.ID, -32602, "Invalid params", err.Error())
+	if len(request.Params) > 0 {
+		if err := json.Unmarshal(request.Params, &initReq); err != nil {
+			return s.sendError(sess, request.ID, -32602, "Invalid params", err.Error())
 		}
 	}
 
+	if s.auth != nil && sess.currentIdentity() == nil {
+		var authorization string
+		if initReq.Meta != nil {
+			authorization = initReq.Meta.Authorization
+		}
+		if !s.authenticate(sess, authorization) {
+			return s.sendError(sess, request.ID, codeUnauthorized, "Unauthorized", "a valid bearer token is required")
+		}
+	}
+
+	version := supportedProtocolVersions[0]
+	if containsString(supportedProtocolVersions, initReq.ProtocolVersion) {
+		version = initReq.ProtocolVersion
+	}
+
 	response := models.JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      request.ID,
 		Result: models.InitializeResponse{
-			ProtocolVersion: "2024-11-05",
+			ProtocolVersion: version,
 			Capabilities:    s.capabilities,
 			ServerInfo: models.ServerInfo{
 				Name:    s.name,
@@ -108,68 +284,17 @@ func (s *Server) handleInitialize(encoder *json.Encoder, request models.JSONRPCR
 		},
 	}
 
-	return encoder.Encode(response)
+	return sess.send(response)
 }
 
-func (s *Server) handleListTools(encoder *json.Encoder, request models.JSONRPCRequest) error {
-	tools := make([]models.Tool, 0, len(s.tools))
-	
-	for name := range s.tools {
-		var inputSchema json.RawMessage
-		switch name {
-		case "analyze_portfolio":
-			inputSchema = json.RawMessage(`{
-				"type": "object",
-				"properties": {
-					"symbols": {
-						"type": "array",
-						"items": {"type": "string"},
-						"description": "Array of stock symbols to analyze"
-					},
-					"timeframe": {
-						"type": "string",
-						"description": "Timeframe for analysis (1D, 5D, 1M, 3M, 6M, 1Y)",
-						"default": "1M"
-					}
-				},
-				"required": ["symbols"]
-			}`)
-		case "get_stock_price":
-			inputSchema = json.RawMessage(`{
-				"type": "object",
-				"properties": {
-					"symbol": {
-						"type": "string",
-						"description": "Stock symbol to get price for"
-					}
-				},
-				"required": ["symbol"]
-			}`)
-		case "export_analysis":
-			inputSchema = json.RawMessage(`{
-				"type": "object",
-				"properties": {
-					"format": {
-						"type": "string",
-						"enum": ["csv", "json"],
-						"description": "Export format",
-						"default": "json"
-					},
-					"filename": {
-						"type": "string",
-						"description": "Output filename"
-					}
-				},
-				"required": ["filename"]
-			}`)
-		}
-
-		tool := models.Tool{
-			Name:        name,
-			Description: s.getToolDescription(name),
-			InputSchema: inputSchema,
+func (s *Server) handleListTools(sess *session, request *models.JSONRPCMessage) error {
+	// Clients only see the tools they may call.
+	tools := make([]models.Tool, 0, len(s.toolOrder))
+	for _, name := range s.toolOrder {
+		if !s.visible(sess, accessTool, name) {
+			continue
 		}
-		tools = append(tools, tool)
+		tools = append(tools, s.tools[name].tool)
 	}
 
 	response := models.JSONRPCResponse{
@@ -178,27 +303,81 @@ func (s *Server) handleListTools(encoder *json.Encoder, request models.JSONRPCRe
 		Result:  models.ListToolsResponse{Tools: tools},
 	}
 
-	return encoder.Encode(response)
+	return sess.send(response)
 }
 
-func (s *Server) handleCallTool(encoder *json.Encoder, request models.JSONRPCRequest) error {
+// startRequest runs a potentially slow request on its own goroutine with a
+// context that is cancelled when the client sends notifications/cancelled for
+// it or the session ends. Logs sent through LogContext with that context
+// reach only sess.
+func (s *Server) startRequest(ctx context.Context, sess *session, request *models.JSONRPCMessage, handle func(context.Context, *session, *models.JSONRPCMessage) error) {
+	callCtx, cancel := context.WithCancel(withSession(ctx, sess))
+	key := idKey(request.ID)
+	sess.track(key, cancel)
+
+	go func() {
+		defer sess.untrack(key)
+		defer cancel()
+
+		if err := handle(callCtx, sess, request); err != nil {
+			s.logfTo(sess, LevelError, "Error handling request %s: %v", request.Method, err)
+		}
+	}()
+}
+
+func (s *Server) handleCallTool(ctx context.Context, sess *session, request *models.JSONRPCMessage) error {
 	var callReq models.CallToolRequest
-	if request.Params != nil {
-		paramsBytes, _ := json.Marshal(request.Params)
-		if err := json.Unmarshal(paramsBytes, &callReq); err != nil {
-			return s.sendError(encoder, request.ID, -32602, "Invalid params", err.Error())
+	if len(request.Params) > 0 {
+		if err := json.Unmarshal(request.Params, &callReq); err != nil {
+			return s.sendError(sess, request.ID, -32602, "Invalid params", err.Error())
 		}
 	}
 
-	handler, exists := s.tools[callReq.Name]
+	if allowed, err := s.authorize(sess, request.ID, accessTool, callReq.Name); !allowed {
+		return err
+	}
+
+	entry, exists := s.tools[callReq.Name]
 	if !exists {
-		return s.sendError(encoder, request.ID, -32601, "Tool not found", callReq.Name)
+		return s.sendError(sess, request.ID, -32601, "Tool not found", callReq.Name)
+	}
+
+	if entry.schema != nil {
+		var arguments interface{} = callReq.Arguments
+		if callReq.Arguments == nil {
+			arguments = map[string]interface{}{}
+		}
+		if problems := entry.schema.Validate(arguments); len(problems) > 0 {
+			s.logfTo(sess, LevelWarning, "Rejected call to %s: %s", callReq.Name, strings.Join(problems, "; "))
+			return s.sendError(sess, request.ID, -32602, "Invalid params", problems)
+		}
+	}
+
+	if allowed, err := s.allowToolCall(sess, request.ID, callReq.Name); !allowed {
+		return err
+	}
+
+	if callReq.Meta != nil && callReq.Meta.ProgressToken != nil {
+		ctx = withProgress(ctx, sess, callReq.Meta.ProgressToken)
+	}
+
+	s.logTo(sess, LevelInfo, fmt.Sprintf("Calling tool: %s", callReq.Name), map[string]interface{}{"tool": callReq.Name, "arguments": callReq.Arguments})
+	result, err := entry.handler.Handle(ctx, callReq.Arguments)
+
+	// A cancelled request must not be answered; the client stopped waiting.
+	// Calls cut short by Shutdown are told why instead.
+	if ctx.Err() != nil && s.isShuttingDown() {
+		s.logfTo(sess, LevelNotice, "Tool call %s interrupted by shutdown", callReq.Name)
+		return s.sendError(sess, request.ID, codeShuttingDown, "Server is shutting down", "the call was interrupted")
+	}
+	if ctx.Err() != nil {
+		s.logfTo(sess, LevelNotice, "Tool call %s cancelled: %v", callReq.Name, ctx.Err())
+		return nil
 	}
 
-	s.logger.Printf("Calling tool: %s", callReq.Name)
-	result, err := handler.Handle(callReq.Arguments)
 	if err != nil {
-		return s.sendError(encoder, request.ID, -32603, "Tool execution error", err.Error())
+		s.logfTo(sess, LevelError, "Tool %s failed: %v", callReq.Name, err)
+		return s.sendError(sess, request.ID, -32603, "Tool execution error", err.Error())
 	}
 
 	response := models.JSONRPCResponse{
@@ -207,15 +386,54 @@ func (s *Server) handleCallTool(encoder *json.Encoder, request models.JSONRPCReq
 		Result:  result,
 	}
 
-	return encoder.Encode(response)
+	return sess.send(response)
 }
 
-func (s *Server) handleInitialized(encoder *json.Encoder, request models.JSONRPCRequest) error {
-	s.logger.Println("Client initialized successfully")
+func (s *Server) handleInitialized(sess *session, request *models.JSONRPCMessage) error {
+	s.logfTo(sess, LevelInfo, "Client initialized successfully")
 	return nil
 }
 
-func (s *Server) sendError(encoder *json.Encoder, id interface{}, code int, message, data string) error {
+func (s *Server) handleCancelled(sess *session, request *models.JSONRPCMessage) error {
+	var notification struct {
+		RequestID json.RawMessage `json:"requestId"`
+		Reason    string          `json:"reason,omitempty"`
+	}
+	if err := json.Unmarshal(request.Params, &notification); err != nil {
+		s.logger.Printf("Ignoring malformed cancellation: %v", err)
+		return nil
+	}
+
+	if sess.cancel(idKey(notification.RequestID)) {
+		s.logfTo(sess, LevelNotice, "Cancelled request %s: %s", idKey(notification.RequestID), notification.Reason)
+	}
+	return nil
+}
+
+func (s *Server) addSession(sess *session) {
+	s.sessionsMu.Lock()
+	s.sessions[sess] = struct{}{}
+	s.sessionsMu.Unlock()
+}
+
+func (s *Server) removeSession(sess *session) {
+	s.sessionsMu.Lock()
+	delete(s.sessions, sess)
+	s.sessionsMu.Unlock()
+}
+
+func (s *Server) activeSessions() []*session {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	sessions := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	return sessions
+}
+
+func (s *Server) sendError(sess *session, id interface{}, code int, message string, data interface{}) error {
 	response := models.JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      id,
@@ -225,45 +443,79 @@ func (s *Server) sendError(encoder *json.Encoder, id interface{}, code int, mess
 			Data:    data,
 		},
 	}
-	return encoder.Encode(response)
-}
-
-func (s *Server) getToolDescription(name string) string {
-	descriptions := map[string]string{
-		"analyze_portfolio": "Analyze a portfolio of stocks and provide investment recommendations",
-		"get_stock_price":   "Get current stock price and basic information",
-		"export_analysis":   "Export analysis results to CSV or JSON format",
-	}
-	return descriptions[name]
+	return sess.send(response)
 }
 
 func (s *Server) Run() error {
 	s.logger.Printf("Starting %s server version %s", s.name, s.version)
-	return s.HandleRequest(os.Stdin, os.Stdout)
+	return s.HandleRequest(withIdentity(context.Background(), localIdentity), os.Stdin, os.Stdout)
 }
 
-// RunOnPort starts the MCP server listening on a TCP port
+// RunOnPort starts the MCP server listening on a TCP port on all interfaces.
+// Prefer ListenAndServe, which takes the interface and optional TLS.
 func (s *Server) RunOnPort(port int) error {
-	s.logger.Printf("Starting %s server version %s on port %d", s.name, s.version, port)
+	return s.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", port), nil)
+}
+
+// ListenAndServe serves newline-delimited JSON-RPC on address, over TLS when
+// tlsConfig is not nil.
+func (s *Server) ListenAndServe(address string, tlsConfig *tls.Config) error {
+	scheme := "tcp"
+	if tlsConfig != nil {
+		scheme = "tls"
+	}
+	s.logger.Printf("Starting %s server version %s on %s://%s", s.name, s.version, scheme, address)
 	
-	// Bind to all interfaces (0.0.0.0:port)
-	address := fmt.Sprintf("0.0.0.0:%d", port)
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", address, err)
 	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
 	defer listener.Close()
+	if !s.trackListener(listener) {
+		return ErrServerClosed
+	}
+	defer s.untrackListener(listener)
 
-	s.logger.Printf("MCP server listening on %s (all interfaces)", listener.Addr().String())
+	if host, _, _ := net.SplitHostPort(address); tlsConfig == nil && (host == "" || host == "0.0.0.0" || host == "::") {
+		s.logger.Printf("Warning: listening on all interfaces without TLS; anyone on the network can call tools")
+	}
+	s.logger.Printf("MCP server listening on %s", listener.Addr().String())
 
+	var delay time.Duration
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			s.logger.Printf("Failed to accept connection: %v", err)
+			if s.isShuttingDown() {
+				return ErrServerClosed
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+
+			// Transient failures such as running out of file descriptors
+			// are retried with backoff instead of spinning.
+			if delay == 0 {
+				delay = 5 * time.Millisecond
+			} else if delay < time.Second {
+				delay *= 2
+			}
+			s.logger.Printf("Failed to accept connection: %v; retrying in %s", err, delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+
+		if !s.acquireConnection() {
+			s.logger.Printf("Rejected client %s: connection limit reached", conn.RemoteAddr())
+			go s.rejectConnection(conn)
 			continue
 		}
 
 		s.logger.Printf("New client connected from %s", conn.RemoteAddr())
+		s.trackConn(conn)
 		go s.handleConnection(conn)
 	}
 }
@@ -273,13 +525,50 @@ func (s *Server) handleConnection(conn net.Conn) {
 	defer func() {
 		s.logger.Printf("Client %s disconnected", conn.RemoteAddr())
 		conn.Close()
+		s.untrackConn(conn)
+		s.releaseConnection()
 	}()
 
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// Handshake up front so a silent client cannot hold the connection
+		// and certificate problems are reported as such.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := tlsConn.HandshakeContext(ctx)
+		cancel()
+		if err != nil {
+			s.logger.Printf("TLS handshake with %s failed: %v", conn.RemoteAddr(), err)
+			return
+		}
+		if peers := tlsConn.ConnectionState().PeerCertificates; len(peers) > 0 {
+			s.logger.Printf("Client %s authenticated as %q", conn.RemoteAddr(), peers[0].Subject.CommonName)
+		}
+	}
+
 	s.logger.Printf("Handling connection from %s", conn.RemoteAddr())
 	
-	if err := s.HandleRequest(conn, conn); err != nil {
+	if err := s.HandleRequest(withPeer(context.Background(), conn.RemoteAddr().String()), conn, conn); err != nil {
 		s.logger.Printf("Connection error from %s: %v", conn.RemoteAddr(), err)
 	} else {
 		s.logger.Printf("Connection from %s completed successfully", conn.RemoteAddr())
 	}
-}
\ No newline at end of file
+}
+
+// rejectConnection answers the first request of a client over the
+// connection limit with an error, so it learns why it is being dropped, and
+// closes the connection.
+func (s *Server) rejectConnection(conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	var request models.JSONRPCMessage
+	json.NewDecoder(conn).Decode(&request)
+
+	json.NewEncoder(conn).Encode(models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      request.ID,
+		Error: &models.JSONRPCError{
+			Code:    codeRateLimited,
+			Message: "Too many connections, try again later",
+		},
+	})
+}
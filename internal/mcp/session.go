@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// session is the server side of one client connection. Writes are serialized
+// because tool calls answer from their own goroutines.
+type session struct {
+	writeMu sync.Mutex
+	write   func(message interface{}) error
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+	wg       sync.WaitGroup
+
+	// logLevel is empty until the client calls logging/setLevel; nothing is
+	// forwarded before that.
+	logLevel string
+
+	subscribed map[string]struct{}
+
+	// identity is set once the client authenticated; peer is its remote
+	// address, empty for stdio.
+	identity *Identity
+	peer     string
+}
+
+func newSession(output io.Writer) *session {
+	return newSessionFunc(json.NewEncoder(output).Encode)
+}
+
+// newSessionFunc creates a session whose messages are handed to write, for
+// transports that route each message themselves.
+func newSessionFunc(write func(message interface{}) error) *session {
+	return &session{
+		write:      write,
+		inflight:   make(map[string]context.CancelFunc),
+		subscribed: make(map[string]struct{}),
+	}
+}
+
+func (ss *session) send(message interface{}) error {
+	ss.writeMu.Lock()
+	defer ss.writeMu.Unlock()
+	return ss.write(message)
+}
+
+func (ss *session) track(key string, cancel context.CancelFunc) {
+	ss.mu.Lock()
+	ss.inflight[key] = cancel
+	ss.mu.Unlock()
+	ss.wg.Add(1)
+}
+
+func (ss *session) untrack(key string) {
+	ss.mu.Lock()
+	delete(ss.inflight, key)
+	ss.mu.Unlock()
+	ss.wg.Done()
+}
+
+// cancel cancels the in-flight request with the given id and reports whether
+// one was found.
+func (ss *session) cancel(key string) bool {
+	ss.mu.Lock()
+	cancel, exists := ss.inflight[key]
+	ss.mu.Unlock()
+
+	if exists {
+		cancel()
+	}
+	return exists
+}
+
+// wait blocks until every in-flight tool call has returned.
+func (ss *session) wait() {
+	ss.wg.Wait()
+}
+
+func (ss *session) setLogLevel(level string) {
+	ss.mu.Lock()
+	ss.logLevel = level
+	ss.mu.Unlock()
+}
+
+func (ss *session) wantsLog(level string) bool {
+	ss.mu.Lock()
+	threshold := ss.logLevel
+	ss.mu.Unlock()
+
+	if threshold == "" {
+		return false
+	}
+	return logSeverity[level] >= logSeverity[threshold]
+}
+
+func (ss *session) subscribe(uri string) {
+	ss.mu.Lock()
+	ss.subscribed[uri] = struct{}{}
+	ss.mu.Unlock()
+}
+
+func (ss *session) unsubscribe(uri string) {
+	ss.mu.Lock()
+	delete(ss.subscribed, uri)
+	ss.mu.Unlock()
+}
+
+func (ss *session) isSubscribed(uri string) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	_, exists := ss.subscribed[uri]
+	return exists
+}
+
+func (ss *session) subscriptions() []string {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	uris := make([]string, 0, len(ss.subscribed))
+	for uri := range ss.subscribed {
+		uris = append(uris, uri)
+	}
+	return uris
+}
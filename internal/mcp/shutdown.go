@@ -0,0 +1,239 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// ErrServerClosed is returned by ListenAndServe and RunHTTP once Shutdown has
+// been called.
+var ErrServerClosed = errors.New("mcp: server closed")
+
+// codeShuttingDown answers requests that arrive while the server drains.
+const codeShuttingDown = -32013
+
+// shutdownHookTimeout bounds the hooks, which run even when the drain
+// deadline has already passed.
+const shutdownHookTimeout = 5 * time.Second
+
+// RegisterOnShutdown registers a function Shutdown calls once every session
+// has ended, e.g. to flush caches to disk.
+func (s *Server) RegisterOnShutdown(hook func(ctx context.Context) error) {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+	s.onShutdown = append(s.onShutdown, hook)
+}
+
+// Shutdown stops accepting connections, tells connected clients the server
+// is going away and waits for in-flight requests to finish. When ctx expires
+// first the remaining requests are cancelled and ctx's error is returned.
+// Connections are then closed and the shutdown hooks run.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownMu.Lock()
+	if s.shuttingDown {
+		s.shutdownMu.Unlock()
+		return nil
+	}
+	s.shuttingDown = true
+	close(s.done)
+
+	listeners := make([]net.Listener, 0, len(s.listeners))
+	for listener := range s.listeners {
+		listeners = append(listeners, listener)
+	}
+	servers := make([]*http.Server, 0, len(s.httpServers))
+	for server := range s.httpServers {
+		servers = append(servers, server)
+	}
+	s.shutdownMu.Unlock()
+
+	s.logger.Printf("Shutting down: no longer accepting connections")
+	for _, listener := range listeners {
+		listener.Close()
+	}
+
+	// http.Server.Shutdown closes its listeners right away and then waits
+	// for connections to go idle, which open streams only do once their
+	// sessions are closed below.
+	httpDone := make(chan struct{}, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			server.Shutdown(ctx)
+			httpDone <- struct{}{}
+		}(server)
+	}
+
+	s.notifyShutdown(ctx)
+
+	err := s.drain(ctx)
+	if err != nil {
+		s.logger.Printf("Shutdown deadline reached; cancelling in-flight requests")
+		for _, sess := range s.activeSessions() {
+			sess.cancelAll()
+		}
+
+		// Give the cancelled calls a moment to send their errors.
+		graceCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		s.drain(graceCtx)
+		cancel()
+	}
+
+	s.closeHTTPSessions()
+	s.shutdownMu.Lock()
+	conns := make([]io.Closer, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	hooks := s.onShutdown
+	s.shutdownMu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	for range servers {
+		<-httpDone
+	}
+
+	hookCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownHookTimeout)
+	defer cancel()
+	for _, hook := range hooks {
+		if hookErr := hook(hookCtx); hookErr != nil {
+			s.logger.Printf("Shutdown hook failed: %v", hookErr)
+			if err == nil {
+				err = hookErr
+			}
+		}
+	}
+
+	s.logger.Printf("Shutdown complete")
+	return err
+}
+
+// notifyShutdown sends notifications/shutdown to every session. Sends run in
+// the background so a client that stopped reading cannot stall shutdown.
+func (s *Server) notifyShutdown(ctx context.Context) {
+	params := models.ShutdownNotification{Reason: "server is shutting down"}
+	if deadline, ok := ctx.Deadline(); ok {
+		params.Deadline = &deadline
+	}
+
+	notification := models.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/shutdown",
+		Params:  params,
+	}
+	for _, sess := range s.activeSessions() {
+		go sess.send(notification)
+	}
+}
+
+// drain waits until no session has a request in flight.
+func (s *Server) drain(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		busy := 0
+		for _, sess := range s.activeSessions() {
+			busy += sess.inflightCount()
+		}
+		if busy == 0 {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Server) closeHTTPSessions() {
+	s.httpMu.Lock()
+	sessions := make([]*httpSession, 0, len(s.httpSessions))
+	for _, hs := range s.httpSessions {
+		sessions = append(sessions, hs)
+	}
+	s.httpMu.Unlock()
+
+	for _, hs := range sessions {
+		s.closeHTTPSession(hs)
+	}
+}
+
+func (s *Server) isShuttingDown() bool {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+	return s.shuttingDown
+}
+
+// trackListener registers a listener for Shutdown to close. It reports false
+// when the server is already shutting down.
+func (s *Server) trackListener(listener net.Listener) bool {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+	if s.shuttingDown {
+		return false
+	}
+	s.listeners[listener] = struct{}{}
+	return true
+}
+
+func (s *Server) untrackListener(listener net.Listener) {
+	s.shutdownMu.Lock()
+	delete(s.listeners, listener)
+	s.shutdownMu.Unlock()
+}
+
+func (s *Server) trackHTTPServer(server *http.Server) bool {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+	if s.shuttingDown {
+		return false
+	}
+	s.httpServers[server] = struct{}{}
+	return true
+}
+
+func (s *Server) untrackHTTPServer(server *http.Server) {
+	s.shutdownMu.Lock()
+	delete(s.httpServers, server)
+	s.shutdownMu.Unlock()
+}
+
+// trackConn registers a client connection for Shutdown to close; untrack it
+// when the connection ends.
+func (s *Server) trackConn(conn io.Closer) {
+	s.shutdownMu.Lock()
+	s.conns[conn] = struct{}{}
+	s.shutdownMu.Unlock()
+}
+
+func (s *Server) untrackConn(conn io.Closer) {
+	s.shutdownMu.Lock()
+	delete(s.conns, conn)
+	s.shutdownMu.Unlock()
+}
+
+func (ss *session) inflightCount() int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return len(ss.inflight)
+}
+
+// cancelAll cancels every in-flight request.
+func (ss *session) cancelAll() {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for _, cancel := range ss.inflight {
+		cancel()
+	}
+}
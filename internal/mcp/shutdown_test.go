@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// waitInflight waits until s has n requests in flight.
+func waitInflight(t *testing.T, s *Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		busy := 0
+		for _, sess := range s.activeSessions() {
+			busy += sess.inflightCount()
+		}
+		if busy == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("server never had %d requests in flight", n)
+}
+
+type callResult struct {
+	result *models.CallToolResponse
+	err    error
+}
+
+func callAsync(ctx context.Context, client *Client, args map[string]interface{}) <-chan callResult {
+	done := make(chan callResult, 1)
+	go func() {
+		result, err := client.CallTool(ctx, "echo", args)
+		done <- callResult{result, err}
+	}()
+	return done
+}
+
+func TestShutdownDrainsInFlightCalls(t *testing.T) {
+	s := newTestServer()
+	address, served := listenAndServe(t, s, nil)
+	client := connectTCP(t, address)
+	var notices logRecorder
+	client.OnNotification("notifications/shutdown", notices.handle)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	call := callAsync(ctx, client, map[string]interface{}{"text": "finished", "delayMs": 300})
+	waitInflight(t, s, 1)
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(ctx) }()
+
+	// Requests arriving while the server drains are refused.
+	for !s.isShuttingDown() {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "late"}); err == nil || !strings.Contains(err.Error(), "shutting down") {
+		t.Errorf("call during shutdown: got %v, want a shutting down error", err)
+	}
+
+	got := <-call
+	if got.err != nil {
+		t.Fatalf("in-flight call: %v", got.err)
+	}
+	if got.result.Content[0].Text != "finished" {
+		t.Errorf("in-flight call result = %+v", got.result.Content)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+	if err := <-served; !errors.Is(err, ErrServerClosed) {
+		t.Errorf("ListenAndServe = %v, want ErrServerClosed", err)
+	}
+	if !notices.contains("server is shutting down") {
+		t.Errorf("client was not told about the shutdown: %v", notices.messages)
+	}
+}
+
+func TestShutdownCancelsCallsAtDeadline(t *testing.T) {
+	s := newTestServer()
+	hookRan := make(chan struct{}, 1)
+	s.RegisterOnShutdown(func(ctx context.Context) error {
+		hookRan <- struct{}{}
+		return nil
+	})
+	address, served := listenAndServe(t, s, nil)
+	client := connectTCP(t, address)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	call := callAsync(ctx, client, map[string]interface{}{"text": "slow", "delayMs": 60000})
+	waitInflight(t, s, 1)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer shutdownCancel()
+	start := time.Now()
+	if err := s.Shutdown(shutdownCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Shutdown took %s, the call was not cancelled", elapsed)
+	}
+
+	got := <-call
+	if got.err == nil || !strings.Contains(got.err.Error(), "shutting down") {
+		t.Errorf("cancelled call: got %v, want a shutting down error", got.err)
+	}
+	select {
+	case <-hookRan:
+	default:
+		t.Error("shutdown hook did not run")
+	}
+	if err := <-served; !errors.Is(err, ErrServerClosed) {
+		t.Errorf("ListenAndServe = %v, want ErrServerClosed", err)
+	}
+}
+
+func TestNoConnectionsAfterShutdown(t *testing.T) {
+	s := newTestServer()
+	address, served := listenAndServe(t, s, nil)
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := <-served; !errors.Is(err, ErrServerClosed) {
+		t.Errorf("ListenAndServe = %v, want ErrServerClosed", err)
+	}
+
+	if conn, err := net.DialTimeout("tcp", address, time.Second); err == nil {
+		conn.Close()
+		t.Error("listener still accepts connections after Shutdown")
+	}
+	if err := s.ListenAndServe("127.0.0.1:0", nil); !errors.Is(err, ErrServerClosed) {
+		t.Errorf("ListenAndServe after Shutdown = %v, want ErrServerClosed", err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown = %v, want nil", err)
+	}
+}
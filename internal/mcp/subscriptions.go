@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// EnableSubscriptions advertises resources/subscribe and re-reads every
+// subscribed resource each interval, sending notifications/resources/updated
+// to its subscribers whenever the contents change.
+func (s *Server) EnableSubscriptions(interval time.Duration) {
+	s.enableResources()
+	s.capabilities.Resources.Subscribe = true
+	s.pollInterval = interval
+}
+
+// NotifyResourceUpdated tells every session subscribed to uri that it
+// changed. Servers with push-based data sources can call it directly.
+func (s *Server) NotifyResourceUpdated(uri string) {
+	notification := models.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/resources/updated",
+		Params:  models.ResourceUpdatedNotification{URI: uri},
+	}
+
+	for _, sess := range s.subscribers(uri) {
+		sess.send(notification)
+	}
+}
+
+// subscribers are the sessions subscribed to uri. Logs about a resource go
+// only to them, since the URI tells what they are watching.
+func (s *Server) subscribers(uri string) []*session {
+	var sessions []*session
+	for _, sess := range s.activeSessions() {
+		if sess.isSubscribed(uri) {
+			sessions = append(sessions, sess)
+		}
+	}
+	return sessions
+}
+
+func (s *Server) handleSubscribe(sess *session, request *models.JSONRPCMessage) error {
+	if s.capabilities.Resources == nil || !s.capabilities.Resources.Subscribe {
+		return s.sendError(sess, request.ID, -32601, "Method not found", request.Method)
+	}
+
+	var subReq models.SubscribeRequest
+	if err := json.Unmarshal(request.Params, &subReq); err != nil || subReq.URI == "" {
+		return s.sendError(sess, request.ID, -32602, "Invalid params", "uri is required")
+	}
+
+	if allowed, err := s.authorize(sess, request.ID, accessResource, subReq.URI); !allowed {
+		return err
+	}
+
+	if _, _, exists := s.resolveResource(subReq.URI); !exists {
+		return s.sendError(sess, request.ID, -32002, "Resource not found", map[string]string{"uri": subReq.URI})
+	}
+
+	sess.subscribe(subReq.URI)
+	s.logfTo(sess, LevelInfo, "Client subscribed to %s", subReq.URI)
+	s.startPolling()
+
+	return sess.send(models.JSONRPCResponse{JSONRPC: "2.0", ID: request.ID, Result: struct{}{}})
+}
+
+func (s *Server) handleUnsubscribe(sess *session, request *models.JSONRPCMessage) error {
+	var unsubReq models.UnsubscribeRequest
+	if err := json.Unmarshal(request.Params, &unsubReq); err != nil || unsubReq.URI == "" {
+		return s.sendError(sess, request.ID, -32602, "Invalid params", "uri is required")
+	}
+
+	sess.unsubscribe(unsubReq.URI)
+	s.logfTo(sess, LevelInfo, "Client unsubscribed from %s", unsubReq.URI)
+
+	return sess.send(models.JSONRPCResponse{JSONRPC: "2.0", ID: request.ID, Result: struct{}{}})
+}
+
+// subscribedURIs is the union of all sessions' subscriptions.
+func (s *Server) subscribedURIs() []string {
+	seen := make(map[string]struct{})
+	for _, sess := range s.activeSessions() {
+		for _, uri := range sess.subscriptions() {
+			seen[uri] = struct{}{}
+		}
+	}
+
+	uris := make([]string, 0, len(seen))
+	for uri := range seen {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// startPolling launches the poller unless it is already running. It stops on
+// its own once nobody is subscribed.
+func (s *Server) startPolling() {
+	s.pollMu.Lock()
+	defer s.pollMu.Unlock()
+
+	if s.polling {
+		return
+	}
+	s.polling = true
+	go s.pollResources()
+}
+
+func (s *Server) pollResources() {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	// digests remembers the last contents seen per URI so only real changes
+	// are reported; the first read just records a baseline.
+	digests := make(map[string][32]byte)
+
+	for {
+		s.pollMu.Lock()
+		uris := s.subscribedURIs()
+		if len(uris) == 0 {
+			s.polling = false
+			s.pollMu.Unlock()
+			return
+		}
+		s.pollMu.Unlock()
+
+		for _, uri := range uris {
+			digest, ok := s.digestResource(uri)
+			if !ok {
+				continue
+			}
+
+			previous, seen := digests[uri]
+			digests[uri] = digest
+			if seen && previous != digest {
+				s.log(s.subscribers(uri), LevelDebug, "Resource updated: "+uri, nil)
+				s.NotifyResourceUpdated(uri)
+			}
+		}
+
+		for uri := range digests {
+			if !containsString(uris, uri) {
+				delete(digests, uri)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			s.pollMu.Lock()
+			s.polling = false
+			s.pollMu.Unlock()
+			return
+		}
+	}
+}
+
+func (s *Server) digestResource(uri string) ([32]byte, bool) {
+	handler, params, exists := s.resolveResource(uri)
+	if !exists {
+		return [32]byte{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.pollInterval)
+	defer cancel()
+
+	contents, err := handler.Read(ctx, uri, params)
+	if err != nil {
+		s.log(s.subscribers(uri), LevelWarning, fmt.Sprintf("Polling %s failed: %v", uri, err), nil)
+		return [32]byte{}, false
+	}
+
+	data, err := json.Marshal(contents)
+	if err != nil {
+		return [32]byte{}, false
+	}
+	return sha256.Sum256(data), true
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package mcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeSelfSigned writes a self-signed certificate for usage and its key to
+// dir and returns their paths and the certificate. Client certificates are
+// their own CA.
+func writeSelfSigned(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (string, string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{usage},
+		BasicConstraintsValid: true,
+		IsCA:                  usage == x509.ExtKeyUsageClientAuth,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath, cert
+}
+
+// colonFingerprint formats cert's fingerprint the way openssl prints it.
+func colonFingerprint(cert *x509.Certificate) string {
+	hex := strings.ToUpper(CertificateFingerprint(cert))
+	pairs := make([]string, 0, len(hex)/2)
+	for i := 0; i < len(hex); i += 2 {
+		pairs = append(pairs, hex[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
+
+// connectTLS connects to address with options and initializes, returning
+// the first error.
+func connectTLS(t *testing.T, address string, options ClientTLSOptions) error {
+	t.Helper()
+	config, err := options.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(nil, log.New(io.Discard, "", 0))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.ConnectTLS(ctx, address, config); err != nil {
+		return err
+	}
+	_, err = client.Initialize(ctx)
+	return err
+}
+
+func serveTLS(t *testing.T, options ServerTLSOptions) string {
+	t.Helper()
+	config, err := options.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serveUntilCleanup(t, newTestServer(), config)
+}
+
+func TestTLSPinning(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, cert := writeSelfSigned(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	_, _, other := writeSelfSigned(t, dir, "other", x509.ExtKeyUsageServerAuth)
+	address := serveTLS(t, ServerTLSOptions{CertFile: certFile, KeyFile: keyFile})
+
+	if err := connectTLS(t, address, ClientTLSOptions{Pins: []string{colonFingerprint(cert)}}); err != nil {
+		t.Errorf("matching pin: %v", err)
+	}
+	if err := connectTLS(t, address, ClientTLSOptions{Pins: []string{CertificateFingerprint(other)}}); err == nil || !strings.Contains(err.Error(), "does not match any pinned fingerprint") {
+		t.Errorf("wrong pin: got %v, want a pin mismatch", err)
+	}
+	// Without a pin or CA the self-signed certificate is not trusted.
+	if err := connectTLS(t, address, ClientTLSOptions{}); err == nil {
+		t.Error("connected to a self-signed server without a pin")
+	}
+}
+
+func TestTLSRequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, cert := writeSelfSigned(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey, _ := writeSelfSigned(t, dir, "client", x509.ExtKeyUsageClientAuth)
+	address := serveTLS(t, ServerTLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCert})
+	pin := []string{CertificateFingerprint(cert)}
+
+	if err := connectTLS(t, address, ClientTLSOptions{Pins: pin}); err == nil {
+		t.Error("connected without a client certificate")
+	}
+	if err := connectTLS(t, address, ClientTLSOptions{Pins: pin, CertFile: clientCert, KeyFile: clientKey}); err != nil {
+		t.Errorf("with a client certificate: %v", err)
+	}
+}
+
+func TestNormalizeFingerprint(t *testing.T) {
+	want := strings.Repeat("ab", 32)
+	for _, pin := range []string{
+		want,
+		strings.ToUpper(want),
+		"sha256:" + want,
+		"SHA256:" + strings.TrimSuffix(strings.Repeat("AB:", 32), ":"),
+		"  " + want + "\n",
+	} {
+		got, err := normalizeFingerprint(pin)
+		if err != nil || got != want {
+			t.Errorf("normalizeFingerprint(%q) = %q, %v; want %q", pin, got, err, want)
+		}
+	}
+
+	for _, pin := range []string{"", "sha1:" + want, strings.Repeat("ab", 20), strings.Repeat("zz", 32)} {
+		if _, err := normalizeFingerprint(pin); err == nil {
+			t.Errorf("normalizeFingerprint(%q) accepted an invalid pin", pin)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// TypedToolFunc is a tool handler whose arguments are decoded into In. The
+// returned Out is sent back as text: strings as-is, *models.CallToolResponse
+// untouched and anything else as indented JSON.
+type TypedToolFunc[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// RegisterTypedTool registers a tool whose input schema is derived from the
+// struct tags of In:
+//
+//	json:"name"          property name ("-" skips the field)
+//	description:"..."    property description
+//	required:"true"      property must be present
+//	enum:"a,b,c"         allowed values
+//	default:"value"      value used when the property is omitted
+//	minimum/maximum:"n"  numeric bounds
+//	minItems/maxItems:"n" array length bounds
+//
+// Embedded structs without a json name contribute their properties, as they
+// do in encoding/json; a default tag on the embedded field replaces the
+// defaults of those properties.
+//
+// A handler error is reported to the client as an isError tool result.
+func RegisterTypedTool[In, Out any](s *Server, name, description string, annotations *models.ToolAnnotations, handler TypedToolFunc[In, Out]) {
+	schema, err := SchemaFor[In]()
+	if err != nil {
+		panic(fmt.Sprintf("mcp: cannot derive schema for tool %s: %v", name, err))
+	}
+
+	rawSchema, err := json.Marshal(schema)
+	if err != nil {
+		panic(fmt.Sprintf("mcp: cannot encode schema for tool %s: %v", name, err))
+	}
+
+	adapter := ToolHandlerFunc(func(ctx context.Context, args map[string]interface{}) (*models.CallToolResponse, error) {
+		var in In
+		if err := decodeArguments(schema, args, &in); err != nil {
+			return nil, err
+		}
+
+		out, err := handler(ctx, in)
+		if err != nil {
+			return &models.CallToolResponse{
+				Content: []models.Content{{Type: "text", Text: err.Error()}},
+				IsError: true,
+			}, nil
+		}
+
+		return toolResult(out)
+	})
+
+	s.RegisterToolWithAnnotations(name, description, rawSchema, annotations, adapter)
+}
+
+// SchemaFor derives the JSON schema for T from its Go type and struct tags.
+func SchemaFor[T any]() (*Schema, error) {
+	var zero T
+	return schemaForType(reflect.TypeOf(&zero).Elem())
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaForType(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Description: "RFC 3339 timestamp"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map keys must be strings, got %s", t.Key())
+		}
+		return &Schema{Type: "object"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Struct:
+		return schemaForStruct(t)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func schemaForStruct(t reflect.Type) (*Schema, error) {
+	schema := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			if err := embedStruct(schema, field); err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		prop, err := schemaForType(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		if err := applyFieldTags(prop, field); err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		if field.Tag.Get("required") == "true" {
+			schema.Required = append(schema.Required, name)
+		}
+
+		schema.Properties[name] = prop
+	}
+
+	return schema, nil
+}
+
+// embedStruct adds the properties of the embedded struct field to schema.
+func embedStruct(schema *Schema, field reflect.StructField) error {
+	t := field.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("embedded %s must be a struct", t)
+	}
+
+	embedded, err := schemaForStruct(t)
+	if err != nil {
+		return err
+	}
+	for name, prop := range embedded.Properties {
+		if def, ok := field.Tag.Lookup("default"); ok {
+			parsed, err := parseTagValue(prop.Type, def)
+			if err != nil {
+				return fmt.Errorf("default: %w", err)
+			}
+			prop.Default = parsed
+		}
+		schema.Properties[name] = prop
+	}
+	schema.Required = append(schema.Required, embedded.Required...)
+	return nil
+}
+
+func applyFieldTags(prop *Schema, field reflect.StructField) error {
+	if description := field.Tag.Get("description"); description != "" {
+		prop.Description = description
+	}
+
+	if enum := field.Tag.Get("enum"); enum != "" {
+		for _, value := range strings.Split(enum, ",") {
+			parsed, err := parseTagValue(prop.Type, strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("enum: %w", err)
+			}
+			prop.Enum = append(prop.Enum, parsed)
+		}
+	}
+
+	if def, ok := field.Tag.Lookup("default"); ok {
+		parsed, err := parseTagValue(prop.Type, def)
+		if err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+		prop.Default = parsed
+	}
+
+	for tag, target := range map[string]**float64{"minimum": &prop.Minimum, "maximum": &prop.Maximum} {
+		if value := field.Tag.Get(tag); value != "" {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", tag, err)
+			}
+			*target = &n
+		}
+	}
+
+	for tag, target := range map[string]**int{"minItems": &prop.MinItems, "maxItems": &prop.MaxItems} {
+		if value := field.Tag.Get(tag); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", tag, err)
+			}
+			*target = &n
+		}
+	}
+
+	return nil
+}
+
+// parseTagValue converts a tag string into the JSON value matching the
+// property type so enum and default values compare equal to decoded input.
+func parseTagValue(schemaType, value string) (interface{}, error) {
+	switch schemaType {
+	case "integer", "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "array", "object":
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			return nil, err
+		}
+		return parsed, nil
+	default:
+		return value, nil
+	}
+}
+
+// decodeArguments fills in schema defaults for omitted properties and decodes
+// the result into target.
+func decodeArguments(schema *Schema, args map[string]interface{}, target interface{}) error {
+	merged := make(map[string]interface{}, len(args))
+	for name, prop := range schema.Properties {
+		if prop.Default != nil {
+			merged[name] = prop.Default
+		}
+	}
+	for name, value := range args {
+		merged[name] = value
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to decode arguments: %w", err)
+	}
+
+	return nil
+}
+
+func toolResult(out interface{}) (*models.CallToolResponse, error) {
+	switch v := out.(type) {
+	case *models.CallToolResponse:
+		return v, nil
+	case models.CallToolResponse:
+		return &v, nil
+	case string:
+		return &models.CallToolResponse{
+			Content: []models.Content{{Type: "text", Text: v}},
+		}, nil
+	default:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tool result: %w", err)
+		}
+		return &models.CallToolResponse{
+			Content: []models.Content{{Type: "text", Text: string(data)}},
+		}, nil
+	}
+}
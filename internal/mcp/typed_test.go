@@ -0,0 +1,49 @@
+package mcp
+
+import "testing"
+
+type timeframeArg struct {
+	Timeframe string `json:"timeframe" enum:"1M,3M" default:"1M" description:"Timeframe"`
+}
+
+type shortInput struct {
+	Symbol string `json:"symbol" required:"true"`
+	timeframeArg
+}
+
+type longInput struct {
+	Symbol       string `json:"symbol" required:"true"`
+	timeframeArg `default:"3M"`
+}
+
+func TestSchemaForEmbeddedStruct(t *testing.T) {
+	short, err := SchemaFor[shortInput]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	long, err := SchemaFor[longInput]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, schema := range []*Schema{short, long} {
+		prop := schema.Properties["timeframe"]
+		if prop == nil || prop.Description != "Timeframe" || len(prop.Enum) != 2 {
+			t.Fatalf("embedded timeframe property = %+v", prop)
+		}
+	}
+	if got := short.Properties["timeframe"].Default; got != "1M" {
+		t.Errorf("default = %v, want 1M", got)
+	}
+	if got := long.Properties["timeframe"].Default; got != "3M" {
+		t.Errorf("overridden default = %v, want 3M", got)
+	}
+
+	var in longInput
+	if err := decodeArguments(long, map[string]interface{}{"symbol": "AAPL"}, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Symbol != "AAPL" || in.Timeframe != "3M" {
+		t.Errorf("decoded %+v", in)
+	}
+}
@@ -0,0 +1,205 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsPingInterval must stay below wsPongWait so a healthy peer always
+	// answers before the read deadline passes.
+	wsPingInterval = 30 * time.Second
+	wsPongWait     = 60 * time.Second
+	wsWriteWait    = 10 * time.Second
+)
+
+// wsSubprotocol is offered by both sides; peers that do not know it still
+// connect.
+const wsSubprotocol = "mcp"
+
+// wsStream adapts a WebSocket connection to the byte streams the JSON
+// encoder and decoder work on: every Write is sent as one text message and
+// Read returns incoming messages back to back. It pings the peer and treats
+// a missing pong as a dead connection.
+type wsStream struct {
+	conn   *websocket.Conn
+	reader io.Reader
+
+	writeMu   sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newWSStream(conn *websocket.Conn) *wsStream {
+	stream := &wsStream{
+		conn: conn,
+		done: make(chan struct{}),
+	}
+
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	go stream.keepAlive()
+	return stream
+}
+
+func (ws *wsStream) Read(p []byte) (int, error) {
+	for {
+		if ws.reader == nil {
+			_, reader, err := ws.conn.NextReader()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+			ws.reader = reader
+		}
+
+		n, err := ws.reader.Read(p)
+		if errors.Is(err, io.EOF) {
+			ws.reader = nil
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+func (ws *wsStream) Write(p []byte) (int, error) {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := ws.conn.WriteMessage(websocket.TextMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (ws *wsStream) keepAlive() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		case <-ws.done:
+			return
+		}
+	}
+}
+
+// Close sends a normal close frame before dropping the connection so the
+// peer sees a clean shutdown rather than a reset.
+func (ws *wsStream) Close() error {
+	var err error
+	ws.closeOnce.Do(func() {
+		close(ws.done)
+		ws.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(wsWriteWait))
+		err = ws.conn.Close()
+	})
+	return err
+}
+
+// WebSocketHandler upgrades requests to WebSocket and serves one MCP session
+// per connection, one JSON-RPC message per text frame. Cross-origin browser
+// requests are refused.
+func (s *Server) WebSocketHandler() http.Handler {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{wsSubprotocol},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := withPeer(context.Background(), r.RemoteAddr)
+
+		// Clients that can set headers authenticate on the upgrade request;
+		// browsers, which cannot, do so in initialize.
+		if s.auth != nil && r.Header.Get("Authorization") != "" {
+			identity, ok := s.authenticateRequest(w, r)
+			if !ok {
+				return
+			}
+			ctx = withIdentity(ctx, identity)
+			s.writeAudit(auditEvent{Event: "authenticated", Identity: identity.Name, Peer: r.RemoteAddr})
+		}
+
+		if !s.acquireConnection() {
+			w.Header().Set("Retry-After", "30")
+			writeHTTPError(w, http.StatusServiceUnavailable, codeRateLimited, "Too many connections, try again later")
+			return
+		}
+		defer s.releaseConnection()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already answered the request.
+			s.logger.Printf("WebSocket upgrade from %s failed: %v", r.RemoteAddr, err)
+			return
+		}
+
+		stream := newWSStream(conn)
+		defer stream.Close()
+		s.trackConn(stream)
+		defer s.untrackConn(stream)
+
+		s.logger.Printf("WebSocket client connected from %s", r.RemoteAddr)
+		if err := s.HandleRequest(ctx, stream, stream); err != nil {
+			s.logger.Printf("WebSocket connection error from %s: %v", r.RemoteAddr, err)
+		}
+	})
+}
+
+// ConnectWebSocket connects to a server at a ws:// or wss:// URL.
+func (c *Client) ConnectWebSocket(ctx context.Context, url string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.serverCmd != nil || c.conn != nil || c.transport != nil {
+		return fmt.Errorf("client already connected")
+	}
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 10 * time.Second,
+		Subprotocols:     []string{wsSubprotocol},
+	}
+
+	var header http.Header
+	if c.authToken != "" {
+		header = http.Header{"Authorization": {"Bearer " + c.authToken}}
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, url, header)
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("failed to connect to %s: %w (HTTP %d)", url, err, resp.StatusCode)
+		}
+		return fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+
+	stream := newWSStream(conn)
+	c.transport = stream
+	c.encoder = json.NewEncoder(stream)
+	c.decoder = json.NewDecoder(stream)
+	c.startReader()
+
+	c.logger.Printf("WebSocket connection established to: %s", url)
+	return nil
+}
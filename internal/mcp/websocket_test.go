@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+func TestWebSocketRoundTrip(t *testing.T) {
+	s := newProgressServer()
+	var serverLog lockedBuffer
+	s.logger = log.New(&serverLog, "", 0)
+
+	httpServer := httptest.NewServer(s.WebSocketHandler())
+	defer httpServer.Close()
+
+	client := NewClient(nil, log.New(io.Discard, "", 0))
+	var logs logRecorder
+	client.OnNotification("notifications/message", logs.handle)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.ConnectWebSocket(ctx, "ws"+strings.TrimPrefix(httpServer.URL, "http")); err != nil {
+		t.Fatal(err)
+	}
+
+	initialized, err := client.Initialize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if initialized.ServerInfo.Name != "test" {
+		t.Errorf("server name = %q, want test", initialized.ServerInfo.Name)
+	}
+	if err := client.SetLogLevel(ctx, LevelInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "over websocket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "over websocket" {
+		t.Errorf("echo result = %+v", result.Content)
+	}
+	if !logs.contains("Calling tool: echo") {
+		t.Errorf("no log notification for the call: %v", logs.messages)
+	}
+
+	var mu sync.Mutex
+	var progress []float64
+	result, err = client.CallToolWithProgress(ctx, "count", nil, func(update models.ProgressNotification) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress = append(progress, update.Progress)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "done" {
+		t.Errorf("count result = %+v", result.Content)
+	}
+	mu.Lock()
+	if len(progress) != 3 || progress[2] != 3 {
+		t.Errorf("progress notifications = %v, want 1, 2, 3", progress)
+	}
+	mu.Unlock()
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// The close frame ends the session as a disconnect, not an error.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.activeSessions()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("server session still open after the client closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if entries := serverLog.String(); !strings.Contains(entries, "Client disconnected") || strings.Contains(entries, "WebSocket connection error") {
+		t.Errorf("server did not see a clean close:\n%s", entries)
+	}
+}
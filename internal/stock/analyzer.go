@@ -1,6 +1,7 @@
 package stock
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
@@ -10,16 +11,16 @@ import (
 )
 
 type Analyzer struct {
-	apiClient *APIClient
+	provider MarketDataProvider
 }
 
-func NewAnalyzer(apiClient *APIClient) *Analyzer {
+func NewAnalyzer(provider MarketDataProvider) *Analyzer {
 	return &Analyzer{
-		apiClient: apiClient,
+		provider: provider,
 	}
 }
 
-func (a *Analyzer) AnalyzePortfolio(symbols []string, timeframe string) (*models.PortfolioAnalysis, error) {
+func (a *Analyzer) AnalyzePortfolio(ctx context.Context, symbols []string, timeframe string) (*models.PortfolioAnalysis, error) {
 	portfolio := models.Portfolio{
 		Name:    "Analysis Portfolio",
 		Symbols: symbols,
@@ -29,7 +30,7 @@ func (a *Analyzer) AnalyzePortfolio(symbols []string, timeframe string) (*models
 	analyses := make([]models.StockAnalysis, 0, len(symbols))
 	
 	for _, symbol := range symbols {
-		analysis, err := a.AnalyzeStock(symbol, timeframe)
+		analysis, err := a.AnalyzeStock(ctx, symbol, timeframe)
 		if err != nil {
 			return nil, fmt.Errorf("failed to analyze %s: %w", symbol, err)
 		}
@@ -52,18 +53,18 @@ func (a *Analyzer) AnalyzePortfolio(symbols []string, timeframe string) (*models
 	}, nil
 }
 
-func (a *Analyzer) AnalyzeStock(symbol, timeframe string) (*models.StockAnalysis, error) {
-	stock, err := a.apiClient.GetQuote(symbol)
+func (a *Analyzer) AnalyzeStock(ctx context.Context, symbol, timeframe string) (*models.StockAnalysis, error) {
+	stock, err := a.provider.GetQuote(ctx, symbol)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get quote: %w", err)
 	}
 
-	timeSeries, err := a.apiClient.GetTimeSeries(symbol, timeframe)
+	frame, timeSeries, err := timeframeSeries(ctx, a.provider, symbol, timeframe)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get time series: %w", err)
 	}
 
-	indicators, err := a.calculateTechnicalIndicators(symbol, timeSeries)
+	indicators, err := a.calculateTechnicalIndicators(symbol, frame.Interval, timeSeries)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate indicators: %w", err)
 	}
@@ -81,9 +82,20 @@ func (a *Analyzer) AnalyzeStock(symbol, timeframe string) (*models.StockAnalysis
 	}, nil
 }
 
-func (a *Analyzer) calculateTechnicalIndicators(symbol string, timeSeries map[string]models.Stock) (*models.TechnicalIndicators, error) {
+// GetTechnicalIndicators computes the indicators for symbol from the bars of
+// timeframe without fetching a quote or building a recommendation.
+func (a *Analyzer) GetTechnicalIndicators(ctx context.Context, symbol, timeframe string) (*models.TechnicalIndicators, error) {
+	frame, timeSeries, err := timeframeSeries(ctx, a.provider, symbol, timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time series: %w", err)
+	}
+
+	return a.calculateTechnicalIndicators(symbol, frame.Interval, timeSeries)
+}
+
+func (a *Analyzer) calculateTechnicalIndicators(symbol, interval string, timeSeries map[string]models.Stock) (*models.TechnicalIndicators, error) {
 	if len(timeSeries) < 50 {
-		return nil, fmt.Errorf("insufficient data for technical analysis (need at least 50 days)")
+		return nil, fmt.Errorf("insufficient data for technical analysis (need at least 50 bars)")
 	}
 
 	dates := make([]string, 0, len(timeSeries))
@@ -124,7 +136,7 @@ func (a *Analyzer) calculateTechnicalIndicators(symbol string, timeSeries map[st
 		indicators.RSI = a.calculateRSI(prices, 14)
 	}
 
-	indicators.Volatility = a.calculateVolatility(prices)
+	indicators.Volatility = a.calculateVolatility(prices, interval)
 
 	if indicators.SMA20 > 0 {
 		stdDev := a.calculateStandardDeviation(prices[len(prices)-20:])
@@ -194,7 +206,8 @@ func (a *Analyzer) calculateRSI(prices []float64, period int) float64 {
 	return rsi
 }
 
-func (a *Analyzer) calculateVolatility(prices []float64) float64 {
+// calculateVolatility is the annualized volatility of bars of interval.
+func (a *Analyzer) calculateVolatility(prices []float64, interval string) float64 {
 	if len(prices) < 2 {
 		return 0
 	}
@@ -204,7 +217,7 @@ func (a *Analyzer) calculateVolatility(prices []float64) float64 {
 		returns[i-1] = (prices[i] - prices[i-1]) / prices[i-1]
 	}
 
-	return a.calculateStandardDeviation(returns) * math.Sqrt(252)
+	return annualize(a.calculateStandardDeviation(returns), interval)
 }
 
 func (a *Analyzer) calculateStandardDeviation(values []float64) float64 {
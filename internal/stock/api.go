@@ -1,11 +1,14 @@
 package stock
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,10 +16,17 @@ import (
 	"proyecto-mcp-bolsa/pkg/models"
 )
 
+// APIClient is the Alpha Vantage MarketDataProvider. Requests are paced by
+// its RequestLimits, and identical requests in flight together are sent
+// once.
 type APIClient struct {
 	apiKey     string
 	baseURL    string
 	httpClient *http.Client
+
+	limits    RequestLimits
+	scheduler *requestScheduler
+	requests  requestGroup
 }
 
 func NewAPIClient(apiKey, baseURL string) *APIClient {
@@ -26,10 +36,28 @@ func NewAPIClient(apiKey, baseURL string) *APIClient {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		scheduler: newRequestScheduler(RequestLimits{}),
 	}
 }
 
-func (c *APIClient) GetQuote(symbol string) (*models.Stock, error) {
+// SetRequestLimits paces requests to the free tier's quota instead of
+// sending them right away.
+func (c *APIClient) SetRequestLimits(limits RequestLimits) {
+	c.limits = limits
+	c.scheduler = newRequestScheduler(limits)
+}
+
+// SetTransport routes the client's requests through transport, e.g. a
+// FixtureRecorder or FixtureReplayer.
+func (c *APIClient) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+}
+
+func (c *APIClient) Name() string {
+	return "alphaVantage"
+}
+
+func (c *APIClient) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
 	if c.apiKey == "" || c.apiKey == "demo" {
 		return nil, fmt.Errorf("API key required for stock quote: %s", symbol)
 	}
@@ -40,25 +68,10 @@ func (c *APIClient) GetQuote(symbol string) (*models.Stock, error) {
 		"apikey":   {c.apiKey},
 	}
 
-	resp, err := c.makeRequest(params)
+	body, err := c.fetch(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get quote for %s: %w", symbol, err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var demoCheck map[string]interface{}
-	if err := json.Unmarshal(body, &demoCheck); err == nil {
-		if info, exists := demoCheck["Information"]; exists {
-			if strings.Contains(fmt.Sprint(info), "demo") {
-				return nil, fmt.Errorf("demo API key not supported for production use")
-			}
-		}
-	}
 
 	var quote models.AlphaVantageQuote
 	if err := json.Unmarshal(body, &quote); err != nil {
@@ -72,62 +85,261 @@ func (c *APIClient) GetQuote(symbol string) (*models.Stock, error) {
 	return c.convertToStock(quote.GlobalQuote)
 }
 
-func (c *APIClient) GetTimeSeries(symbol string, interval string) (map[string]models.Stock, error) {
+// GetTimeSeries returns symbol's latest bars of interval, one of
+// BarIntervals, keyed by date.
+func (c *APIClient) GetTimeSeries(ctx context.Context, symbol string, interval string) (map[string]models.Stock, error) {
+	bars, err := c.GetBars(ctx, symbol, interval, compactBars)
+	if err != nil {
+		return nil, err
+	}
+	return barSeries(symbol, interval, bars), nil
+}
+
+// GetDailyBars returns the daily OHLCV bars for symbol, oldest first.
+func (c *APIClient) GetDailyBars(ctx context.Context, symbol string) ([]models.PriceBar, error) {
+	return c.GetBars(ctx, symbol, "daily", compactBars)
+}
+
+// alphaVantageSeries names the function of each interval of a day or longer
+// and the response key holding its bars.
+var alphaVantageSeries = map[string]struct{ function, key string }{
+	"daily":   {"TIME_SERIES_DAILY", "Time Series (Daily)"},
+	"weekly":  {"TIME_SERIES_WEEKLY", "Weekly Time Series"},
+	"monthly": {"TIME_SERIES_MONTHLY", "Monthly Time Series"},
+}
+
+// GetBars returns symbol's bars of interval, oldest first. Intraday bars
+// cover regular trading hours. Daily and intraday series are fetched in
+// full when more than compactBars bars are wanted; weekly and monthly ones
+// always are.
+func (c *APIClient) GetBars(ctx context.Context, symbol, interval string, count int) ([]models.PriceBar, error) {
+	if err := checkInterval(interval); err != nil {
+		return nil, err
+	}
 	if c.apiKey == "" || c.apiKey == "demo" {
 		return nil, fmt.Errorf("API key required for time series data: %s", symbol)
 	}
 
 	params := url.Values{
-		"function": {"TIME_SERIES_DAILY"},
-		"symbol":   {symbol},
-		"apikey":   {c.apiKey},
+		"symbol": {symbol},
+		"apikey": {c.apiKey},
+	}
+	// Intraday series are keyed by interval, e.g. "Time Series (5min)".
+	seriesKey := fmt.Sprintf("Time Series (%s)", interval)
+	if series, exists := alphaVantageSeries[interval]; exists {
+		params.Set("function", series.function)
+		seriesKey = series.key
+	} else {
+		params.Set("function", "TIME_SERIES_INTRADAY")
+		params.Set("interval", interval)
+		params.Set("extended_hours", "false")
+	}
+	full := count > compactBars && (interval == "daily" || isIntraday(interval))
+	if full {
+		params.Set("outputsize", "full")
 	}
 
-	resp, err := c.makeRequest(params)
+	body, err := c.fetch(ctx, params)
+	if full && errors.Is(err, errAPINotice) {
+		// Free keys may not get full series; make do with the compact one.
+		params.Del("outputsize")
+		body, err = c.fetch(ctx, params)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get time series for %s: %w", symbol, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse time series response: %w", err)
 	}
-
-	var demoCheck map[string]interface{}
-	if err := json.Unmarshal(body, &demoCheck); err == nil {
-		if info, exists := demoCheck["Information"]; exists {
-			if strings.Contains(fmt.Sprint(info), "demo") {
-				return nil, fmt.Errorf("demo API key not supported for production use")
-			}
+	var series map[string]models.AlphaVantageBar
+	if raw, exists := response[seriesKey]; exists {
+		if err := json.Unmarshal(raw, &series); err != nil {
+			return nil, fmt.Errorf("failed to parse time series response: %w", err)
 		}
 	}
 
-	var timeSeries models.AlphaVantageTimeSeries
-	if err := json.Unmarshal(body, &timeSeries); err != nil {
-		return nil, fmt.Errorf("failed to parse time series response: %w", err)
-	}
-
-	result := make(map[string]models.Stock)
-	for date, data := range timeSeries.TimeSeries {
-		stock, err := c.convertTimeSeriesData(symbol, date, data)
+	bars := make([]models.PriceBar, 0, len(series))
+	for timestamp, data := range series {
+		bar, err := c.convertBar(timestamp, data)
 		if err != nil {
 			continue
 		}
-		result[date] = *stock
+		bars = append(bars, *bar)
 	}
 
-	if len(result) == 0 {
+	if len(bars) == 0 {
 		return nil, fmt.Errorf("no valid time series data returned for symbol: %s", symbol)
 	}
 
-	return result, nil
+	sort.Slice(bars, func(i, j int) bool {
+		return bars[i].Date.Before(bars[j].Date)
+	})
+
+	return bars, nil
+}
+
+func (c *APIClient) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	if c.apiKey == "" || c.apiKey == "demo" {
+		return nil, fmt.Errorf("API key required for symbol search: %s", keywords)
+	}
+
+	params := url.Values{
+		"function": {"SYMBOL_SEARCH"},
+		"keywords": {keywords},
+		"apikey":   {c.apiKey},
+	}
+
+	body, err := c.fetch(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search symbols for %q: %w", keywords, err)
+	}
+
+	var search models.AlphaVantageSearch
+	if err := json.Unmarshal(body, &search); err != nil {
+		return nil, fmt.Errorf("failed to parse search response: %w", err)
+	}
+
+	matches := make([]models.SymbolMatch, 0, len(search.BestMatches))
+	for _, match := range search.BestMatches {
+		score, _ := strconv.ParseFloat(match.MatchScore, 64)
+		matches = append(matches, models.SymbolMatch{
+			Symbol:   match.Symbol,
+			Name:     match.Name,
+			Type:     match.Type,
+			Region:   match.Region,
+			Currency: match.Currency,
+			Score:    score,
+		})
+	}
+
+	return matches, nil
+}
+
+func (c *APIClient) GetCompanyOverview(ctx context.Context, symbol string) (*models.CompanyOverview, error) {
+	if c.apiKey == "" || c.apiKey == "demo" {
+		return nil, fmt.Errorf("API key required for company overview: %s", symbol)
+	}
+
+	params := url.Values{
+		"function": {"OVERVIEW"},
+		"symbol":   {symbol},
+		"apikey":   {c.apiKey},
+	}
+
+	body, err := c.fetch(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overview for %s: %w", symbol, err)
+	}
+
+	var overview models.AlphaVantageOverview
+	if err := json.Unmarshal(body, &overview); err != nil {
+		return nil, fmt.Errorf("failed to parse overview response: %w", err)
+	}
+
+	if overview.Symbol == "" {
+		return nil, fmt.Errorf("no data returned for symbol: %s", symbol)
+	}
+
+	// Missing figures come back as "None" or "-" and are left zero.
+	number := func(raw string) float64 {
+		value, _ := strconv.ParseFloat(raw, 64)
+		return value
+	}
+	marketCap, _ := strconv.ParseInt(overview.MarketCapitalization, 10, 64)
+
+	return &models.CompanyOverview{
+		Symbol:        overview.Symbol,
+		Name:          overview.Name,
+		Description:   overview.Description,
+		Exchange:      overview.Exchange,
+		Currency:      overview.Currency,
+		Country:       overview.Country,
+		Sector:        overview.Sector,
+		Industry:      overview.Industry,
+		MarketCap:     marketCap,
+		PERatio:       number(overview.PERatio),
+		EPS:           number(overview.EPS),
+		DividendYield: number(overview.DividendYield),
+		Beta:          number(overview.Beta),
+		Week52High:    number(overview.Week52High),
+		Week52Low:     number(overview.Week52Low),
+	}, nil
+}
+
+// errAPINotice is wrapped by errors for notices that are not about the
+// API key or throttling, e.g. a premium-only parameter.
+var errAPINotice = errors.New("API notice")
+
+// fetch runs a query and returns the response body. It waits for the
+// scheduler, retries throttled requests with backoff and shares the result
+// with identical calls made meanwhile.
+func (c *APIClient) fetch(ctx context.Context, params url.Values) ([]byte, error) {
+	return c.requests.do(ctx, params.Encode(), func(ctx context.Context) ([]byte, error) {
+		for attempt := 0; ; attempt++ {
+			if err := c.scheduler.wait(ctx); err != nil {
+				return nil, err
+			}
+			body, retry, err := c.send(ctx, params)
+			if !retry || attempt >= c.limits.MaxRetries {
+				return body, err
+			}
+			c.scheduler.pause(c.limits.RetryBackoff << attempt)
+		}
+	})
 }
 
-func (c *APIClient) makeRequest(params url.Values) (*http.Response, error) {
+// send makes one request. Alpha Vantage reports throttling and other
+// problems with HTTP 200 and a "Note" or "Information" message instead of
+// data; those become errors, and retry tells whether waiting may help.
+func (c *APIClient) send(ctx context.Context, params url.Values) (body []byte, retry bool, err error) {
+	resp, err := c.makeRequest(ctx, params)
+	if errors.Is(err, ErrRateLimited) {
+		return nil, true, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var notice struct {
+		Note        string `json:"Note"`
+		Information string `json:"Information"`
+	}
+	if json.Unmarshal(body, &notice) != nil || (notice.Note == "" && notice.Information == "") {
+		return body, false, nil
+	}
+
+	message := notice.Note
+	if message == "" {
+		message = notice.Information
+	}
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "demo"):
+		return nil, false, fmt.Errorf("demo API key not supported for production use")
+	case strings.Contains(lower, "frequency") || strings.Contains(lower, "per minute") || strings.Contains(lower, "per second"):
+		// Checked first: the per-minute note quotes the daily quota too.
+		return nil, true, fmt.Errorf("%w: %s", ErrRateLimited, message)
+	case strings.Contains(lower, "per day"):
+		// The daily quota does not come back by waiting a minute.
+		return nil, false, fmt.Errorf("%w: %s", ErrRateLimited, message)
+	case notice.Note != "" || strings.Contains(lower, "rate limit"):
+		return nil, true, fmt.Errorf("%w: %s", ErrRateLimited, message)
+	}
+	return nil, false, fmt.Errorf("%w: %s", errAPINotice, message)
+}
+
+func (c *APIClient) makeRequest(ctx context.Context, params url.Values) (*http.Response, error) {
 	fullURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
 	
-	req, err := http.NewRequest("GET", fullURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -139,6 +351,10 @@ func (c *APIClient) makeRequest(params url.Values) (*http.Response, error) {
 		return nil, err
 	}
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		resp.Body.Close()
+		return nil, ErrRateLimited
+	}
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
 		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
@@ -196,21 +412,19 @@ func (c *APIClient) convertToStock(quote struct {
 	}, nil
 }
 
-func (c *APIClient) convertTimeSeriesData(symbol, date string, data struct {
-	Open   string `json:"1. open"`
-	High   string `json:"2. high"`
-	Low    string `json:"3. low"`
-	Close  string `json:"4. close"`
-	Volume string `json:"5. volume"`
-}) (*models.Stock, error) {
-	price, err := strconv.ParseFloat(data.Close, 64)
+func (c *APIClient) convertBar(date string, data models.AlphaVantageBar) (*models.PriceBar, error) {
+	parsedDate, err := parseBarTime(date)
 	if err != nil {
-		return nil, fmt.Errorf("invalid close price: %s", data.Close)
+		return nil, err
 	}
 
-	open, err := strconv.ParseFloat(data.Open, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid open price: %s", data.Open)
+	values := make([]float64, 4)
+	for i, raw := range []string{data.Open, data.High, data.Low, data.Close} {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price: %s", raw)
+		}
+		values[i] = value
 	}
 
 	volume, err := strconv.ParseInt(data.Volume, 10, 64)
@@ -218,22 +432,12 @@ func (c *APIClient) convertTimeSeriesData(symbol, date string, data struct {
 		return nil, fmt.Errorf("invalid volume: %s", data.Volume)
 	}
 
-	lastUpdated, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		return nil, fmt.Errorf("invalid date: %s", date)
-	}
-
-	change := price - open
-	changePerc := (change / open) * 100
-
-	return &models.Stock{
-		Symbol:      symbol,
-		Name:        symbol,
-		Price:       price,
-		Change:      change,
-		ChangePerc:  changePerc,
-		Volume:      volume,
-		LastUpdated: lastUpdated,
+	return &models.PriceBar{
+		Date:   parsedDate,
+		Open:   values[0],
+		High:   values[1],
+		Low:    values[2],
+		Close:  values[3],
+		Volume: volume,
 	}, nil
 }
-
@@ -0,0 +1,148 @@
+package stock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const quoteBody = `{"Global Quote": {"01. symbol": "AAPL", "02. open": "189.00", "03. high": "191.00", "04. low": "188.00", "05. price": "190.50", "06. volume": "1200", "07. latest trading day": "2024-05-10", "08. previous close": "189.50", "09. change": "1.00", "10. change percent": "0.53%"}}`
+
+const minuteThrottleNote = `{"Note": "Thank you for using Alpha Vantage! Our standard API call frequency is 5 calls per minute and 25 calls per day."}`
+
+// scriptedAPI answers the n-th request (from 0) with respond and counts the
+// requests it got.
+func scriptedAPI(t *testing.T, respond func(n int, w http.ResponseWriter, r *http.Request)) (*APIClient, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1) - 1
+		w.Header().Set("Content-Type", "application/json")
+		respond(int(n), w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewAPIClient(testAPIKey, server.URL+"/query")
+	client.SetRequestLimits(RequestLimits{MaxRetries: 2, RetryBackoff: time.Millisecond})
+	return client, &requests
+}
+
+func TestAPIClientSharesIdenticalCalls(t *testing.T) {
+	release := make(chan struct{})
+	client, requests := scriptedAPI(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, quoteBody)
+	})
+
+	const callers = 8
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if quote, err := client.GetQuote(context.Background(), "AAPL"); err != nil || quote.Price != 190.5 {
+				t.Errorf("GetQuote = %+v, %v", quote, err)
+			}
+		}()
+	}
+	key := url.Values{"function": {"GLOBAL_QUOTE"}, "symbol": {"AAPL"}, "apikey": {testAPIKey}}.Encode()
+	waitForWaiters(t, &client.requests, key, callers)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("%d upstream requests for %d identical calls, want 1", got, callers)
+	}
+}
+
+func TestAPIClientRetriesThrottleNote(t *testing.T) {
+	client, requests := scriptedAPI(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if n == 0 {
+			fmt.Fprint(w, minuteThrottleNote)
+			return
+		}
+		fmt.Fprint(w, quoteBody)
+	})
+
+	quote, err := client.GetQuote(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Symbol != "AAPL" {
+		t.Errorf("quote = %+v", quote)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("%d upstream requests, want the throttled one and its retry", got)
+	}
+}
+
+func TestAPIClientClassifiesNotices(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		rateLimited bool
+		requests    int32
+	}{
+		{"minute throttle note", http.StatusOK, minuteThrottleNote, true, 3},
+		{"HTTP 429", http.StatusTooManyRequests, "", true, 3},
+		{"daily quota", http.StatusOK, `{"Information": "We have detected your API key as secret-test-key and our standard API rate limit is 25 requests per day."}`, true, 1},
+		{"demo key", http.StatusOK, `{"Information": "The demo API key is for demo purposes only."}`, false, 1},
+		{"premium notice", http.StatusOK, `{"Information": "Thank you for using Alpha Vantage! This is a premium endpoint."}`, false, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, requests := scriptedAPI(t, func(n int, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				fmt.Fprint(w, test.body)
+			})
+
+			_, err := client.GetQuote(context.Background(), "AAPL")
+			if err == nil {
+				t.Fatal("GetQuote succeeded on a notice")
+			}
+			if errors.Is(err, ErrRateLimited) != test.rateLimited {
+				t.Errorf("error %v: rate limited %v, want %v", err, !test.rateLimited, test.rateLimited)
+			}
+			if got := atomic.LoadInt32(requests); got != test.requests {
+				t.Errorf("%d upstream requests, want %d", got, test.requests)
+			}
+		})
+	}
+}
+
+func TestGetBarsFallsBackToCompactSeries(t *testing.T) {
+	var mu sync.Mutex
+	var outputSizes []string
+	client, _ := scriptedAPI(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		outputSize := r.URL.Query().Get("outputsize")
+		mu.Lock()
+		outputSizes = append(outputSizes, outputSize)
+		mu.Unlock()
+		if outputSize == "full" {
+			fmt.Fprint(w, `{"Information": "Thank you for using Alpha Vantage! The outputsize=full parameter value is a premium feature."}`)
+			return
+		}
+		fmt.Fprint(w, `{"Meta Data": {}, "Time Series (Daily)": {
+			"2024-05-10": {"1. open": "189", "2. high": "191", "3. low": "188", "4. close": "190.5", "5. volume": "1200"},
+			"2024-05-09": {"1. open": "188", "2. high": "190", "3. low": "187", "4. close": "189.5", "5. volume": "1100"}}}`)
+	})
+
+	bars, err := client.GetBars(context.Background(), "AAPL", "daily", 2*compactBars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 2 || bars[0].Close != 189.5 {
+		t.Errorf("bars = %+v, want the compact series oldest first", bars)
+	}
+	if len(outputSizes) != 2 || outputSizes[0] != "full" || outputSizes[1] != "" {
+		t.Errorf("outputsize of the requests = %q, want full then compact", outputSizes)
+	}
+}
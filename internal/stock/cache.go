@@ -0,0 +1,282 @@
+package stock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// refreshTimeout bounds a background revalidation.
+const refreshTimeout = 30 * time.Second
+
+// CacheTTLs says how long each kind of data is fresh. Daily bars are fresh
+// until the next market close. MaxStale is how long after expiry an entry
+// is still served while a fresh copy is fetched in the background.
+type CacheTTLs struct {
+	Quote    time.Duration
+	Intraday time.Duration
+	Search   time.Duration
+	Overview time.Duration
+	MaxStale time.Duration
+}
+
+// CacheStats counts how requests were answered since the cache was opened.
+type CacheStats struct {
+	Hits          int
+	StaleHits     int
+	Misses        int
+	RefreshErrors int
+	StoreErrors   int
+}
+
+// cacheEntry is what the store holds for a key.
+type cacheEntry struct {
+	FetchedAt time.Time       `json:"fetchedAt"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Cache keeps provider responses in a CacheStore, keyed by provider,
+// function, symbol and, for bars, interval and count. Wrap puts it in front
+// of a provider.
+type Cache struct {
+	store CacheStore
+	ttls  CacheTTLs
+
+	mu         sync.Mutex
+	stats      CacheStats
+	refreshing map[string]bool
+	closed     bool
+	refreshes  sync.WaitGroup
+}
+
+// NewCache serves from store and drops the entries that are too old to be
+// served even stale.
+func NewCache(store CacheStore, ttls CacheTTLs) (*Cache, error) {
+	c := &Cache{
+		store:      store,
+		ttls:       ttls,
+		refreshing: make(map[string]bool),
+	}
+	if err := c.prune(); err != nil {
+		return nil, fmt.Errorf("failed to prune cache: %w", err)
+	}
+	return c, nil
+}
+
+// Wrap returns provider answering from the cache where it can.
+func (c *Cache) Wrap(provider MarketDataProvider) MarketDataProvider {
+	return &cachedProvider{next: provider, cache: c}
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Close waits for background refreshes and closes the store. Its signature
+// fits mcp.Server.RegisterOnShutdown.
+func (c *Cache) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.refreshes.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	return c.store.Close()
+}
+
+func (c *Cache) prune() error {
+	cutoff := time.Now().Add(-c.ttls.MaxStale)
+
+	var expired []string
+	err := c.store.ForEach(func(key string, value []byte) error {
+		var entry cacheEntry
+		if err := json.Unmarshal(value, &entry); err != nil || entry.ExpiresAt.Before(cutoff) {
+			expired = append(expired, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return c.store.Delete(expired...)
+}
+
+func (c *Cache) count(update func(*CacheStats)) {
+	c.mu.Lock()
+	update(&c.stats)
+	c.mu.Unlock()
+}
+
+func (c *Cache) save(key string, value interface{}, fetchedAt, expiresAt time.Time) {
+	data, err := json.Marshal(value)
+	if err == nil {
+		data, err = json.Marshal(cacheEntry{FetchedAt: fetchedAt, ExpiresAt: expiresAt, Data: data})
+	}
+	if err == nil {
+		err = c.store.Put(key, data)
+	}
+	if err != nil {
+		c.count(func(s *CacheStats) { s.StoreErrors++ })
+	}
+}
+
+// revalidate runs refresh in the background unless one is already running
+// for key.
+func (c *Cache) revalidate(key string, refresh func(ctx context.Context) error) {
+	c.mu.Lock()
+	if c.closed || c.refreshing[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.refreshes.Add(1)
+	c.mu.Unlock()
+
+	go func() {
+		defer c.refreshes.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		err := refresh(ctx)
+		cancel()
+
+		c.mu.Lock()
+		delete(c.refreshing, key)
+		if err != nil {
+			c.stats.RefreshErrors++
+		}
+		c.mu.Unlock()
+	}()
+}
+
+// cached answers key from the cache: fresh entries as they are, stale ones
+// while fetch refreshes them in the background, anything else by calling
+// fetch. expiry gives the expiry of data fetched at a time.
+func cached[T any](ctx context.Context, c *Cache, key string, expiry func(time.Time) time.Time, fetch func(context.Context) (T, error)) (T, error) {
+	fetchAndSave := func(ctx context.Context) (T, error) {
+		value, err := fetch(ctx)
+		if err == nil {
+			now := time.Now()
+			c.save(key, value, now, expiry(now))
+		}
+		return value, err
+	}
+
+	if data, err := c.store.Get(key); err == nil && data != nil {
+		var entry cacheEntry
+		var value T
+		if json.Unmarshal(data, &entry) == nil && json.Unmarshal(entry.Data, &value) == nil {
+			now := time.Now()
+			switch {
+			case now.Before(entry.ExpiresAt):
+				c.count(func(s *CacheStats) { s.Hits++ })
+				return value, nil
+			case now.Before(entry.ExpiresAt.Add(c.ttls.MaxStale)):
+				c.count(func(s *CacheStats) { s.StaleHits++ })
+				c.revalidate(key, func(ctx context.Context) error {
+					_, err := fetchAndSave(ctx)
+					return err
+				})
+				return value, nil
+			}
+		}
+	}
+
+	c.count(func(s *CacheStats) { s.Misses++ })
+	return fetchAndSave(ctx)
+}
+
+func cacheKey(provider, function, symbol, interval string) string {
+	return strings.Join([]string{provider, function, symbol, interval}, "/")
+}
+
+// nextMarketClose is the first 16:00 New York close on a weekday after t.
+func nextMarketClose(t time.Time) time.Time {
+	local := t.In(newYork)
+	closeTime := time.Date(local.Year(), local.Month(), local.Day(), 16, 0, 0, 0, newYork)
+	for !closeTime.After(t) || closeTime.Weekday() == time.Saturday || closeTime.Weekday() == time.Sunday {
+		closeTime = closeTime.AddDate(0, 0, 1)
+	}
+	return closeTime
+}
+
+var newYork = loadNewYork()
+
+func loadNewYork() *time.Location {
+	if location, err := time.LoadLocation("America/New_York"); err == nil {
+		return location
+	}
+	// Without a time zone database, ignore daylight saving time.
+	return time.FixedZone("EST", -5*60*60)
+}
+
+// cachedProvider is a MarketDataProvider served through a Cache.
+type cachedProvider struct {
+	next  MarketDataProvider
+	cache *Cache
+}
+
+func (p *cachedProvider) Name() string {
+	return p.next.Name()
+}
+
+func (p *cachedProvider) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+	key := cacheKey(p.next.Name(), "quote", symbol, "")
+	return cached(ctx, p.cache, key, p.after(p.cache.ttls.Quote), func(ctx context.Context) (*models.Stock, error) {
+		return p.next.GetQuote(ctx, symbol)
+	})
+}
+
+func (p *cachedProvider) GetDailyBars(ctx context.Context, symbol string) ([]models.PriceBar, error) {
+	return p.GetBars(ctx, symbol, "daily", compactBars)
+}
+
+// GetBars keeps series of each count apart, since providers may return just
+// the bars asked for. Bars of a day or longer are fresh until the next
+// market close.
+func (p *cachedProvider) GetBars(ctx context.Context, symbol, interval string, count int) ([]models.PriceBar, error) {
+	expiry := nextMarketClose
+	if isIntraday(interval) {
+		expiry = p.after(p.cache.ttls.Intraday)
+	}
+
+	key := cacheKey(p.next.Name(), "bars", symbol, fmt.Sprintf("%s/%d", interval, count))
+	return cached(ctx, p.cache, key, expiry, func(ctx context.Context) ([]models.PriceBar, error) {
+		return p.next.GetBars(ctx, symbol, interval, count)
+	})
+}
+
+func (p *cachedProvider) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	key := cacheKey(p.next.Name(), "search", strings.ToLower(keywords), "")
+	return cached(ctx, p.cache, key, p.after(p.cache.ttls.Search), func(ctx context.Context) ([]models.SymbolMatch, error) {
+		return p.next.SearchSymbols(ctx, keywords)
+	})
+}
+
+func (p *cachedProvider) GetCompanyOverview(ctx context.Context, symbol string) (*models.CompanyOverview, error) {
+	key := cacheKey(p.next.Name(), "overview", symbol, "")
+	return cached(ctx, p.cache, key, p.after(p.cache.ttls.Overview), func(ctx context.Context) (*models.CompanyOverview, error) {
+		return p.next.GetCompanyOverview(ctx, symbol)
+	})
+}
+
+func (p *cachedProvider) after(ttl time.Duration) func(time.Time) time.Time {
+	return func(fetchedAt time.Time) time.Time {
+		return fetchedAt.Add(ttl)
+	}
+}
@@ -0,0 +1,148 @@
+package stock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// CacheStore holds the serialized entries of a Cache.
+type CacheStore interface {
+	// Get returns nil when key is not stored.
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+	Delete(keys ...string) error
+	ForEach(fn func(key string, value []byte) error) error
+	Close() error
+}
+
+// MemoryStore is a CacheStore that lasts as long as the process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string][]byte)}
+}
+
+func (m *MemoryStore) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.entries[key], nil
+}
+
+func (m *MemoryStore) Put(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = value
+	return nil
+}
+
+func (m *MemoryStore) Delete(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+func (m *MemoryStore) ForEach(fn func(key string, value []byte) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, value := range m.entries {
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+var cacheBucket = []byte("market-data")
+
+// BoltStore is a CacheStore in a bbolt database file, so cached data
+// survives restarts. Only one process can have the file open.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens or creates the database at path.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("cache %s is in use by another process", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(cacheBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize cache %s: %w", path, err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (b *BoltStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		// Values are only valid inside the transaction.
+		if stored := tx.Bucket(cacheBucket).Get([]byte(key)); stored != nil {
+			value = append([]byte(nil), stored...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (b *BoltStore) Put(key string, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(cacheBucket).Put([]byte(key), value)
+	})
+}
+
+func (b *BoltStore) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(cacheBucket)
+		for _, key := range keys {
+			if err := bucket.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltStore) ForEach(fn func(key string, value []byte) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(cacheBucket).ForEach(func(key, value []byte) error {
+			return fn(string(key), value)
+		})
+	})
+}
+
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,226 @@
+package stock
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// countingProvider answers with the current price and counts the calls that
+// reach it.
+type countingProvider struct {
+	mu    sync.Mutex
+	price float64
+	calls int
+}
+
+func (p *countingProvider) setPrice(price float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.price = price
+}
+
+func (p *countingProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (p *countingProvider) call() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	return p.price
+}
+
+func (p *countingProvider) Name() string {
+	return "counting"
+}
+
+func (p *countingProvider) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+	return &models.Stock{Symbol: symbol, Price: p.call()}, nil
+}
+
+func (p *countingProvider) GetDailyBars(ctx context.Context, symbol string) ([]models.PriceBar, error) {
+	return p.GetBars(ctx, symbol, "daily", compactBars)
+}
+
+// GetBars returns exactly count bars, like providers that page by count.
+func (p *countingProvider) GetBars(ctx context.Context, symbol, interval string, count int) ([]models.PriceBar, error) {
+	price := p.call()
+	bars := make([]models.PriceBar, count)
+	for i := range bars {
+		bars[i] = models.PriceBar{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i), Close: price}
+	}
+	return bars, nil
+}
+
+func (p *countingProvider) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	p.call()
+	return []models.SymbolMatch{{Symbol: keywords}}, nil
+}
+
+func (p *countingProvider) GetCompanyOverview(ctx context.Context, symbol string) (*models.CompanyOverview, error) {
+	p.call()
+	return &models.CompanyOverview{Symbol: symbol}, nil
+}
+
+var testTTLs = CacheTTLs{
+	Quote:    time.Hour,
+	Intraday: time.Hour,
+	Search:   time.Hour,
+	Overview: time.Hour,
+	MaxStale: time.Hour,
+}
+
+func newTestCache(t *testing.T, store CacheStore) *Cache {
+	t.Helper()
+	cache, err := NewCache(store, testTTLs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func quotePrice(t *testing.T, provider MarketDataProvider) float64 {
+	t.Helper()
+	quote, err := provider.GetQuote(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return quote.Price
+}
+
+func TestCacheServesFreshEntries(t *testing.T) {
+	upstream := &countingProvider{price: 100}
+	cache := newTestCache(t, NewMemoryStore())
+	provider := cache.Wrap(upstream)
+
+	quotePrice(t, provider)
+	upstream.setPrice(101)
+	if price := quotePrice(t, provider); price != 100 {
+		t.Errorf("fresh entry served price %v, want 100", price)
+	}
+	if calls := upstream.callCount(); calls != 1 {
+		t.Errorf("upstream called %d times, want 1", calls)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want 1 hit and 1 miss", stats)
+	}
+}
+
+func TestCacheRefetchesEntriesPastMaxStale(t *testing.T) {
+	upstream := &countingProvider{price: 101}
+	cache := newTestCache(t, NewMemoryStore())
+	provider := cache.Wrap(upstream)
+
+	expired := time.Now().Add(-testTTLs.MaxStale - time.Minute)
+	cache.save(cacheKey("counting", "quote", "AAPL", ""), &models.Stock{Symbol: "AAPL", Price: 100}, expired.Add(-time.Hour), expired)
+
+	if price := quotePrice(t, provider); price != 101 {
+		t.Errorf("expired entry: got price %v, want the upstream's 101", price)
+	}
+	if stats := cache.Stats(); stats.Misses != 1 || stats.StaleHits != 0 {
+		t.Errorf("stats = %+v, want 1 miss", stats)
+	}
+}
+
+func TestCacheServesStaleWhileRevalidating(t *testing.T) {
+	upstream := &countingProvider{price: 101}
+	cache := newTestCache(t, NewMemoryStore())
+	provider := cache.Wrap(upstream)
+
+	expired := time.Now().Add(-time.Minute)
+	cache.save(cacheKey("counting", "quote", "AAPL", ""), &models.Stock{Symbol: "AAPL", Price: 100}, expired.Add(-time.Hour), expired)
+
+	if price := quotePrice(t, provider); price != 100 {
+		t.Errorf("stale entry: got price %v, want the cached 100", price)
+	}
+	cache.refreshes.Wait()
+	if calls := upstream.callCount(); calls != 1 {
+		t.Errorf("upstream called %d times, want 1 background refresh", calls)
+	}
+
+	if price := quotePrice(t, provider); price != 101 {
+		t.Errorf("after revalidation: got price %v, want 101", price)
+	}
+	if stats := cache.Stats(); stats.StaleHits != 1 || stats.Hits != 1 || stats.Misses != 0 {
+		t.Errorf("stats = %+v, want 1 stale hit and 1 hit", stats)
+	}
+}
+
+func TestCacheKeepsBarCountsApart(t *testing.T) {
+	upstream := &countingProvider{price: 100}
+	provider := newTestCache(t, NewMemoryStore()).Wrap(upstream)
+	ctx := context.Background()
+
+	for _, count := range []int{63, 126, 63} {
+		bars, err := provider.GetBars(ctx, "AAPL", "daily", count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bars) != count {
+			t.Errorf("asked for %d bars, got %d", count, len(bars))
+		}
+	}
+	if calls := upstream.callCount(); calls != 2 {
+		t.Errorf("upstream called %d times, want one per count", calls)
+	}
+}
+
+func TestCachePrunesEntriesPastMaxStale(t *testing.T) {
+	store := NewMemoryStore()
+	seed := newTestCache(t, store)
+	now := time.Now()
+	seed.save("fresh", 1, now, now.Add(time.Hour))
+	seed.save("stale", 2, now.Add(-2*time.Hour), now.Add(-time.Minute))
+	seed.save("expired", 3, now.Add(-3*time.Hour), now.Add(-testTTLs.MaxStale-time.Minute))
+	store.Put("corrupt", []byte("not json"))
+
+	newTestCache(t, store)
+
+	var kept []string
+	store.ForEach(func(key string, value []byte) error {
+		kept = append(kept, key)
+		return nil
+	})
+	sort.Strings(kept)
+	if len(kept) != 2 || kept[0] != "fresh" || kept[1] != "stale" {
+		t.Errorf("kept %v after pruning, want [fresh stale]", kept)
+	}
+}
+
+func TestBoltCachePersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "market.db")
+	ctx := context.Background()
+
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := newTestCache(t, store)
+	first := &countingProvider{price: 100}
+	quotePrice(t, cache.Wrap(first))
+	if err := cache.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache = newTestCache(t, store)
+	defer cache.Close(ctx)
+	second := &countingProvider{price: 101}
+	if price := quotePrice(t, cache.Wrap(second)); price != 100 {
+		t.Errorf("after reopening: got price %v, want the persisted 100", price)
+	}
+	if calls := second.callCount(); calls != 0 {
+		t.Errorf("upstream called %d times after reopening, want 0", calls)
+	}
+}
@@ -0,0 +1,275 @@
+package stock
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// CSVProvider reads market data from files in a directory: SYMBOL.csv holds
+// daily bars, SYMBOL_5min.csv (or another of IntradayIntervals) intraday
+// bars and the optional SYMBOL.json a models.CompanyOverview. Weekly and
+// monthly bars are merged from the daily ones. Bar files start
+// with a header naming the date (or datetime/timestamp), open, high, low,
+// close and optionally volume columns, in any order.
+type CSVProvider struct {
+	dir string
+}
+
+func NewCSVProvider(dir string) *CSVProvider {
+	return &CSVProvider{dir: dir}
+}
+
+func (p *CSVProvider) Name() string {
+	return "csv"
+}
+
+// GetQuote reports the latest daily close, changed against the close before.
+func (p *CSVProvider) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+	bars, err := p.GetDailyBars(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	last := bars[len(bars)-1]
+	previous := last.Open
+	if len(bars) > 1 {
+		previous = bars[len(bars)-2].Close
+	}
+	change := last.Close - previous
+	changePerc := 0.0
+	if previous != 0 {
+		changePerc = (change / previous) * 100
+	}
+
+	return &models.Stock{
+		Symbol:      symbol,
+		Name:        symbol,
+		Price:       last.Close,
+		Change:      change,
+		ChangePerc:  changePerc,
+		Volume:      last.Volume,
+		LastUpdated: last.Date,
+	}, nil
+}
+
+func (p *CSVProvider) GetDailyBars(ctx context.Context, symbol string) ([]models.PriceBar, error) {
+	return p.readBars(symbol, symbol+".csv")
+}
+
+// GetBars returns every bar in the file, however many count asks for.
+func (p *CSVProvider) GetBars(ctx context.Context, symbol, interval string, count int) ([]models.PriceBar, error) {
+	if err := checkInterval(interval); err != nil {
+		return nil, err
+	}
+	if isIntraday(interval) {
+		return p.readBars(symbol, symbol+"_"+interval+".csv")
+	}
+
+	bars, err := p.GetDailyBars(ctx, symbol)
+	if err != nil || interval == "daily" {
+		return bars, err
+	}
+	return resampleBars(bars, interval), nil
+}
+
+// SearchSymbols matches keywords against the symbols that have daily files.
+func (p *CSVProvider) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", p.dir, err)
+	}
+
+	query := strings.ToUpper(strings.TrimSpace(keywords))
+	var matches []models.SymbolMatch
+	for _, entry := range entries {
+		symbol, isCSV := strings.CutSuffix(entry.Name(), ".csv")
+		if entry.IsDir() || !isCSV || isIntradayFile(symbol) {
+			continue
+		}
+
+		upper := strings.ToUpper(symbol)
+		var score float64
+		switch {
+		case upper == query:
+			score = 1
+		case strings.HasPrefix(upper, query):
+			score = 0.8
+		case strings.Contains(upper, query):
+			score = 0.5
+		default:
+			continue
+		}
+		matches = append(matches, models.SymbolMatch{Symbol: symbol, Name: symbol, Score: score})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Symbol < matches[j].Symbol
+	})
+	return matches, nil
+}
+
+func (p *CSVProvider) GetCompanyOverview(ctx context.Context, symbol string) (*models.CompanyOverview, error) {
+	path, err := p.path(symbol, symbol+".json")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overview for %s: %w", symbol, err)
+	}
+
+	var overview models.CompanyOverview
+	if err := json.Unmarshal(data, &overview); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if overview.Symbol == "" {
+		overview.Symbol = symbol
+	}
+	return &overview, nil
+}
+
+// path resolves a data file of symbol, refusing symbols that would leave
+// the directory.
+func (p *CSVProvider) path(symbol, file string) (string, error) {
+	if symbol == "" || strings.ContainsAny(symbol, `/\`) || strings.Contains(symbol, "..") {
+		return "", fmt.Errorf("invalid symbol: %q", symbol)
+	}
+	return filepath.Join(p.dir, file), nil
+}
+
+func (p *CSVProvider) readBars(symbol, file string) ([]models.PriceBar, error) {
+	path, err := p.path(symbol, file)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("no data for symbol %s: %w", symbol, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header of %s: %w", path, err)
+	}
+	columns, err := barColumns(header)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var bars []models.PriceBar
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		bar, err := convertCSVBar(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		bars = append(bars, *bar)
+	}
+
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no bars in %s", path)
+	}
+
+	sort.Slice(bars, func(i, j int) bool {
+		return bars[i].Date.Before(bars[j].Date)
+	})
+
+	return bars, nil
+}
+
+// barColumns finds the index of each bar field in header; volume is -1 when
+// the file has none.
+func barColumns(header []string) (map[string]int, error) {
+	columns := map[string]int{"volume": -1}
+	for i, name := range header {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "date", "datetime", "timestamp", "time":
+			columns["date"] = i
+		case "open", "high", "low", "close", "volume":
+			columns[name] = i
+		}
+	}
+
+	for _, required := range []string{"date", "open", "high", "low", "close"} {
+		if _, exists := columns[required]; !exists {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+	return columns, nil
+}
+
+func convertCSVBar(record []string, columns map[string]int) (*models.PriceBar, error) {
+	field := func(name string) string {
+		if i := columns[name]; i >= 0 && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	date, err := parseBarTime(field("date"))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, 4)
+	for i, name := range []string{"open", "high", "low", "close"} {
+		value, err := strconv.ParseFloat(field(name), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", name, field(name))
+		}
+		values[i] = value
+	}
+
+	var volume int64
+	if raw := field("volume"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid volume: %q", raw)
+		}
+		volume = int64(parsed)
+	}
+
+	return &models.PriceBar{
+		Date:   date,
+		Open:   values[0],
+		High:   values[1],
+		Low:    values[2],
+		Close:  values[3],
+		Volume: volume,
+	}, nil
+}
+
+func isIntradayFile(name string) bool {
+	for _, interval := range IntradayIntervals {
+		if strings.HasSuffix(name, "_"+interval) {
+			return true
+		}
+	}
+	return false
+}
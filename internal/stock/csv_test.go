@@ -0,0 +1,136 @@
+package stock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+func writeCSVFiles(t *testing.T, files map[string]string) *CSVProvider {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewCSVProvider(dir)
+}
+
+func TestCSVProviderReadsBars(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name    string
+		file    string
+		want    []models.PriceBar
+		errText string
+	}{
+		{
+			name: "newest first",
+			file: "Date,Open,High,Low,Close,Volume\n2024-05-10,189,191,188,190.5,1200\n2024-05-09,188,190,187,189.5,1100\n",
+			want: []models.PriceBar{
+				{Date: day(9), Open: 188, High: 190, Low: 187, Close: 189.5, Volume: 1100},
+				{Date: day(10), Open: 189, High: 191, Low: 188, Close: 190.5, Volume: 1200},
+			},
+		},
+		{
+			name: "columns in another order without volume",
+			file: " close , timestamp, low, high, open, adj close\n190.5, 2024-05-10, 188, 191, 189, 190.1\n",
+			want: []models.PriceBar{{Date: day(10), Open: 189, High: 191, Low: 188, Close: 190.5}},
+		},
+		{
+			name: "fractional volume",
+			file: "date,open,high,low,close,volume\n2024-05-10,189,191,188,190.5,1200.0\n",
+			want: []models.PriceBar{{Date: day(10), Open: 189, High: 191, Low: 188, Close: 190.5, Volume: 1200}},
+		},
+		{name: "missing column", file: "date,open,high,low\n2024-05-10,189,191,188\n", errText: "missing close column"},
+		{name: "invalid price", file: "date,open,high,low,close\n2024-05-10,n/a,191,188,190.5\n", errText: "line 2: invalid open"},
+		{name: "invalid date", file: "date,open,high,low,close\n10/05/2024,189,191,188,190.5\n", errText: "invalid date"},
+		{name: "header only", file: "date,open,high,low,close\n", errText: "no bars"},
+		{name: "empty file", file: "", errText: "failed to read header"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider := writeCSVFiles(t, map[string]string{"AAPL.csv": test.file})
+			bars, err := provider.GetDailyBars(context.Background(), "AAPL")
+			if test.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errText) {
+					t.Errorf("error = %v, want %q", err, test.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(bars, test.want) {
+				t.Errorf("bars = %+v, want %+v", bars, test.want)
+			}
+		})
+	}
+}
+
+func TestCSVProviderIntradayAndQuote(t *testing.T) {
+	provider := writeCSVFiles(t, map[string]string{
+		"AAPL.csv":      "date,open,high,low,close\n2024-05-09,188,190,187,189.5\n2024-05-10,189,191,188,190.5\n",
+		"AAPL_5min.csv": "datetime,open,high,low,close\n2024-05-10 09:35:00,189.2,189.6,189.1,189.4\n2024-05-10 09:30:00,189,189.3,188.9,189.2\n",
+	})
+	ctx := context.Background()
+
+	bars, err := provider.GetBars(ctx, "AAPL", "5min", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 2 || bars[0].Date.Minute() != 30 || bars[1].Date.Minute() != 35 {
+		t.Errorf("5min bars = %+v, want 09:30 then 09:35", bars)
+	}
+	if _, err := provider.GetBars(ctx, "AAPL", "15min", 10); err == nil {
+		t.Error("15min bars read without a 15min file")
+	}
+
+	quote, err := provider.GetQuote(ctx, "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Price != 190.5 || quote.Change != 1 {
+		t.Errorf("quote = %+v, want 190.5 changed by 1 from the previous close", quote)
+	}
+
+	for _, symbol := range []string{"../AAPL", `a\b`, ""} {
+		if _, err := provider.GetDailyBars(ctx, symbol); err == nil || !strings.Contains(err.Error(), "invalid symbol") {
+			t.Errorf("GetDailyBars(%q) = %v, want an invalid symbol", symbol, err)
+		}
+	}
+}
+
+func TestCSVProviderSearchSymbols(t *testing.T) {
+	provider := writeCSVFiles(t, map[string]string{
+		"XAAP.csv":      "",
+		"AAPL.csv":      "",
+		"AAP.csv":       "",
+		"AAPB.csv":      "",
+		"AAPL_5min.csv": "",
+		"AAPL.json":     "{}",
+		"MSFT.csv":      "",
+	})
+
+	matches, err := provider.SearchSymbols(context.Background(), " aap ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, match := range matches {
+		got = append(got, match.Symbol)
+	}
+	if want := []string{"AAP", "AAPB", "AAPL", "XAAP"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matches = %v, want %v: exact, then prefixes by symbol, then substrings", got, want)
+	}
+	if matches[0].Score != 1 || matches[1].Score != 0.8 || matches[3].Score != 0.5 {
+		t.Errorf("scores = %+v", matches)
+	}
+}
@@ -1,35 +1,34 @@
 package stock
 
 import (
+	"context"
 	"fmt"
 	"math"
-	"sort"
-	"time"
 
 	"proyecto-mcp-bolsa/pkg/models"
 )
 
 type EnhancedAnalyzer struct {
-	apiClient       *APIClient
-	historicalData  map[string]models.PriceHistory
+	provider        MarketDataProvider
 	predictionCache map[string]models.StockAnalysis
 }
 
-func NewEnhancedAnalyzer(apiClient *APIClient) *EnhancedAnalyzer {
+// NewEnhancedAnalyzer fetches every series from provider; wrap it in a Cache
+// to avoid refetching.
+func NewEnhancedAnalyzer(provider MarketDataProvider) *EnhancedAnalyzer {
 	return &EnhancedAnalyzer{
-		apiClient:       apiClient,
-		historicalData:  make(map[string]models.PriceHistory),
+		provider:        provider,
 		predictionCache: make(map[string]models.StockAnalysis),
 	}
 }
 
-func (e *EnhancedAnalyzer) AnalyzeStockWithReliability(symbol, timeframe string) (*models.StockAnalysis, error) {
-	stock, err := e.apiClient.GetQuote(symbol)
+func (e *EnhancedAnalyzer) AnalyzeStockWithReliability(ctx context.Context, symbol, timeframe string) (*models.StockAnalysis, error) {
+	stock, err := e.provider.GetQuote(ctx, symbol)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stock quote: %w", err)
 	}
 
-	priceHistory, err := e.buildPriceHistory(symbol, timeframe)
+	priceHistory, err := e.buildPriceHistory(ctx, symbol, timeframe)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build price history: %w", err)
 	}
@@ -62,42 +61,37 @@ func (e *EnhancedAnalyzer) AnalyzeStockWithReliability(symbol, timeframe string)
 	}, nil
 }
 
-func (e *EnhancedAnalyzer) buildPriceHistory(symbol, timeframe string) (models.PriceHistory, error) {
-	if cached, exists := e.historicalData[symbol]; exists {
-		if time.Since(cached.DataPoints[0].Date) < 1*time.Hour {
-			return cached, nil
-		}
-	}
-
-	timeSeries, err := e.apiClient.GetTimeSeries(symbol, timeframe)
+func (e *EnhancedAnalyzer) buildPriceHistory(ctx context.Context, symbol, timeframe string) (models.PriceHistory, error) {
+	frame, bars, err := timeframeBars(ctx, e.provider, symbol, timeframe)
 	if err != nil {
 		return models.PriceHistory{}, err
 	}
 
-	dataPoints := make([]models.PriceDataPoint, 0, len(timeSeries))
-	for date, stockData := range timeSeries {
-		parsedDate, _ := time.Parse("2006-01-02", date)
+	// Newest first, as the trend and pattern detection expects.
+	dataPoints := make([]models.PriceDataPoint, 0, len(bars))
+	for i := len(bars) - 1; i >= 0; i-- {
+		bar := bars[i]
+		change := bar.Close - bar.Open
+		changePerc := 0.0
+		if bar.Open != 0 {
+			changePerc = (change / bar.Open) * 100
+		}
 		dataPoints = append(dataPoints, models.PriceDataPoint{
-			Date:       parsedDate,
-			Price:      stockData.Price,
-			Volume:     stockData.Volume,
-			Change:     stockData.Change,
-			ChangePerc: stockData.ChangePerc,
+			Date:       bar.Date,
+			Price:      bar.Close,
+			Volume:     bar.Volume,
+			Change:     change,
+			ChangePerc: changePerc,
 		})
 	}
 
-	sort.Slice(dataPoints, func(i, j int) bool {
-		return dataPoints[i].Date.After(dataPoints[j].Date)
-	})
-
 	priceHistory := models.PriceHistory{
 		Symbol:     symbol,
-		Timeframe:  timeframe,
+		Timeframe:  frame.Name,
+		Interval:   frame.Interval,
 		DataPoints: dataPoints,
 	}
 
-	e.historicalData[symbol] = priceHistory
-
 	return priceHistory, nil
 }
 
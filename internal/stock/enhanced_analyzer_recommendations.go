@@ -467,7 +467,7 @@ func (e *EnhancedAnalyzer) calculateEnhancedIndicators(history models.PriceHisto
 		indicators.RSI = e.calculateRSI(prices, 14)
 	}
 
-	indicators.Volatility = e.calculateVolatility(prices)
+	indicators.Volatility = e.calculateVolatility(prices, history.Interval)
 
 	if indicators.SMA20 > 0 {
 		stdDev := e.calculateStandardDeviation(prices[len(prices)-20:])
@@ -577,7 +577,8 @@ func (e *EnhancedAnalyzer) calculateRSI(prices []float64, period int) float64 {
 	return rsi
 }
 
-func (e *EnhancedAnalyzer) calculateVolatility(prices []float64) float64 {
+// calculateVolatility is the annualized volatility of bars of interval.
+func (e *EnhancedAnalyzer) calculateVolatility(prices []float64, interval string) float64 {
 	if len(prices) < 2 {
 		return 0
 	}
@@ -585,7 +586,7 @@ func (e *EnhancedAnalyzer) calculateVolatility(prices []float64) float64 {
 	for i := 1; i < len(prices); i++ {
 		returns[i-1] = (prices[i] - prices[i-1]) / prices[i-1]
 	}
-	return e.calculateStandardDeviation(returns) * math.Sqrt(252)
+	return annualize(e.calculateStandardDeviation(returns), interval)
 }
 
 func (e *EnhancedAnalyzer) calculateStandardDeviation(values []float64) float64 {
@@ -0,0 +1,178 @@
+package stock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fixtureSecrets are query parameters left out of fixture names and files.
+var fixtureSecrets = []string{"apikey", "api_key", "token"}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fixture is one recorded HTTP response. Body holds JSON responses as is so
+// fixtures stay readable and editable; anything else goes in Text.
+type fixture struct {
+	URL         string          `json:"url"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"contentType,omitempty"`
+	RecordedAt  time.Time       `json:"recordedAt"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Text        string          `json:"text,omitempty"`
+}
+
+// FixtureRecorder is an http.RoundTripper that passes requests on and saves
+// each successful response under dir, for FixtureReplayer to serve later.
+type FixtureRecorder struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func NewFixtureRecorder(dir string, next http.RoundTripper) *FixtureRecorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &FixtureRecorder{dir: dir, next: next}
+}
+
+func (r *FixtureRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := fixture{
+		URL:         redactedURL(req),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		RecordedAt:  time.Now().UTC(),
+	}
+	if json.Valid(body) {
+		recorded.Body = body
+	} else {
+		recorded.Text = string(body)
+	}
+
+	if err := writeFixture(fixturePath(r.dir, req), recorded); err != nil {
+		return nil, fmt.Errorf("failed to record fixture: %w", err)
+	}
+	return resp, nil
+}
+
+// FixtureReplayer is an http.RoundTripper that answers from fixtures saved
+// by FixtureRecorder and never touches the network.
+type FixtureReplayer struct {
+	dir string
+}
+
+func NewFixtureReplayer(dir string) *FixtureReplayer {
+	return &FixtureReplayer{dir: dir}
+}
+
+func (r *FixtureReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := fixturePath(r.dir, req)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no fixture for %s (record it with -data-source=record:%s): %w", redactedURL(req), r.dir, err)
+	}
+
+	var recorded fixture
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+
+	body := []byte(recorded.Text)
+	if len(recorded.Body) > 0 {
+		body = recorded.Body
+	}
+	header := make(http.Header)
+	if recorded.ContentType != "" {
+		header.Set("Content-Type", recorded.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// fixturePath names the fixture of req after its host, path and query
+// values in key order, e.g. www.alphavantage.co/query/GLOBAL_QUOTE_AAPL.json.
+func fixturePath(dir string, req *http.Request) string {
+	query := req.URL.Query()
+	for _, secret := range fixtureSecrets {
+		query.Del(secret)
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, unsafeFixtureChars.ReplaceAllString(value, "-"))
+		}
+	}
+	name := strings.Join(parts, "_")
+	if name == "" {
+		name = "index"
+	}
+
+	segments := []string{dir, unsafeFixtureChars.ReplaceAllString(req.URL.Host, "-")}
+	for _, segment := range strings.Split(req.URL.Path, "/") {
+		if segment = unsafeFixtureChars.ReplaceAllString(segment, "-"); segment != "" && segment != "." && segment != ".." {
+			segments = append(segments, segment)
+		}
+	}
+	return filepath.Join(append(segments, name+".json")...)
+}
+
+func redactedURL(req *http.Request) string {
+	redacted := *req.URL
+	query := redacted.Query()
+	for _, secret := range fixtureSecrets {
+		query.Del(secret)
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+func writeFixture(path string, recorded fixture) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(recorded); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data.Bytes(), 0644)
+}
@@ -0,0 +1,119 @@
+package stock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAPIKey = "secret-test-key"
+
+// alphaVantageStub answers GLOBAL_QUOTE and TIME_SERIES_DAILY the way Alpha
+// Vantage does, and fails the test when the API key is missing.
+func alphaVantageStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("apikey") != testAPIKey {
+			t.Errorf("request without the API key: %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch query.Get("function") {
+		case "GLOBAL_QUOTE":
+			fmt.Fprintf(w, `{"Global Quote": {"01. symbol": "%s", "02. open": "189.00", "03. high": "191.00", "04. low": "188.00", "05. price": "190.50", "06. volume": "1200", "07. latest trading day": "2024-05-10", "08. previous close": "189.50", "09. change": "1.00", "10. change percent": "0.53%%"}}`, query.Get("symbol"))
+		case "TIME_SERIES_DAILY":
+			fmt.Fprint(w, `{"Meta Data": {}, "Time Series (Daily)": {
+				"2024-05-10": {"1. open": "189", "2. high": "191", "3. low": "188", "4. close": "190.5", "5. volume": "1200"},
+				"2024-05-09": {"1. open": "188", "2. high": "190", "3. low": "187", "4. close": "189.5", "5. volume": "1100"}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestFixturesRecordThenReplay(t *testing.T) {
+	upstream := alphaVantageStub(t)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	recorder := NewAPIClient(testAPIKey, upstream.URL+"/query")
+	recorder.SetTransport(NewFixtureRecorder(dir, nil))
+	recordedQuote, err := recorder.GetQuote(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("recording quote: %v", err)
+	}
+	recordedBars, err := recorder.GetDailyBars(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("recording bars: %v", err)
+	}
+
+	// Replay must not need the upstream any more.
+	upstream.Close()
+
+	replayer := NewAPIClient(testAPIKey, upstream.URL+"/query")
+	replayer.SetTransport(NewFixtureReplayer(dir))
+	replayedQuote, err := replayer.GetQuote(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("replaying quote: %v", err)
+	}
+	replayedBars, err := replayer.GetDailyBars(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("replaying bars: %v", err)
+	}
+	if !reflect.DeepEqual(recordedQuote, replayedQuote) {
+		t.Errorf("replayed quote %+v, recorded %+v", replayedQuote, recordedQuote)
+	}
+	if !reflect.DeepEqual(recordedBars, replayedBars) {
+		t.Errorf("replayed bars %+v, recorded %+v", replayedBars, recordedBars)
+	}
+
+	if _, err := replayer.GetQuote(ctx, "MSFT"); err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Errorf("replaying an unrecorded quote: got %v, want a missing fixture error", err)
+	}
+
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		files = append(files, path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(path, testAPIKey) || strings.Contains(string(data), testAPIKey) {
+			t.Errorf("fixture %s leaks the API key", path)
+		}
+		if strings.Contains(string(data), "apikey") {
+			t.Errorf("fixture %s keeps the apikey parameter", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("recorded %d fixtures, want 2: %v", len(files), files)
+	}
+}
+
+func TestFixturePathRedactsSecrets(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://api.example.com/v1/quote?symbol=BRK.B&token=abc&api_key=def&apikey=ghi&interval=5min", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := filepath.Join("fixtures", "api.example.com", "v1", "quote", "5min_BRK.B.json")
+	if got := fixturePath("fixtures", req); got != want {
+		t.Errorf("fixturePath = %s, want %s", got, want)
+	}
+	if got := redactedURL(req); strings.Contains(got, "abc") || strings.Contains(got, "def") || strings.Contains(got, "ghi") {
+		t.Errorf("redactedURL = %s still holds a secret", got)
+	}
+}
@@ -0,0 +1,248 @@
+package stock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// MarketDataProvider is a source of quotes, bars and company data. Bars are
+// returned oldest first.
+type MarketDataProvider interface {
+	// Name identifies the provider in errors and logs.
+	Name() string
+	GetQuote(ctx context.Context, symbol string) (*models.Stock, error)
+	// GetDailyBars returns recent daily bars, at least the latest 100 when
+	// the provider has them.
+	GetDailyBars(ctx context.Context, symbol string) ([]models.PriceBar, error)
+	// GetBars returns bars of one of BarIntervals, including at least the
+	// latest count of them when the provider has that many.
+	GetBars(ctx context.Context, symbol, interval string, count int) ([]models.PriceBar, error)
+	SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error)
+	GetCompanyOverview(ctx context.Context, symbol string) (*models.CompanyOverview, error)
+}
+
+// ErrRateLimited is wrapped by provider errors caused by an exhausted API
+// quota.
+var ErrRateLimited = errors.New("market data rate limit reached")
+
+// IntradayIntervals are the bar sizes shorter than a day.
+var IntradayIntervals = []string{"1min", "5min", "15min", "30min", "60min"}
+
+// BarIntervals are the bar sizes GetBars accepts.
+var BarIntervals = append(append([]string(nil), IntradayIntervals...), "daily", "weekly", "monthly")
+
+// compactBars is how many bars a compact upstream series holds; asking for
+// more fetches the full one.
+const compactBars = 100
+
+// rateLimitCooldown is how long FallbackProvider tries a rate limited
+// provider last.
+const rateLimitCooldown = time.Minute
+
+func checkInterval(interval string) error {
+	for _, valid := range BarIntervals {
+		if interval == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported bar interval %q (use %s)", interval, strings.Join(BarIntervals, ", "))
+}
+
+func isIntraday(interval string) bool {
+	for _, intraday := range IntradayIntervals {
+		if interval == intraday {
+			return true
+		}
+	}
+	return false
+}
+
+var barTimeLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", "2006-01-02 15:04", time.RFC3339}
+
+// parseBarTime reads the date or timestamp of a bar.
+func parseBarTime(value string) (time.Time, error) {
+	for _, layout := range barTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s", value)
+}
+
+// barSeries keys bars of interval by their date, or date and time for
+// intraday bars, the shape the indicator calculations work on.
+func barSeries(symbol, interval string, bars []models.PriceBar) map[string]models.Stock {
+	layout := "2006-01-02"
+	if isIntraday(interval) {
+		layout = "2006-01-02 15:04"
+	}
+
+	series := make(map[string]models.Stock, len(bars))
+	for _, bar := range bars {
+		change := bar.Close - bar.Open
+		changePerc := 0.0
+		if bar.Open != 0 {
+			changePerc = (change / bar.Open) * 100
+		}
+		series[bar.Date.Format(layout)] = models.Stock{
+			Symbol:      symbol,
+			Name:        symbol,
+			Price:       bar.Close,
+			Change:      change,
+			ChangePerc:  changePerc,
+			Volume:      bar.Volume,
+			LastUpdated: bar.Date,
+		}
+	}
+	return series
+}
+
+// resampleBars merges daily bars, oldest first, into weekly or monthly
+// bars dated on their last session.
+func resampleBars(daily []models.PriceBar, interval string) []models.PriceBar {
+	period := func(date time.Time) int {
+		if interval == "monthly" {
+			return date.Year()*100 + int(date.Month())
+		}
+		year, week := date.ISOWeek()
+		return year*100 + week
+	}
+
+	var bars []models.PriceBar
+	for i, bar := range daily {
+		if i == 0 || period(bar.Date) != period(daily[i-1].Date) {
+			bars = append(bars, bar)
+			continue
+		}
+		merged := &bars[len(bars)-1]
+		merged.Date = bar.Date
+		merged.High = math.Max(merged.High, bar.High)
+		merged.Low = math.Min(merged.Low, bar.Low)
+		merged.Close = bar.Close
+		merged.Volume += bar.Volume
+	}
+	return bars
+}
+
+// FallbackProvider asks its providers in order until one answers. A provider
+// that reported ErrRateLimited is tried last for a minute.
+type FallbackProvider struct {
+	providers []MarketDataProvider
+
+	mu           sync.Mutex
+	limitedUntil map[string]time.Time
+}
+
+func NewFallbackProvider(providers ...MarketDataProvider) *FallbackProvider {
+	return &FallbackProvider{
+		providers:    providers,
+		limitedUntil: make(map[string]time.Time),
+	}
+}
+
+func (f *FallbackProvider) Name() string {
+	names := make([]string, len(f.providers))
+	for i, provider := range f.providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, " > ")
+}
+
+func (f *FallbackProvider) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+	var quote *models.Stock
+	err := f.try(ctx, func(provider MarketDataProvider) (err error) {
+		quote, err = provider.GetQuote(ctx, symbol)
+		return err
+	})
+	return quote, err
+}
+
+func (f *FallbackProvider) GetDailyBars(ctx context.Context, symbol string) ([]models.PriceBar, error) {
+	var bars []models.PriceBar
+	err := f.try(ctx, func(provider MarketDataProvider) (err error) {
+		bars, err = provider.GetDailyBars(ctx, symbol)
+		return err
+	})
+	return bars, err
+}
+
+func (f *FallbackProvider) GetBars(ctx context.Context, symbol, interval string, count int) ([]models.PriceBar, error) {
+	var bars []models.PriceBar
+	err := f.try(ctx, func(provider MarketDataProvider) (err error) {
+		bars, err = provider.GetBars(ctx, symbol, interval, count)
+		return err
+	})
+	return bars, err
+}
+
+func (f *FallbackProvider) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	var matches []models.SymbolMatch
+	err := f.try(ctx, func(provider MarketDataProvider) (err error) {
+		matches, err = provider.SearchSymbols(ctx, keywords)
+		return err
+	})
+	return matches, err
+}
+
+func (f *FallbackProvider) GetCompanyOverview(ctx context.Context, symbol string) (*models.CompanyOverview, error) {
+	var overview *models.CompanyOverview
+	err := f.try(ctx, func(provider MarketDataProvider) (err error) {
+		overview, err = provider.GetCompanyOverview(ctx, symbol)
+		return err
+	})
+	return overview, err
+}
+
+// try runs call against each provider until one succeeds. Cancellation of
+// ctx stops the failover.
+func (f *FallbackProvider) try(ctx context.Context, call func(MarketDataProvider) error) error {
+	var errs []error
+	for _, provider := range f.ordered() {
+		err := call(provider)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if errors.Is(err, ErrRateLimited) {
+			f.mu.Lock()
+			f.limitedUntil[provider.Name()] = time.Now().Add(rateLimitCooldown)
+			f.mu.Unlock()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return fmt.Errorf("all market data providers failed: %w", errors.Join(errs...))
+}
+
+// ordered returns the providers in configured order, rate limited ones
+// moved to the end.
+func (f *FallbackProvider) ordered() []MarketDataProvider {
+	now := time.Now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ready := make([]MarketDataProvider, 0, len(f.providers))
+	var limited []MarketDataProvider
+	for _, provider := range f.providers {
+		if now.Before(f.limitedUntil[provider.Name()]) {
+			limited = append(limited, provider)
+		} else {
+			ready = append(ready, provider)
+		}
+	}
+	return append(ready, limited...)
+}
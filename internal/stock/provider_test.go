@@ -0,0 +1,134 @@
+package stock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// failoverProvider answers quotes with err, or a quote when err is nil, and
+// records its name in calls. Its other methods are not used.
+type failoverProvider struct {
+	MarketDataProvider
+	name  string
+	err   error
+	calls *[]string
+	// onCall runs before answering, e.g. to cancel the caller's context.
+	onCall func()
+}
+
+func (p *failoverProvider) Name() string {
+	return p.name
+}
+
+func (p *failoverProvider) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+	*p.calls = append(*p.calls, p.name)
+	if p.onCall != nil {
+		p.onCall()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &models.Stock{Symbol: symbol, Name: p.name}, nil
+}
+
+func TestFallbackProviderFailsOver(t *testing.T) {
+	unavailable := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		errs     []error
+		want     []string
+		answered string
+		errText  string
+	}{
+		{"first answers", []error{nil, nil, nil}, []string{"a"}, "a", ""},
+		{"first fails", []error{unavailable, nil, nil}, []string{"a", "b"}, "b", ""},
+		{"first two fail", []error{unavailable, unavailable, nil}, []string{"a", "b", "c"}, "c", ""},
+		{"all fail", []error{unavailable, unavailable, unavailable}, []string{"a", "b", "c"}, "", "all market data providers failed"},
+		{"only provider fails", []error{unavailable}, []string{"a"}, "", "a: connection refused"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []string
+			var providers []MarketDataProvider
+			for i, err := range test.errs {
+				providers = append(providers, &failoverProvider{name: string(rune('a' + i)), err: err, calls: &calls})
+			}
+
+			quote, err := NewFallbackProvider(providers...).GetQuote(context.Background(), "AAPL")
+			if !reflect.DeepEqual(calls, test.want) {
+				t.Errorf("asked %v, want %v", calls, test.want)
+			}
+			if test.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errText) || !errors.Is(err, unavailable) {
+					t.Errorf("error = %v, want %q wrapping the providers' errors", err, test.errText)
+				}
+				return
+			}
+			if err != nil || quote.Name != test.answered {
+				t.Errorf("GetQuote = %+v, %v; want the answer of %s", quote, err, test.answered)
+			}
+		})
+	}
+}
+
+func TestFallbackProviderTriesRateLimitedLast(t *testing.T) {
+	var calls []string
+	limited := &failoverProvider{name: "a", err: fmt.Errorf("%w: quota", ErrRateLimited), calls: &calls}
+	fallback := NewFallbackProvider(limited, &failoverProvider{name: "b", calls: &calls}, &failoverProvider{name: "c", calls: &calls})
+	ctx := context.Background()
+
+	for _, want := range [][]string{{"a", "b"}, {"b"}} {
+		calls = nil
+		if _, err := fallback.GetQuote(ctx, "AAPL"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("asked %v, want %v", calls, want)
+		}
+	}
+	if until := fallback.limitedUntil["a"]; time.Until(until) < rateLimitCooldown-time.Second {
+		t.Errorf("a limited until %v, want about %v from now", until, rateLimitCooldown)
+	}
+	names := func(providers []MarketDataProvider) []string {
+		var names []string
+		for _, provider := range providers {
+			names = append(names, provider.Name())
+		}
+		return names
+	}
+	if got := names(fallback.ordered()); !reflect.DeepEqual(got, []string{"b", "c", "a"}) {
+		t.Errorf("order during the cooldown = %v, want b, c, a", got)
+	}
+
+	// After the cooldown a is back in front.
+	fallback.limitedUntil["a"] = time.Now().Add(-time.Second)
+	limited.err = nil
+	calls = nil
+	if quote, err := fallback.GetQuote(ctx, "AAPL"); err != nil || quote.Name != "a" {
+		t.Errorf("GetQuote after the cooldown = %+v, %v; want a's answer", quote, err)
+	}
+}
+
+func TestFallbackProviderStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls []string
+	fallback := NewFallbackProvider(
+		&failoverProvider{name: "a", err: context.Canceled, calls: &calls, onCall: cancel},
+		&failoverProvider{name: "b", calls: &calls},
+	)
+
+	if _, err := fallback.GetQuote(ctx, "AAPL"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetQuote = %v, want %v", err, context.Canceled)
+	}
+	if !reflect.DeepEqual(calls, []string{"a"}) {
+		t.Errorf("asked %v after the caller gave up, want only a", calls)
+	}
+}
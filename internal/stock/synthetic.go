@@ -0,0 +1,218 @@
+package stock
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// syntheticDays is how many daily bars SyntheticProvider generates, about
+// ten years of sessions for the weekly and monthly series.
+const syntheticDays = 2610
+
+// sessionsPerYear is how many daily bars make a year.
+const sessionsPerYear = 261
+
+// syntheticIntradayBars is how many bars an intraday series has unless more
+// are asked for.
+const syntheticIntradayBars = 100
+
+var syntheticSectors = []string{"Technology", "Healthcare", "Financials", "Energy", "Industrials", "Consumer Staples", "Utilities"}
+
+// SyntheticProvider makes up random-walk OHLCV series, so the analysis runs
+// without an API key or network. A symbol's prices depend only on the seed
+// and the symbol; the bars end on the latest weekday.
+type SyntheticProvider struct {
+	seed int64
+}
+
+func NewSyntheticProvider(seed int64) *SyntheticProvider {
+	return &SyntheticProvider{seed: seed}
+}
+
+func (p *SyntheticProvider) Name() string {
+	return fmt.Sprintf("synthetic(seed %d)", p.seed)
+}
+
+func (p *SyntheticProvider) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+	bars, err := p.GetDailyBars(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	last, previous := bars[len(bars)-1], bars[len(bars)-2]
+	change := last.Close - previous.Close
+
+	return &models.Stock{
+		Symbol:      symbol,
+		Name:        symbol,
+		Price:       last.Close,
+		Change:      change,
+		ChangePerc:  (change / previous.Close) * 100,
+		Volume:      last.Volume,
+		LastUpdated: last.Date,
+	}, nil
+}
+
+func (p *SyntheticProvider) GetDailyBars(ctx context.Context, symbol string) ([]models.PriceBar, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	rng := p.rand(symbol, "daily")
+	price := 20 + rng.Float64()*480
+	volatility := 0.01 + rng.Float64()*0.02
+	drift := (rng.Float64() - 0.45) * 0.002
+	baseVolume := 1e5 + rng.Float64()*5e6
+
+	dates := tradingDays(latestWeekday(time.Now().UTC()), syntheticDays)
+	bars := make([]models.PriceBar, len(dates))
+	for i, date := range dates {
+		bars[i] = randomBar(rng, date, price, drift, volatility, baseVolume)
+		price = bars[i].Close
+	}
+	return bars, nil
+}
+
+// GetBars merges the daily bars into weekly or monthly ones. Intraday bars
+// cover the regular session, 9:30 to 16:00 New York time, of the latest
+// weekdays.
+func (p *SyntheticProvider) GetBars(ctx context.Context, symbol, interval string, count int) ([]models.PriceBar, error) {
+	if err := checkInterval(interval); err != nil {
+		return nil, err
+	}
+
+	daily, err := p.GetDailyBars(ctx, symbol)
+	if err != nil || interval == "daily" {
+		return daily, err
+	}
+	if !isIntraday(interval) {
+		return resampleBars(daily, interval), nil
+	}
+	count = max(count, syntheticIntradayBars)
+
+	step, _ := time.ParseDuration(strings.TrimSuffix(interval, "in"))
+	rng := p.rand(symbol, interval)
+	price := daily[len(daily)-2].Close
+	// Scale the daily volatility down to one bar of a 6.5 hour session.
+	volatility := 0.015 * math.Sqrt(step.Minutes()/390)
+	baseVolume := 1e5 * step.Minutes()
+
+	var stamps []time.Time
+	for day := latestWeekday(time.Now().UTC()); len(stamps) < count; day = previousWeekday(day) {
+		open := time.Date(day.Year(), day.Month(), day.Day(), 9, 30, 0, 0, newYork)
+		var session []time.Time
+		for stamp := open.Add(step); !stamp.After(open.Add(390 * time.Minute)); stamp = stamp.Add(step) {
+			session = append(session, stamp)
+		}
+		stamps = append(session, stamps...)
+	}
+	stamps = stamps[len(stamps)-count:]
+
+	bars := make([]models.PriceBar, len(stamps))
+	for i, stamp := range stamps {
+		bars[i] = randomBar(rng, stamp, price, 0, volatility, baseVolume)
+		price = bars[i].Close
+	}
+	return bars, nil
+}
+
+// SearchSymbols offers the keywords themselves: every symbol exists here.
+func (p *SyntheticProvider) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(keywords))
+	if symbol == "" {
+		return nil, nil
+	}
+	return []models.SymbolMatch{{
+		Symbol: symbol,
+		Name:   symbol + " (synthetic)",
+		Type:   "Equity",
+		Score:  1,
+	}}, nil
+}
+
+func (p *SyntheticProvider) GetCompanyOverview(ctx context.Context, symbol string) (*models.CompanyOverview, error) {
+	bars, err := p.GetDailyBars(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	rng := p.rand(symbol, "overview")
+	price := bars[len(bars)-1].Close
+	bars = bars[len(bars)-sessionsPerYear:]
+	eps := price / (8 + rng.Float64()*30)
+	high, low := bars[0].High, bars[0].Low
+	for _, bar := range bars {
+		high = math.Max(high, bar.High)
+		low = math.Min(low, bar.Low)
+	}
+
+	return &models.CompanyOverview{
+		Symbol:        symbol,
+		Name:          symbol + " Synthetic Corp",
+		Description:   "Generated company for offline runs; its figures are random.",
+		Exchange:      "SYNTH",
+		Currency:      "USD",
+		Country:       "USA",
+		Sector:        syntheticSectors[rng.Intn(len(syntheticSectors))],
+		MarketCap:     int64(price * (1e7 + rng.Float64()*5e9)),
+		PERatio:       price / eps,
+		EPS:           eps,
+		DividendYield: rng.Float64() * 0.04,
+		Beta:          0.5 + rng.Float64()*1.2,
+		Week52High:    high,
+		Week52Low:     low,
+	}, nil
+}
+
+// rand returns the generator of one series of symbol.
+func (p *SyntheticProvider) rand(symbol, series string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(symbol + "/" + series))
+	return rand.New(rand.NewSource(p.seed ^ int64(h.Sum64())))
+}
+
+// randomBar moves price one step of a geometric random walk.
+func randomBar(rng *rand.Rand, date time.Time, price, drift, volatility, baseVolume float64) models.PriceBar {
+	open := price * (1 + rng.NormFloat64()*volatility/4)
+	closePrice := open * math.Exp(drift+rng.NormFloat64()*volatility)
+	high := math.Max(open, closePrice) * (1 + math.Abs(rng.NormFloat64())*volatility/2)
+	low := math.Min(open, closePrice) * (1 - math.Abs(rng.NormFloat64())*volatility/2)
+
+	return models.PriceBar{
+		Date:   date,
+		Open:   math.Round(open*100) / 100,
+		High:   math.Round(high*100) / 100,
+		Low:    math.Round(low*100) / 100,
+		Close:  math.Round(closePrice*100) / 100,
+		Volume: int64(baseVolume * math.Exp(rng.NormFloat64()*0.3)),
+	}
+}
+
+// tradingDays returns the count weekdays ending at last, oldest first.
+func tradingDays(last time.Time, count int) []time.Time {
+	days := make([]time.Time, count)
+	for i := count - 1; i >= 0; i-- {
+		days[i] = last
+		last = previousWeekday(last)
+	}
+	return days
+}
+
+func latestWeekday(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+func previousWeekday(day time.Time) time.Time {
+	return latestWeekday(day.AddDate(0, 0, -1))
+}
@@ -0,0 +1,52 @@
+package stock
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSyntheticProviderIsDeterministic(t *testing.T) {
+	ctx := context.Background()
+	for _, interval := range []string{"5min", "60min", "daily", "weekly", "monthly"} {
+		first, err := NewSyntheticProvider(42).GetBars(ctx, "AAPL", interval, 100)
+		if err != nil {
+			t.Fatalf("%s: %v", interval, err)
+		}
+		second, err := NewSyntheticProvider(42).GetBars(ctx, "AAPL", interval, 100)
+		if err != nil {
+			t.Fatalf("%s: %v", interval, err)
+		}
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("%s: the same seed gave different series", interval)
+		}
+
+		otherSeed, err := NewSyntheticProvider(43).GetBars(ctx, "AAPL", interval, 100)
+		if err != nil {
+			t.Fatalf("%s: %v", interval, err)
+		}
+		if reflect.DeepEqual(first, otherSeed) {
+			t.Errorf("%s: seeds 42 and 43 gave the same series", interval)
+		}
+
+		otherSymbol, err := NewSyntheticProvider(42).GetBars(ctx, "MSFT", interval, 100)
+		if err != nil {
+			t.Fatalf("%s: %v", interval, err)
+		}
+		if reflect.DeepEqual(first, otherSymbol) {
+			t.Errorf("%s: AAPL and MSFT gave the same series", interval)
+		}
+	}
+
+	quote, err := NewSyntheticProvider(42).GetQuote(ctx, "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := NewSyntheticProvider(42).GetQuote(ctx, "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(quote, again) {
+		t.Errorf("the same seed gave quotes %+v and %+v", quote, again)
+	}
+}
@@ -0,0 +1,156 @@
+package stock
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RequestLimits paces the requests a client sends upstream. Requests wait
+// for a token of a bucket refilled at RequestsPerMinute and holding Burst;
+// zero RequestsPerMinute sends them right away. A throttled request is
+// retried up to MaxRetries times, waiting RetryBackoff and then twice as
+// long after each further throttle.
+type RequestLimits struct {
+	RequestsPerMinute float64
+	Burst             int
+	MaxRetries        int
+	RetryBackoff      time.Duration
+}
+
+// requestScheduler queues requests through a token bucket. After upstream
+// throttles a request, pause holds every request until the backoff is over.
+type requestScheduler struct {
+	mu          sync.Mutex
+	tokens      float64
+	capacity    float64
+	rate        float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newRequestScheduler(limits RequestLimits) *requestScheduler {
+	capacity := math.Max(1, float64(limits.Burst))
+	return &requestScheduler{
+		tokens:   capacity,
+		capacity: capacity,
+		rate:     limits.RequestsPerMinute / 60,
+		last:     time.Now(),
+	}
+}
+
+// wait blocks until a request may be sent or ctx is done.
+func (s *requestScheduler) wait(ctx context.Context) error {
+	var delay time.Duration
+	s.mu.Lock()
+	if s.rate > 0 {
+		now := time.Now()
+		s.tokens = math.Min(s.capacity, s.tokens+now.Sub(s.last).Seconds()*s.rate)
+		s.last = now
+		// Taking the token on credit keeps waiting requests in order.
+		s.tokens--
+		if s.tokens < 0 {
+			delay = time.Duration(-s.tokens / s.rate * float64(time.Second))
+		}
+	}
+	s.mu.Unlock()
+
+	for {
+		s.mu.Lock()
+		if pause := time.Until(s.pausedUntil); pause > delay {
+			delay = pause
+		}
+		s.mu.Unlock()
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if s.rate > 0 {
+				s.mu.Lock()
+				s.tokens++
+				s.mu.Unlock()
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = 0
+	}
+}
+
+func (s *requestScheduler) pause(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until := time.Now().Add(d); until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+}
+
+// inflightRequest is an upstream request shared by identical calls.
+type inflightRequest struct {
+	done    chan struct{}
+	body    []byte
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// requestGroup sends concurrent identical requests upstream once.
+type requestGroup struct {
+	mu       sync.Mutex
+	requests map[string]*inflightRequest
+}
+
+// do returns the body fetched for key, joining a request already in flight
+// for it. The fetch is cancelled once every caller has given up.
+func (g *requestGroup) do(ctx context.Context, key string, fetch func(context.Context) ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.requests == nil {
+		g.requests = make(map[string]*inflightRequest)
+	}
+	request, exists := g.requests[key]
+	if !exists {
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		request = &inflightRequest{done: make(chan struct{}), cancel: cancel}
+		g.requests[key] = request
+		go func() {
+			request.body, request.err = fetch(fetchCtx)
+			cancel()
+			g.forget(key, request)
+			close(request.done)
+		}()
+	}
+	request.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-request.done:
+		return request.body, request.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		request.waiters--
+		if request.waiters == 0 {
+			g.forgetLocked(key, request)
+			request.cancel()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (g *requestGroup) forget(key string, request *inflightRequest) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.forgetLocked(key, request)
+}
+
+// forgetLocked stops new calls from joining request.
+func (g *requestGroup) forgetLocked(key string, request *inflightRequest) {
+	if g.requests[key] == request {
+		delete(g.requests, key)
+	}
+}
@@ -0,0 +1,179 @@
+package stock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestSchedulerPacesRequests(t *testing.T) {
+	// Ten requests a second, two of them at once.
+	scheduler := newRequestScheduler(RequestLimits{RequestsPerMinute: 600, Burst: 2})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := scheduler.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("the burst waited %v", elapsed)
+	}
+	if err := scheduler.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("the request after the burst waited %v, want about 100ms", elapsed)
+	}
+
+	// A request that gives up returns its token to the bucket.
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := scheduler.wait(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait past the deadline = %v, want %v", err, context.DeadlineExceeded)
+	}
+	scheduler.mu.Lock()
+	tokens := scheduler.tokens
+	scheduler.mu.Unlock()
+	if tokens < -0.5 {
+		t.Errorf("tokens = %.2f after a cancelled wait, want the token returned", tokens)
+	}
+}
+
+func TestRequestSchedulerPause(t *testing.T) {
+	// Unpaced requests still wait out a pause.
+	scheduler := newRequestScheduler(RequestLimits{})
+	scheduler.pause(60 * time.Millisecond)
+	scheduler.pause(time.Millisecond) // does not shorten the pause
+
+	start := time.Now()
+	if err := scheduler.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("wait during a 60ms pause took %v", elapsed)
+	}
+	start = time.Now()
+	if err := scheduler.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("wait after the pause took %v", elapsed)
+	}
+}
+
+// waitForWaiters blocks until n callers have joined the request for key.
+func waitForWaiters(t *testing.T, g *requestGroup, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.mu.Lock()
+		waiters := 0
+		if request := g.requests[key]; request != nil {
+			waiters = request.waiters
+		}
+		g.mu.Unlock()
+		if waiters == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers joined %q, want %d", waiters, key, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRequestGroupSharesFetches(t *testing.T) {
+	var g requestGroup
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return []byte("body"), nil
+	}
+
+	const callers = 5
+	bodies := make([]string, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body, err := g.do(context.Background(), "key", fetch)
+			if err != nil {
+				t.Error(err)
+			}
+			bodies[i] = string(body)
+		}(i)
+	}
+	waitForWaiters(t, &g, "key", callers)
+	close(release)
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("%d fetches for %d identical calls, want 1", fetches, callers)
+	}
+	for i, body := range bodies {
+		if body != "body" {
+			t.Errorf("caller %d got %q", i, body)
+		}
+	}
+	if _, err := g.do(context.Background(), "key", fetch); err != nil || fetches != 2 {
+		t.Errorf("a call after the fetch finished: %v, %d fetches; want a new fetch", err, fetches)
+	}
+}
+
+func TestRequestGroupCancelsWithLastWaiter(t *testing.T) {
+	var g requestGroup
+	cancelled := make(chan struct{})
+	fetch := func(ctx context.Context) ([]byte, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+	errs := make(chan error, 2)
+	go func() {
+		_, err := g.do(first, "key", fetch)
+		errs <- err
+	}()
+	waitForWaiters(t, &g, "key", 1)
+	go func() {
+		_, err := g.do(second, "key", fetch)
+		errs <- err
+	}()
+	waitForWaiters(t, &g, "key", 2)
+
+	cancelFirst()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("first caller = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("fetch cancelled while a caller still waits for it")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelSecond()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("second caller = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fetch not cancelled after every caller gave up")
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.requests) != 0 {
+		t.Errorf("cancelled request still joinable: %v", g.requests)
+	}
+}
@@ -0,0 +1,123 @@
+package stock
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// DefaultTimeframe is analyzed when no timeframe is given.
+const DefaultTimeframe = "1M"
+
+// Timeframe is an analysis window covered by its latest Bars bars of
+// Interval.
+type Timeframe struct {
+	Name     string
+	Interval string
+	Bars     int
+}
+
+// Timeframes are the windows the analyzers accept, shortest first. A trading
+// session has 78 five-minute or 26 quarter-hour bars and a month 21
+// sessions. Only the windows of a week or less are read in intraday bars.
+var Timeframes = []Timeframe{
+	{Name: "1D", Interval: "5min", Bars: 78},
+	{Name: "5D", Interval: "15min", Bars: 130},
+	{Name: "1M", Interval: "daily", Bars: 21},
+	{Name: "3M", Interval: "daily", Bars: 63},
+	{Name: "6M", Interval: "daily", Bars: 126},
+	{Name: "1Y", Interval: "daily", Bars: 252},
+	{Name: "5Y", Interval: "weekly", Bars: 260},
+	{Name: "10Y", Interval: "monthly", Bars: 120},
+}
+
+// indicatorBars is how many bars the indicators need. Shorter windows are
+// read together with the bars before them, so that SMA50 and the long-term
+// trend still cover 50 bars.
+const indicatorBars = 50
+
+// barsPerYear is how many bars of each interval a year of trading has, to
+// annualize per-bar volatility. Intraday intervals count the regular
+// session only.
+var barsPerYear = map[string]float64{
+	"1min":    252 * 390,
+	"5min":    252 * 78,
+	"15min":   252 * 26,
+	"30min":   252 * 13,
+	"60min":   252 * 7,
+	"daily":   252,
+	"weekly":  52,
+	"monthly": 12,
+}
+
+// annualize scales the standard deviation of per-bar returns of interval to
+// a year. Unknown intervals are taken as daily.
+func annualize(stdDev float64, interval string) float64 {
+	perYear, ok := barsPerYear[interval]
+	if !ok {
+		perYear = barsPerYear["daily"]
+	}
+	return stdDev * math.Sqrt(perYear)
+}
+
+// BarName describes the timeframe's bars in labels, e.g. "daily" or
+// "5-minute".
+func (t Timeframe) BarName() string {
+	switch t.Interval {
+	case "60min":
+		return "hourly"
+	case "daily", "weekly", "monthly":
+		return t.Interval
+	}
+	return strings.TrimSuffix(t.Interval, "min") + "-minute"
+}
+
+// ParseTimeframe looks name up in Timeframes; empty means DefaultTimeframe.
+func ParseTimeframe(name string) (Timeframe, error) {
+	if name == "" {
+		name = DefaultTimeframe
+	}
+	names := make([]string, len(Timeframes))
+	for i, timeframe := range Timeframes {
+		if strings.EqualFold(name, timeframe.Name) {
+			return timeframe, nil
+		}
+		names[i] = timeframe.Name
+	}
+	return Timeframe{}, fmt.Errorf("unsupported timeframe %q (use %s)", name, strings.Join(names, ", "))
+}
+
+// timeframeBars fetches symbol's bars for the named timeframe, trimmed to
+// its window or to indicatorBars, whichever is longer.
+func timeframeBars(ctx context.Context, provider MarketDataProvider, symbol, name string) (Timeframe, []models.PriceBar, error) {
+	timeframe, err := ParseTimeframe(name)
+	if err != nil {
+		return Timeframe{}, nil, err
+	}
+
+	count := timeframe.Bars
+	if count < indicatorBars {
+		count = indicatorBars
+	}
+	bars, err := provider.GetBars(ctx, symbol, timeframe.Interval, count)
+	if err != nil {
+		return Timeframe{}, nil, err
+	}
+	if len(bars) > count {
+		bars = bars[len(bars)-count:]
+	}
+	return timeframe, bars, nil
+}
+
+// timeframeSeries is timeframeBars keyed by date for the indicator
+// calculations.
+func timeframeSeries(ctx context.Context, provider MarketDataProvider, symbol, name string) (Timeframe, map[string]models.Stock, error) {
+	timeframe, bars, err := timeframeBars(ctx, provider, symbol, name)
+	if err != nil {
+		return Timeframe{}, nil, err
+	}
+	return timeframe, barSeries(symbol, timeframe.Interval, bars), nil
+}
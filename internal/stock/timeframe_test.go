@@ -0,0 +1,43 @@
+package stock
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestTimeframeBarsCoverTheIndicators(t *testing.T) {
+	provider := &countingProvider{price: 100}
+
+	for _, tc := range []struct {
+		name     string
+		interval string
+		bars     int
+	}{
+		{"1D", "5min", 78},
+		{"1M", "daily", indicatorBars},
+		{"1Y", "daily", 252},
+	} {
+		frame, bars, err := timeframeBars(context.Background(), provider, "AAPL", tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frame.Interval != tc.interval || len(bars) != tc.bars {
+			t.Errorf("%s: got %d bars of %s, want %d of %s", tc.name, len(bars), frame.Interval, tc.bars, tc.interval)
+		}
+	}
+}
+
+func TestAnnualizeByInterval(t *testing.T) {
+	for interval, want := range map[string]float64{
+		"5min":    math.Sqrt(252 * 78),
+		"daily":   math.Sqrt(252),
+		"weekly":  math.Sqrt(52),
+		"monthly": math.Sqrt(12),
+		"":        math.Sqrt(252),
+	} {
+		if got := annualize(1, interval); math.Abs(got-want) > 1e-9 {
+			t.Errorf("annualize(1, %q) = %v, want %v", interval, got, want)
+		}
+	}
+}
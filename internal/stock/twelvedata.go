@@ -0,0 +1,279 @@
+package stock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// twelveDataIntervals maps BarIntervals to Twelve Data's names.
+var twelveDataIntervals = map[string]string{
+	"1min":    "1min",
+	"5min":    "5min",
+	"15min":   "15min",
+	"30min":   "30min",
+	"60min":   "1h",
+	"daily":   "1day",
+	"weekly":  "1week",
+	"monthly": "1month",
+}
+
+// twelveDataFullSize is the most bars Twelve Data returns at once.
+const twelveDataFullSize = 5000
+
+// TwelveDataClient is the Twelve Data (twelvedata.com) MarketDataProvider.
+type TwelveDataClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewTwelveDataClient(apiKey, baseURL string) *TwelveDataClient {
+	return &TwelveDataClient{
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SetTransport routes the client's requests through transport, e.g. a
+// FixtureRecorder or FixtureReplayer.
+func (c *TwelveDataClient) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+}
+
+func (c *TwelveDataClient) Name() string {
+	return "twelveData"
+}
+
+func (c *TwelveDataClient) GetQuote(ctx context.Context, symbol string) (*models.Stock, error) {
+	body, err := c.get(ctx, "/quote", url.Values{"symbol": {symbol}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote for %s: %w", symbol, err)
+	}
+
+	var quote models.TwelveDataQuote
+	if err := json.Unmarshal(body, &quote); err != nil {
+		return nil, fmt.Errorf("failed to parse quote response: %w", err)
+	}
+	if quote.Symbol == "" {
+		return nil, fmt.Errorf("no data returned for symbol: %s", symbol)
+	}
+
+	price, err := strconv.ParseFloat(quote.Close, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price: %s", quote.Close)
+	}
+	change, err := strconv.ParseFloat(quote.Change, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid change: %s", quote.Change)
+	}
+	changePerc, err := strconv.ParseFloat(quote.PercentChange, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid change percent: %s", quote.PercentChange)
+	}
+	volume, _ := strconv.ParseInt(quote.Volume, 10, 64)
+	lastUpdated, err := parseBarTime(quote.Datetime)
+	if err != nil {
+		return nil, err
+	}
+
+	name := quote.Name
+	if name == "" {
+		name = quote.Symbol
+	}
+
+	return &models.Stock{
+		Symbol:      quote.Symbol,
+		Name:        name,
+		Price:       price,
+		Change:      change,
+		ChangePerc:  changePerc,
+		Volume:      volume,
+		LastUpdated: lastUpdated,
+	}, nil
+}
+
+func (c *TwelveDataClient) GetDailyBars(ctx context.Context, symbol string) ([]models.PriceBar, error) {
+	return c.GetBars(ctx, symbol, "daily", compactBars)
+}
+
+func (c *TwelveDataClient) GetBars(ctx context.Context, symbol, interval string, count int) ([]models.PriceBar, error) {
+	if err := checkInterval(interval); err != nil {
+		return nil, err
+	}
+	size := compactBars
+	if count > compactBars {
+		size = twelveDataFullSize
+	}
+	return c.timeSeries(ctx, symbol, twelveDataIntervals[interval], size)
+}
+
+func (c *TwelveDataClient) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	body, err := c.get(ctx, "/symbol_search", url.Values{"symbol": {keywords}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search symbols for %q: %w", keywords, err)
+	}
+
+	var search models.TwelveDataSearch
+	if err := json.Unmarshal(body, &search); err != nil {
+		return nil, fmt.Errorf("failed to parse search response: %w", err)
+	}
+
+	matches := make([]models.SymbolMatch, 0, len(search.Data))
+	for _, match := range search.Data {
+		matches = append(matches, models.SymbolMatch{
+			Symbol:   match.Symbol,
+			Name:     match.InstrumentName,
+			Type:     match.InstrumentType,
+			Region:   match.Country,
+			Currency: match.Currency,
+		})
+	}
+
+	return matches, nil
+}
+
+func (c *TwelveDataClient) GetCompanyOverview(ctx context.Context, symbol string) (*models.CompanyOverview, error) {
+	body, err := c.get(ctx, "/profile", url.Values{"symbol": {symbol}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overview for %s: %w", symbol, err)
+	}
+
+	var profile models.TwelveDataProfile
+	if err := json.Unmarshal(body, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse overview response: %w", err)
+	}
+	if profile.Symbol == "" {
+		return nil, fmt.Errorf("no data returned for symbol: %s", symbol)
+	}
+
+	return &models.CompanyOverview{
+		Symbol:      profile.Symbol,
+		Name:        profile.Name,
+		Description: profile.Description,
+		Exchange:    profile.Exchange,
+		Country:     profile.Country,
+		Sector:      profile.Sector,
+		Industry:    profile.Industry,
+	}, nil
+}
+
+func (c *TwelveDataClient) timeSeries(ctx context.Context, symbol, interval string, size int) ([]models.PriceBar, error) {
+	params := url.Values{
+		"symbol":     {symbol},
+		"interval":   {interval},
+		"outputsize": {strconv.Itoa(size)},
+	}
+
+	body, err := c.get(ctx, "/time_series", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time series for %s: %w", symbol, err)
+	}
+
+	var series models.TwelveDataTimeSeries
+	if err := json.Unmarshal(body, &series); err != nil {
+		return nil, fmt.Errorf("failed to parse time series response: %w", err)
+	}
+
+	bars := make([]models.PriceBar, 0, len(series.Values))
+	for _, value := range series.Values {
+		bar, err := convertTwelveDataBar(value)
+		if err != nil {
+			continue
+		}
+		bars = append(bars, *bar)
+	}
+
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no valid time series data returned for symbol: %s", symbol)
+	}
+
+	sort.Slice(bars, func(i, j int) bool {
+		return bars[i].Date.Before(bars[j].Date)
+	})
+
+	return bars, nil
+}
+
+// get calls an endpoint and returns the body, turning Twelve Data's error
+// envelope into an error.
+func (c *TwelveDataClient) get(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("Twelve Data API key required")
+	}
+	params.Set("apikey", c.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "MCP Stock Analyzer/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var status models.TwelveDataStatus
+	if err := json.Unmarshal(body, &status); err == nil && status.Status == "error" {
+		if status.Code == http.StatusTooManyRequests {
+			return nil, fmt.Errorf("%w: %s", ErrRateLimited, status.Message)
+		}
+		return nil, fmt.Errorf("API error %d: %s", status.Code, status.Message)
+	}
+
+	return body, nil
+}
+
+func convertTwelveDataBar(data models.TwelveDataBar) (*models.PriceBar, error) {
+	date, err := parseBarTime(data.Datetime)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, 4)
+	for i, raw := range []string{data.Open, data.High, data.Low, data.Close} {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price: %s", raw)
+		}
+		values[i] = value
+	}
+
+	// Indices and currencies come without volume.
+	volume, _ := strconv.ParseInt(data.Volume, 10, 64)
+
+	return &models.PriceBar{
+		Date:   date,
+		Open:   values[0],
+		High:   values[1],
+		Low:    values[2],
+		Close:  values[3],
+		Volume: volume,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package stock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTwelveDataErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		rateLimited bool
+		errText     string
+	}{
+		{"credits exhausted", http.StatusOK, `{"code": 429, "message": "You have run out of API credits for the current minute.", "status": "error"}`, true, "run out of API credits"},
+		{"HTTP 429", http.StatusTooManyRequests, "", true, ""},
+		{"unknown symbol", http.StatusOK, `{"code": 404, "message": "symbol not found: NOPE", "status": "error"}`, false, "API error 404: symbol not found"},
+		{"server error", http.StatusBadGateway, "", false, "status 502"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/quote" || r.URL.Query().Get("apikey") != testAPIKey {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			_, err := NewTwelveDataClient(testAPIKey, server.URL+"/").GetQuote(context.Background(), "AAPL")
+			if err == nil {
+				t.Fatal("GetQuote succeeded on an error")
+			}
+			if errors.Is(err, ErrRateLimited) != test.rateLimited {
+				t.Errorf("error %v: rate limited %v, want %v", err, !test.rateLimited, test.rateLimited)
+			}
+			if !strings.Contains(err.Error(), test.errText) {
+				t.Errorf("error = %v, want %q", err, test.errText)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 type JSONRPCRequest struct {
 	JSONRPC string      `json:"jsonrpc"`
@@ -16,6 +19,25 @@ type JSONRPCResponse struct {
 	Error   *JSONRPCError `json:"error,omitempty"`
 }
 
+// JSONRPCMessage is any incoming JSON-RPC message before it is known whether
+// it is a request, a notification or a response.
+type JSONRPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+}
+
+func (m *JSONRPCMessage) IsResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+func (m *JSONRPCMessage) IsNotification() bool {
+	return m.Method != "" && len(m.ID) == 0
+}
+
 type JSONRPCError struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
@@ -26,6 +48,20 @@ type InitializeRequest struct {
 	ProtocolVersion string                 `json:"protocolVersion"`
 	Capabilities    ClientCapabilities     `json:"capabilities"`
 	ClientInfo      ClientInfo             `json:"clientInfo"`
+	Meta            *InitializeMeta        `json:"_meta,omitempty"`
+}
+
+// ShutdownNotification is sent with notifications/shutdown when the server
+// starts shutting down; requests still running have until Deadline.
+type ShutdownNotification struct {
+	Reason   string     `json:"reason"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// InitializeMeta carries the credentials of clients of network servers that
+// require authentication.
+type InitializeMeta struct {
+	Authorization string `json:"authorization,omitempty"`
 }
 
 type InitializeResponse struct {
@@ -76,14 +112,31 @@ type ListToolsResponse struct {
 }
 
 type Tool struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	InputSchema json.RawMessage `json:"inputSchema"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	InputSchema json.RawMessage  `json:"inputSchema"`
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are optional hints about how a tool behaves. Nil hints are
+// omitted so clients fall back to the protocol defaults.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
 }
 
 type CallToolRequest struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"`
+}
+
+// RequestMeta carries protocol-level metadata sent alongside request params.
+type RequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"`
 }
 
 type CallToolResponse struct {
@@ -91,15 +144,27 @@ type CallToolResponse struct {
 	IsError bool      `json:"isError,omitempty"`
 }
 
+// Content is a text block, or with Type "resource" an embedded resource.
 type Content struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	Resource *ResourceContents `json:"resource,omitempty"`
+}
+
+type CancelledNotification struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
 }
 
 type ProgressNotification struct {
 	ProgressToken interface{} `json:"progressToken"`
 	Progress      float64     `json:"progress"`
 	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+type SetLevelRequest struct {
+	Level string `json:"level"`
 }
 
 type LoggingMessageNotification struct {
@@ -107,3 +172,86 @@ type LoggingMessageNotification struct {
 	Data   interface{} `json:"data"`
 	Logger string      `json:"logger,omitempty"`
 }
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceTemplate describes a family of resources by an RFC 6570 URI
+// template such as stock://{symbol}/quote.
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ListResourcesResponse struct {
+	Resources []Resource `json:"resources"`
+}
+
+type ListResourceTemplatesResponse struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+type ReadResourceRequest struct {
+	URI string `json:"uri"`
+}
+
+type ReadResourceResponse struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// ResourceContents holds either Text or base64-encoded Blob data.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+type SubscribeRequest struct {
+	URI string `json:"uri"`
+}
+
+type UnsubscribeRequest struct {
+	URI string `json:"uri"`
+}
+
+type ResourceUpdatedNotification struct {
+	URI string `json:"uri"`
+}
+
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type ListPromptsResponse struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+type GetPromptRequest struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+type GetPromptResponse struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
@@ -71,9 +71,52 @@ type PriceDataPoint struct {
 	ChangePerc float64  `json:"changePerc"`
 }
 
+// PriceBar is one OHLCV bar as reported by the data provider.
+type PriceBar struct {
+	Date   time.Time `json:"date"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume int64     `json:"volume"`
+}
+
+// SymbolMatch is one result of a symbol search. Score is the provider's
+// relevance from 0 to 1 when it reports one.
+type SymbolMatch struct {
+	Symbol   string  `json:"symbol"`
+	Name     string  `json:"name"`
+	Type     string  `json:"type,omitempty"`
+	Region   string  `json:"region,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+	Score    float64 `json:"score,omitempty"`
+}
+
+// CompanyOverview is the company profile and key figures of a symbol. Fields
+// a provider does not report are left zero.
+type CompanyOverview struct {
+	Symbol        string  `json:"symbol"`
+	Name          string  `json:"name"`
+	Description   string  `json:"description,omitempty"`
+	Exchange      string  `json:"exchange,omitempty"`
+	Currency      string  `json:"currency,omitempty"`
+	Country       string  `json:"country,omitempty"`
+	Sector        string  `json:"sector,omitempty"`
+	Industry      string  `json:"industry,omitempty"`
+	MarketCap     int64   `json:"marketCap,omitempty"`
+	PERatio       float64 `json:"peRatio,omitempty"`
+	EPS           float64 `json:"eps,omitempty"`
+	DividendYield float64 `json:"dividendYield,omitempty"`
+	Beta          float64 `json:"beta,omitempty"`
+	Week52High    float64 `json:"week52High,omitempty"`
+	Week52Low     float64 `json:"week52Low,omitempty"`
+}
+
 type PriceHistory struct {
 	Symbol     string            `json:"symbol"`
 	Timeframe  string            `json:"timeframe"`
+	// Interval is the size of the bars in DataPoints, e.g. "5min" or "daily".
+	Interval   string            `json:"interval"`
 	DataPoints []PriceDataPoint  `json:"dataPoints"`
 	Trends     TrendAnalysis     `json:"trends"`
 	Patterns   []PatternMatch    `json:"patterns"`
@@ -177,37 +220,258 @@ type AlphaVantageQuote struct {
 
 type AlphaVantageTimeSeries struct {
 	MetaData   map[string]string `json:"Meta Data"`
-	TimeSeries map[string]struct {
-		Open   string `json:"1. open"`
-		High   string `json:"2. high"`
-		Low    string `json:"3. low"`
-		Close  string `json:"4. close"`
-		Volume string `json:"5. volume"`
-	} `json:"Time Series (Daily)"`
+	TimeSeries map[string]AlphaVantageBar `json:"Time Series (Daily)"`
+}
+
+type AlphaVantageBar struct {
+	Open   string `json:"1. open"`
+	High   string `json:"2. high"`
+	Low    string `json:"3. low"`
+	Close  string `json:"4. close"`
+	Volume string `json:"5. volume"`
+}
+
+type AlphaVantageSearch struct {
+	BestMatches []struct {
+		Symbol     string `json:"1. symbol"`
+		Name       string `json:"2. name"`
+		Type       string `json:"3. type"`
+		Region     string `json:"4. region"`
+		Currency   string `json:"8. currency"`
+		MatchScore string `json:"9. matchScore"`
+	} `json:"bestMatches"`
+}
+
+type AlphaVantageOverview struct {
+	Symbol               string `json:"Symbol"`
+	Name                 string `json:"Name"`
+	Description          string `json:"Description"`
+	Exchange             string `json:"Exchange"`
+	Currency             string `json:"Currency"`
+	Country              string `json:"Country"`
+	Sector               string `json:"Sector"`
+	Industry             string `json:"Industry"`
+	MarketCapitalization string `json:"MarketCapitalization"`
+	PERatio              string `json:"PERatio"`
+	EPS                  string `json:"EPS"`
+	DividendYield        string `json:"DividendYield"`
+	Beta                 string `json:"Beta"`
+	Week52High           string `json:"52WeekHigh"`
+	Week52Low            string `json:"52WeekLow"`
+}
+
+// TwelveDataStatus is the envelope Twelve Data reports errors in, usually
+// with HTTP status 200.
+type TwelveDataStatus struct {
+	Status  string `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type TwelveDataQuote struct {
+	Symbol        string `json:"symbol"`
+	Name          string `json:"name"`
+	Datetime      string `json:"datetime"`
+	Close         string `json:"close"`
+	Volume        string `json:"volume"`
+	Change        string `json:"change"`
+	PercentChange string `json:"percent_change"`
+}
+
+type TwelveDataTimeSeries struct {
+	Values []TwelveDataBar `json:"values"`
+}
+
+type TwelveDataBar struct {
+	Datetime string `json:"datetime"`
+	Open     string `json:"open"`
+	High     string `json:"high"`
+	Low      string `json:"low"`
+	Close    string `json:"close"`
+	Volume   string `json:"volume"`
+}
+
+type TwelveDataSearch struct {
+	Data []struct {
+		Symbol         string `json:"symbol"`
+		InstrumentName string `json:"instrument_name"`
+		Exchange       string `json:"exchange"`
+		InstrumentType string `json:"instrument_type"`
+		Country        string `json:"country"`
+		Currency       string `json:"currency"`
+	} `json:"data"`
+}
+
+type TwelveDataProfile struct {
+	Symbol      string `json:"symbol"`
+	Name        string `json:"name"`
+	Exchange    string `json:"exchange"`
+	Sector      string `json:"sector"`
+	Industry    string `json:"industry"`
+	Description string `json:"description"`
+	Country     string `json:"country"`
 }
 
 type Config struct {
-	Server ServerConfig `yaml:"server"`
-	APIs   APIConfig    `yaml:"apis"`
-	Claude ClaudeConfig `yaml:"claude"`
+	Server  ServerConfig  `yaml:"server"`
+	APIs    APIConfig     `yaml:"apis"`
+	Claude  ClaudeConfig  `yaml:"claude"`
+	OpenAI  OpenAIConfig  `yaml:"openai"`
+	Chatbot ChatbotConfig `yaml:"chatbot"`
+
+	MCPServers map[string]MCPServerConfig `yaml:"mcpServers"`
 }
 
 type ServerConfig struct {
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
+	// Transport is "stdio", "tcp", "tls" or "http"; the network transports
+	// listen on Host:Port, http serving Streamable HTTP at /mcp.
+	Transport     string        `yaml:"transport"`
+	WatchInterval time.Duration `yaml:"watchInterval"`
+	// ShutdownTimeout is how long in-flight calls may run after SIGINT or
+	// SIGTERM before they are cancelled.
+	ShutdownTimeout time.Duration    `yaml:"shutdownTimeout"`
+	TLS             ServerTLSConfig  `yaml:"tls"`
+	Auth            ServerAuthConfig `yaml:"auth"`
+	Limits          LimitsConfig     `yaml:"limits"`
+}
+
+// LimitsConfig bounds what network clients may use; zero disables a limit.
+// Clients are counted by token name, or by address without auth.
+type LimitsConfig struct {
+	MaxConnections    int                    `yaml:"maxConnections"`
+	RequestsPerMinute float64                `yaml:"requestsPerMinute"`
+	Burst             int                    `yaml:"burst"`
+	ToolQuotas        map[string]QuotaConfig `yaml:"toolQuotas"`
+}
+
+// QuotaConfig allows Calls calls of a tool per client in every Per.
+type QuotaConfig struct {
+	Calls int           `yaml:"calls"`
+	Per   time.Duration `yaml:"per"`
+}
+
+// ServerTLSConfig holds the certificate of a tls:// server. ClientCAFile
+// turns on client-certificate verification.
+type ServerTLSConfig struct {
+	CertFile     string `yaml:"certFile"`
+	KeyFile      string `yaml:"keyFile"`
+	ClientCAFile string `yaml:"clientCAFile"`
+}
+
+// ServerAuthConfig lists the bearer tokens network clients must present.
+// Without tokens the network transports accept anyone. AuditLog is a file
+// that authentication and tool call decisions are appended to.
+type ServerAuthConfig struct {
+	Tokens   []TokenConfig `yaml:"tokens"`
+	AuditLog string        `yaml:"auditLog"`
 }
 
+// TokenConfig grants the client holding Token the tools listed in Tools,
+// the resources matching Resources (URI patterns such as "stock://*/quote")
+// and the prompts listed in Prompts; "*" grants all of a kind.
+type TokenConfig struct {
+	Name      string   `yaml:"name"`
+	Token     string   `yaml:"token"`
+	Tools     []string `yaml:"tools"`
+	Resources []string `yaml:"resources"`
+	Prompts   []string `yaml:"prompts"`
+}
+
+// APIConfig configures the market data providers. Providers lists them in
+// the order they are tried: alphaVantage, twelveData and csv. When one fails
+// or is rate limited the next one answers. DataSource is "live" (default),
+// "record:DIR" or "replay:DIR" to save or serve HTTP responses as fixtures,
+// or "synthetic[:SEED]" for generated prices.
 type APIConfig struct {
+	DataSource   string             `yaml:"dataSource"`
+	Providers    []string           `yaml:"providers"`
 	AlphaVantage AlphaVantageConfig `yaml:"alphaVantage"`
+	TwelveData   TwelveDataConfig   `yaml:"twelveData"`
+	CSV          CSVConfig          `yaml:"csv"`
+	Cache        CacheConfig        `yaml:"cache"`
+}
+
+// CacheConfig controls the cache in front of the HTTP providers. Path is a
+// database file kept across restarts; empty keeps the cache in memory. Daily
+// bars stay fresh until the next market close, and expired entries are
+// served for MaxStale more while they are refetched in the background.
+type CacheConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Path        string        `yaml:"path"`
+	QuoteTTL    time.Duration `yaml:"quoteTTL"`
+	IntradayTTL time.Duration `yaml:"intradayTTL"`
+	SearchTTL   time.Duration `yaml:"searchTTL"`
+	OverviewTTL time.Duration `yaml:"overviewTTL"`
+	MaxStale    time.Duration `yaml:"maxStale"`
 }
 
+// AlphaVantageConfig paces requests to RequestsPerMinute with bursts of
+// Burst; throttled requests are retried MaxRetries times, backing off from
+// RetryBackoff.
 type AlphaVantageConfig struct {
+	APIKey            string        `yaml:"apiKey"`
+	BaseURL           string        `yaml:"baseURL"`
+	RequestsPerMinute float64       `yaml:"requestsPerMinute"`
+	Burst             int           `yaml:"burst"`
+	MaxRetries        int           `yaml:"maxRetries"`
+	RetryBackoff      time.Duration `yaml:"retryBackoff"`
+}
+
+type TwelveDataConfig struct {
 	APIKey  string `yaml:"apiKey"`
 	BaseURL string `yaml:"baseURL"`
 }
 
+// CSVConfig points the csv provider at a directory of SYMBOL.csv files.
+type CSVConfig struct {
+	Dir string `yaml:"dir"`
+}
+
 type ClaudeConfig struct {
 	APIKey  string `yaml:"apiKey"`
 	BaseURL string `yaml:"baseURL"`
 	Model   string `yaml:"model"`
 }
+
+type OpenAIConfig struct {
+	APIKey  string `yaml:"apiKey"`
+	BaseURL string `yaml:"baseURL"`
+	Model   string `yaml:"model"`
+}
+
+type ChatbotConfig struct {
+	Provider     string   `yaml:"provider"`
+	MockScript   string   `yaml:"mockScript"`
+	MaxTokens    int      `yaml:"maxTokens"`
+	Temperature  *float64 `yaml:"temperature"`
+	SystemPrompt string   `yaml:"systemPrompt"`
+}
+
+// MCPServerConfig is one entry of the mcpServers registry. Stdio servers are
+// launched from Command; tcp, tls, http and websocket servers are reached at
+// URL.
+type MCPServerConfig struct {
+	Name        string            `yaml:"-"`
+	Command     string            `yaml:"command"`
+	Args        []string          `yaml:"args"`
+	Env         map[string]string `yaml:"env"`
+	Cwd         string            `yaml:"cwd"`
+	Transport   string            `yaml:"transport"`
+	URL         string            `yaml:"url"`
+	AutoConnect bool              `yaml:"autoConnect"`
+	TLS         ClientTLSConfig   `yaml:"tls"`
+	// Token is the bearer token presented to servers requiring one.
+	Token string `yaml:"token"`
+}
+
+// ClientTLSConfig configures tls:// entries. Pins are SHA-256 certificate
+// fingerprints; without CAFile they replace CA verification.
+type ClientTLSConfig struct {
+	CAFile     string   `yaml:"caFile"`
+	CertFile   string   `yaml:"certFile"`
+	KeyFile    string   `yaml:"keyFile"`
+	ServerName string   `yaml:"serverName"`
+	Pins       []string `yaml:"pins"`
+}
@@ -5,8 +5,8 @@
 
 cd "$(dirname "$0")/.."
 
-# Agregar uv al PATH
-export PATH="/home/Japo/.local/bin:$PATH"
+# Agregar uv al PATH (instalado por defecto en ~/.local/bin)
+export PATH="${UV_BIN_DIR:-$HOME/.local/bin}:$PATH"
 
 # Lanzar el servidor MCP Git con acceso al repositorio actual
 # Redirect echo output to stderr to avoid interfering with MCP protocol
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"proyecto-mcp-bolsa/internal/config"
 	"proyecto-mcp-bolsa/internal/mcp"
 	"proyecto-mcp-bolsa/internal/stock"
 	"proyecto-mcp-bolsa/pkg/models"
@@ -16,171 +25,445 @@ import (
 
 type StockAnalyzerServer struct {
 	server           *mcp.Server
+	marketData       stock.MarketDataProvider
 	analyzer         *stock.Analyzer
 	enhancedAnalyzer *stock.EnhancedAnalyzer
-}
 
-func NewStockAnalyzerServer() *StockAnalyzerServer {
-	apiKey := os.Getenv("ALPHA_VANTAGE_API_KEY")
-	if apiKey == "" {
-		apiKey = "demo"
-		log.Println("No API key set - Set ALPHA_VANTAGE_API_KEY for real data")
-	} else if apiKey == "demo" {
-		log.Println("Using demo API key - Get free API key at https://www.alphavantage.co/support/#api-key")
-	} else {
-		log.Printf("Using Alpha Vantage API key: %s...%s", apiKey[:4], apiKey[len(apiKey)-4:])
-	}
+	// dataNotice heads reports that are not based on live market data.
+	dataNotice string
+	// cache is nil when market data is not cached.
+	cache *stock.Cache
+}
 
-	apiClient := stock.NewAPIClient(apiKey, "https://www.alphavantage.co/query")
-	analyzer := stock.NewAnalyzer(apiClient)
-	enhancedAnalyzer := stock.NewEnhancedAnalyzer(apiClient)
+func NewStockAnalyzerServer(cfg *models.Config) *StockAnalyzerServer {
+	cache := newCache(cfg.APIs)
+	marketData, dataNotice := newMarketData(cfg.APIs, cache)
+	analyzer := stock.NewAnalyzer(marketData)
+	enhancedAnalyzer := stock.NewEnhancedAnalyzer(marketData)
 	
 	server := mcp.NewServer("Stock Analyzer MCP Server", "2.0.0")
 	
 	sas := &StockAnalyzerServer{
 		server:           server,
+		marketData:       marketData,
 		analyzer:         analyzer,
 		enhancedAnalyzer: enhancedAnalyzer,
+		dataNotice:       dataNotice,
+		cache:            cache,
+	}
+	if cache != nil {
+		server.RegisterOnShutdown(cache.Close)
 	}
 
 	sas.registerTools()
+	sas.registerResources()
+	sas.registerPrompts()
 	
 	return sas
 }
 
-func (s *StockAnalyzerServer) registerTools() {
-	s.server.RegisterTool("analyze_stock_with_reliability", "Advanced stock analysis with reliability percentage and price predictions", nil, mcp.ToolHandlerFunc(s.handleAnalyzeStockWithReliability))
-	
-	s.server.RegisterTool("analyze_portfolio_advanced", "Advanced portfolio analysis with reliability metrics and risk assessment", nil, mcp.ToolHandlerFunc(s.handleAnalyzePortfolioAdvanced))
-	
-	s.server.RegisterTool("get_price_prediction", "Get price predictions with confidence intervals and timeframes", nil, mcp.ToolHandlerFunc(s.handleGetPricePrediction))
-	
-	s.server.RegisterTool("analyze_historical_trends", "Analyze historical price trends and patterns", nil, mcp.ToolHandlerFunc(s.handleAnalyzeHistoricalTrends))
-	
-	s.server.RegisterTool("analyze_portfolio", "Basic portfolio analysis (legacy)", nil, mcp.ToolHandlerFunc(s.handleAnalyzePortfolio))
-	
-	s.server.RegisterTool("get_stock_price", "Basic stock price information (legacy)", nil, mcp.ToolHandlerFunc(s.handleGetStockPrice))
-	
-	s.server.RegisterTool("export_analysis", "Export analysis results to CSV or JSON format", nil, mcp.ToolHandlerFunc(s.handleExportAnalysis))
-}
+// newCache opens the market data cache, or returns nil when it is disabled
+// or the data does not come live from the APIs. A cache file held by another
+// server falls back to memory.
+func newCache(apis models.APIConfig) *stock.Cache {
+	source, _ := config.ParseDataSource(apis.DataSource)
+	if !apis.Cache.Enabled || source.Mode != "live" {
+		return nil
+	}
 
-func (s *StockAnalyzerServer) handleAnalyzePortfolio(args map[string]interface{}) (*models.CallToolResponse, error) {
-	symbolsInterface, ok := args["symbols"]
-	if !ok {
-		return nil, fmt.Errorf("symbols parameter is required")
+	var store stock.CacheStore = stock.NewMemoryStore()
+	if apis.Cache.Path != "" {
+		db, err := stock.OpenBoltStore(apis.Cache.Path)
+		if err != nil {
+			log.Printf("Warning: %v; caching market data in memory only", err)
+		} else {
+			store = db
+		}
 	}
 
-	symbolsSlice, ok := symbolsInterface.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("symbols must be an array")
+	cache, err := stock.NewCache(store, stock.CacheTTLs{
+		Quote:    apis.Cache.QuoteTTL,
+		Intraday: apis.Cache.IntradayTTL,
+		Search:   apis.Cache.SearchTTL,
+		Overview: apis.Cache.OverviewTTL,
+		MaxStale: apis.Cache.MaxStale,
+	})
+	if err != nil {
+		log.Printf("Warning: %v; market data is not cached", err)
+		store.Close()
+		return nil
 	}
+	if apis.Cache.Path != "" {
+		log.Printf("Caching market data in %s", apis.Cache.Path)
+	}
+	return cache
+}
 
-	symbols := make([]string, len(symbolsSlice))
-	for i, sym := range symbolsSlice {
-		symbol, ok := sym.(string)
-		if !ok {
-			return nil, fmt.Errorf("all symbols must be strings")
+// newMarketData builds the configured providers in fallback order, with
+// their HTTP traffic recorded or replayed when apis.dataSource asks for it.
+// HTTP providers without an API key are left out, except when replaying,
+// and answer through cache when it is not nil.
+// The returned notice heads reports that do not show live market data.
+func newMarketData(apis models.APIConfig, cache *stock.Cache) (stock.MarketDataProvider, string) {
+	// Validate has already rejected malformed data sources.
+	source, _ := config.ParseDataSource(apis.DataSource)
+	if source.Mode == "synthetic" {
+		log.Printf("Using synthetic market data (seed %d)", source.Seed)
+		return stock.NewSyntheticProvider(source.Seed), fmt.Sprintf("SYNTHETIC DATA - random walk with seed %d, not market prices\n", source.Seed)
+	}
+
+	var transport http.RoundTripper
+	notice := ""
+	switch source.Mode {
+	case "record":
+		log.Printf("Recording market data responses to %s", source.Dir)
+		transport = stock.NewFixtureRecorder(source.Dir, nil)
+	case "replay":
+		log.Printf("Replaying market data responses from %s", source.Dir)
+		transport = stock.NewFixtureReplayer(source.Dir)
+		notice = fmt.Sprintf("RECORDED DATA - replayed from %s\n", source.Dir)
+	}
+
+	var providers []stock.MarketDataProvider
+	addHTTP := func(provider stock.MarketDataProvider) {
+		if cache != nil {
+			provider = cache.Wrap(provider)
+		}
+		providers = append(providers, provider)
+	}
+	for _, name := range apis.Providers {
+		switch name {
+		case "alphaVantage":
+			apiKey := apis.AlphaVantage.APIKey
+			if source.Mode == "replay" && apiKey == "" {
+				// Fixtures are stored without the key, so any key replays them.
+				apiKey = "replay"
+			}
+			if apiKey == "" {
+				log.Println("No API key set - Set ALPHA_VANTAGE_API_KEY for real data")
+				continue
+			} else if apiKey == "demo" {
+				log.Println("Using demo API key - Get free API key at https://www.alphavantage.co/support/#api-key")
+				continue
+			} else if len(apiKey) > 8 {
+				log.Printf("Using Alpha Vantage API key: %s...%s", apiKey[:4], apiKey[len(apiKey)-4:])
+			}
+			client := stock.NewAPIClient(apiKey, apis.AlphaVantage.BaseURL)
+			if transport != nil {
+				client.SetTransport(transport)
+			}
+			if source.Mode != "replay" {
+				client.SetRequestLimits(stock.RequestLimits{
+					RequestsPerMinute: apis.AlphaVantage.RequestsPerMinute,
+					Burst:             apis.AlphaVantage.Burst,
+					MaxRetries:        apis.AlphaVantage.MaxRetries,
+					RetryBackoff:      apis.AlphaVantage.RetryBackoff,
+				})
+			}
+			addHTTP(client)
+		case "twelveData":
+			apiKey := apis.TwelveData.APIKey
+			if source.Mode == "replay" && apiKey == "" {
+				apiKey = "replay"
+			}
+			if apiKey == "" {
+				log.Println("No Twelve Data API key set - Set TWELVE_DATA_API_KEY to use it")
+				continue
+			}
+			client := stock.NewTwelveDataClient(apiKey, apis.TwelveData.BaseURL)
+			if transport != nil {
+				client.SetTransport(transport)
+			}
+			addHTTP(client)
+		case "csv":
+			log.Printf("Reading market data from CSV files in %s", apis.CSV.Dir)
+			providers = append(providers, stock.NewCSVProvider(apis.CSV.Dir))
 		}
-		symbols[i] = strings.ToUpper(symbol)
 	}
 
-	timeframe := "1M"
-	if tf, exists := args["timeframe"]; exists {
-		if tfStr, ok := tf.(string); ok {
-			timeframe = tfStr
-		}
+	switch len(providers) {
+	case 0:
+		return stock.NewAPIClient("demo", apis.AlphaVantage.BaseURL), "DEMO DATA - Set ALPHA_VANTAGE_API_KEY for real-time data\n"
+	case 1:
+		return providers[0], notice
 	}
+	fallback := stock.NewFallbackProvider(providers...)
+	log.Printf("Market data providers: %s", fallback.Name())
+	return fallback, notice
+}
 
-	analysis, err := s.analyzer.AnalyzePortfolio(symbols, timeframe)
-	if err != nil {
-		return &models.CallToolResponse{
-			Content: []models.Content{
-				{Type: "text", Text: fmt.Sprintf("Error analyzing portfolio: %v", err)},
-			},
-			IsError: true,
-		}, nil
+// TimeframeInput is the timeframe argument shared by the analysis tools.
+type TimeframeInput struct {
+	Timeframe string `json:"timeframe" enum:"1D,5D,1M,3M,6M,1Y,5Y,10Y" default:"1M" description:"Timeframe for analysis: 1D and 5D use 5 and 15 minute bars, 1M to 1Y daily bars, 5Y weekly and 10Y monthly bars"`
+}
+
+// SymbolInput is the input of the single-stock analysis tools.
+type SymbolInput struct {
+	Symbol string `json:"symbol" required:"true" description:"Stock symbol to analyze (e.g. AAPL)"`
+	TimeframeInput
+}
+
+// TrendsInput is like SymbolInput but looks further back by default.
+type TrendsInput struct {
+	Symbol         string `json:"symbol" required:"true" description:"Stock symbol to analyze (e.g. AAPL)"`
+	TimeframeInput `default:"3M"`
+}
+
+type PortfolioInput struct {
+	Symbols []string `json:"symbols" required:"true" minItems:"1" description:"Array of stock symbols to analyze"`
+	TimeframeInput
+}
+
+type StockPriceInput struct {
+	Symbol string `json:"symbol" required:"true" description:"Stock symbol to get price for"`
+}
+
+type ExportInput struct {
+	Format   string `json:"format" enum:"csv,json" default:"json" description:"Export format"`
+	Filename string `json:"filename" required:"true" description:"Output filename"`
+}
+
+type SearchInput struct {
+	Keywords string `json:"keywords" required:"true" description:"Company name or symbol fragment to look up (e.g. micro)"`
+}
+
+type OverviewInput struct {
+	Symbol string `json:"symbol" required:"true" description:"Stock symbol to describe (e.g. MSFT)"`
+}
+
+// DiagnosticsInput takes no arguments.
+type DiagnosticsInput struct{}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+// readOnlyMarketTool describes the analysis tools: they only read market data
+// from an external API.
+func readOnlyMarketTool(title string) *models.ToolAnnotations {
+	return &models.ToolAnnotations{
+		Title:         title,
+		ReadOnlyHint:  boolPtr(true),
+		OpenWorldHint: boolPtr(true),
 	}
+}
 
-	response := s.formatPortfolioAnalysis(analysis)
-	
-	return &models.CallToolResponse{
-		Content: []models.Content{
-			{Type: "text", Text: response},
-		},
-	}, nil
+func (s *StockAnalyzerServer) registerTools() {
+	mcp.RegisterTypedTool(s.server, "analyze_stock_with_reliability", "Advanced stock analysis with reliability percentage and price predictions", readOnlyMarketTool("Analyze stock with reliability"), s.handleAnalyzeStockWithReliability)
+
+	mcp.RegisterTypedTool(s.server, "analyze_portfolio_advanced", "Advanced portfolio analysis with reliability metrics and risk assessment", readOnlyMarketTool("Analyze portfolio (advanced)"), s.handleAnalyzePortfolioAdvanced)
+
+	mcp.RegisterTypedTool(s.server, "get_price_prediction", "Get price predictions with confidence intervals and timeframes", readOnlyMarketTool("Price prediction"), s.handleGetPricePrediction)
+
+	mcp.RegisterTypedTool(s.server, "analyze_historical_trends", "Analyze historical price trends and patterns", readOnlyMarketTool("Historical trends"), s.handleAnalyzeHistoricalTrends)
+
+	mcp.RegisterTypedTool(s.server, "analyze_portfolio", "Basic portfolio analysis (legacy)", readOnlyMarketTool("Analyze portfolio"), s.handleAnalyzePortfolio)
+
+	mcp.RegisterTypedTool(s.server, "get_stock_price", "Basic stock price information (legacy)", readOnlyMarketTool("Stock price"), s.handleGetStockPrice)
+
+	mcp.RegisterTypedTool(s.server, "search_symbols", "Find stock symbols matching a company name or symbol fragment", readOnlyMarketTool("Search symbols"), s.handleSearchSymbols)
+
+	mcp.RegisterTypedTool(s.server, "get_company_overview", "Company profile and key figures: sector, market cap, P/E, EPS, dividend yield, beta", readOnlyMarketTool("Company overview"), s.handleCompanyOverview)
+
+	mcp.RegisterTypedTool(s.server, "export_analysis", "Export analysis results to CSV or JSON format", &models.ToolAnnotations{
+		Title:           "Export analysis",
+		ReadOnlyHint:    boolPtr(false),
+		DestructiveHint: boolPtr(false),
+		OpenWorldHint:   boolPtr(false),
+	}, s.handleExportAnalysis)
+
+	mcp.RegisterTypedTool(s.server, "get_server_diagnostics", "Show connections, rate limits and per-client usage of this server", &models.ToolAnnotations{
+		Title:         "Server diagnostics",
+		ReadOnlyHint:  boolPtr(true),
+		OpenWorldHint: boolPtr(false),
+	}, s.handleServerDiagnostics)
 }
 
-func (s *StockAnalyzerServer) handleGetStockPrice(args map[string]interface{}) (*models.CallToolResponse, error) {
-	symbolInterface, ok := args["symbol"]
-	if !ok {
-		return nil, fmt.Errorf("symbol parameter is required")
+func upperSymbols(symbols []string) []string {
+	upper := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		upper[i] = strings.ToUpper(strings.TrimSpace(symbol))
 	}
+	return upper
+}
 
-	symbol, ok := symbolInterface.(string)
-	if !ok {
-		return nil, fmt.Errorf("symbol must be a string")
+func (s *StockAnalyzerServer) handleAnalyzePortfolio(ctx context.Context, in PortfolioInput) (string, error) {
+	analysis, err := s.analyzer.AnalyzePortfolio(ctx, upperSymbols(in.Symbols), in.Timeframe)
+	if err != nil {
+		return "", fmt.Errorf("Error analyzing portfolio: %v", err)
 	}
 
-	symbol = strings.ToUpper(symbol)
+	return s.formatPortfolioAnalysis(analysis), nil
+}
+
+func (s *StockAnalyzerServer) handleGetStockPrice(ctx context.Context, in StockPriceInput) (string, error) {
+	symbol := strings.ToUpper(in.Symbol)
 
-	stock, err := s.analyzer.AnalyzeStock(symbol, "1D")
+	// Daily bars, so that the indicators next to the price are the usual ones.
+	stock, err := s.analyzer.AnalyzeStock(ctx, symbol, "1M")
 	if err != nil {
-		return &models.CallToolResponse{
-			Content: []models.Content{
-				{Type: "text", Text: fmt.Sprintf("Error getting stock price for %s: %v", symbol, err)},
-			},
-			IsError: true,
-		}, nil
+		return "", fmt.Errorf("Error getting stock price for %s: %v", symbol, err)
 	}
 
-	response := s.formatStockAnalysis(stock)
-	
-	return &models.CallToolResponse{
-		Content: []models.Content{
-			{Type: "text", Text: response},
-		},
-	}, nil
+	return s.formatStockAnalysis(stock), nil
 }
 
-func (s *StockAnalyzerServer) handleExportAnalysis(args map[string]interface{}) (*models.CallToolResponse, error) {
-	
-	format := "json"
-	if f, exists := args["format"]; exists {
-		if fStr, ok := f.(string); ok {
-			format = strings.ToLower(fStr)
+func (s *StockAnalyzerServer) handleSearchSymbols(ctx context.Context, in SearchInput) (string, error) {
+	matches, err := s.marketData.SearchSymbols(ctx, strings.TrimSpace(in.Keywords))
+	if err != nil {
+		return "", fmt.Errorf("Error searching symbols for %q: %v", in.Keywords, err)
+	}
+	if len(matches) == 0 {
+		return fmt.Sprintf("No symbols match %q", in.Keywords), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("SYMBOLS MATCHING %q\n", in.Keywords))
+	sb.WriteString("=" + strings.Repeat("=", 30) + "\n")
+	for _, match := range matches {
+		line := fmt.Sprintf("%-10s %s", match.Symbol, match.Name)
+		details := make([]string, 0, 3)
+		for _, detail := range []string{match.Type, match.Region, match.Currency} {
+			if detail != "" {
+				details = append(details, detail)
+			}
+		}
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String(), nil
+}
+
+func (s *StockAnalyzerServer) handleCompanyOverview(ctx context.Context, in OverviewInput) (string, error) {
+	symbol := strings.ToUpper(in.Symbol)
+
+	overview, err := s.marketData.GetCompanyOverview(ctx, symbol)
+	if err != nil {
+		return "", fmt.Errorf("Error getting overview for %s: %v", symbol, err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("COMPANY OVERVIEW: %s\n", overview.Symbol))
+	sb.WriteString("=" + strings.Repeat("=", 30) + "\n")
+	sb.WriteString(fmt.Sprintf("Name: %s\n", overview.Name))
+	for _, field := range []struct{ label, value string }{
+		{"Exchange", overview.Exchange},
+		{"Country", overview.Country},
+		{"Currency", overview.Currency},
+		{"Sector", overview.Sector},
+		{"Industry", overview.Industry},
+	} {
+		if field.value != "" {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", field.label, field.value))
 		}
 	}
 
-	filenameInterface, ok := args["filename"]
-	if !ok {
-		return nil, fmt.Errorf("filename parameter is required")
+	if overview.MarketCap > 0 {
+		sb.WriteString(fmt.Sprintf("\nMarket Cap: $%.2fB\n", float64(overview.MarketCap)/1e9))
+	}
+	if overview.PERatio != 0 {
+		sb.WriteString(fmt.Sprintf("P/E Ratio: %.2f\n", overview.PERatio))
+	}
+	if overview.EPS != 0 {
+		sb.WriteString(fmt.Sprintf("EPS: %.2f\n", overview.EPS))
+	}
+	if overview.DividendYield != 0 {
+		sb.WriteString(fmt.Sprintf("Dividend Yield: %.2f%%\n", overview.DividendYield*100))
+	}
+	if overview.Beta != 0 {
+		sb.WriteString(fmt.Sprintf("Beta: %.2f\n", overview.Beta))
+	}
+	if overview.Week52High != 0 {
+		sb.WriteString(fmt.Sprintf("52-Week Range: $%.2f - $%.2f\n", overview.Week52Low, overview.Week52High))
 	}
 
-	filename, ok := filenameInterface.(string)
-	if !ok {
-		return nil, fmt.Errorf("filename must be a string")
+	if overview.Description != "" {
+		sb.WriteString("\n" + overview.Description + "\n")
 	}
+	return sb.String(), nil
+}
+
+func (s *StockAnalyzerServer) handleServerDiagnostics(ctx context.Context, in DiagnosticsInput) (string, error) {
+	usage := s.server.Usage()
+	limits := usage.Limits
+
+	var sb strings.Builder
+	sb.WriteString("SERVER DIAGNOSTICS\n")
+	sb.WriteString("=" + strings.Repeat("=", 40) + "\n")
+	sb.WriteString(fmt.Sprintf("Market data: %s\n", s.marketData.Name()))
+	if s.cache != nil {
+		stats := s.cache.Stats()
+		sb.WriteString(fmt.Sprintf("Cache: %d hits, %d stale, %d misses, %d refresh errors, %d store errors\n", stats.Hits, stats.StaleHits, stats.Misses, stats.RefreshErrors, stats.StoreErrors))
+	} else {
+		sb.WriteString("Cache: off\n")
+	}
+
+	if limits.MaxConnections > 0 {
+		sb.WriteString(fmt.Sprintf("Connections: %d/%d\n", usage.Connections, limits.MaxConnections))
+	} else {
+		sb.WriteString(fmt.Sprintf("Connections: %d (no limit)\n", usage.Connections))
+	}
+	if limits.RequestsPerMinute > 0 {
+		sb.WriteString(fmt.Sprintf("Request rate: %g/min per client\n", limits.RequestsPerMinute))
+	} else {
+		sb.WriteString("Request rate: no limit\n")
+	}
+	for _, tool := range sortedKeys(limits.ToolQuotas) {
+		quota := limits.ToolQuotas[tool]
+		sb.WriteString(fmt.Sprintf("Quota %s: %d calls per %s\n", tool, quota.Calls, quota.Per))
+	}
+
+	if len(usage.Clients) == 0 {
+		sb.WriteString("\nNo client activity recorded (limits apply to network transports only)\n")
+		return sb.String(), nil
+	}
+
+	sb.WriteString("\nCLIENTS:\n")
+	sb.WriteString("-" + strings.Repeat("-", 30) + "\n")
+	for _, client := range usage.Clients {
+		sb.WriteString(fmt.Sprintf("\n%s (last seen %s ago)\n", client.Client, time.Since(client.LastSeen).Round(time.Second)))
+		sb.WriteString(fmt.Sprintf("  Requests: %d, rate limited: %d\n", client.Requests, client.RateLimited))
+		if limits.RequestsPerMinute > 0 {
+			sb.WriteString(fmt.Sprintf("  Requests available now: %.0f\n", client.Tokens))
+		}
+		for _, tool := range sortedKeys(client.ToolCalls) {
+			line := fmt.Sprintf("  %s: %d calls", tool, client.ToolCalls[tool])
+			if tokens, limited := client.ToolTokens[tool]; limited {
+				line += fmt.Sprintf(" (%.0f left in quota)", tokens)
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *StockAnalyzerServer) handleExportAnalysis(ctx context.Context, in ExportInput) (string, error) {
+	format := strings.ToLower(in.Format)
 
 	sampleData := map[string]interface{}{
 		"exported_at": time.Now().Format(time.RFC3339),
 		"format":     format,
-		"filename":   filename,
+		"filename":   in.Filename,
 		"message":    "Export functionality implemented - would save analysis results to specified file",
 	}
 
-	var response string
 	if format == "csv" {
-		response = "CSV export functionality implemented. Would save analysis data as comma-separated values."
-	} else {
-		jsonData, _ := json.MarshalIndent(sampleData, "", "  ")
-		response = fmt.Sprintf("JSON export prepared:\n%s", string(jsonData))
+		return "CSV export functionality implemented. Would save analysis data as comma-separated values.", nil
 	}
-	
-	return &models.CallToolResponse{
-		Content: []models.Content{
-			{Type: "text", Text: response},
-		},
-	}, nil
+
+	jsonData, _ := json.MarshalIndent(sampleData, "", "  ")
+	return fmt.Sprintf("JSON export prepared:\n%s", string(jsonData)), nil
 }
 
 func (s *StockAnalyzerServer) formatPortfolioAnalysis(analysis *models.PortfolioAnalysis) string {
@@ -189,10 +472,7 @@ func (s *StockAnalyzerServer) formatPortfolioAnalysis(analysis *models.Portfolio
 	sb.WriteString("PORTFOLIO ANALYSIS REPORT\n")
 	sb.WriteString("=" + strings.Repeat("=", 40) + "\n")
 	
-	apiKey := os.Getenv("ALPHA_VANTAGE_API_KEY")
-	if apiKey == "" || apiKey == "demo" {
-		sb.WriteString("DEMO DATA - Set ALPHA_VANTAGE_API_KEY for real-time data\n")
-	}
+	sb.WriteString(s.dataNotice)
 	sb.WriteString("\n")
 	
 	sb.WriteString(fmt.Sprintf("Portfolio: %s\n", analysis.Portfolio.Name))
@@ -247,10 +527,7 @@ func (s *StockAnalyzerServer) formatStockAnalysis(analysis *models.StockAnalysis
 	sb.WriteString(fmt.Sprintf("STOCK ANALYSIS: %s\n", analysis.Stock.Symbol))
 	sb.WriteString("=" + strings.Repeat("=", 30) + "\n")
 	
-	apiKey := os.Getenv("ALPHA_VANTAGE_API_KEY")
-	if apiKey == "" || apiKey == "demo" {
-		sb.WriteString("DEMO DATA - Set ALPHA_VANTAGE_API_KEY for real-time data\n")
-	}
+	sb.WriteString(s.dataNotice)
 	sb.WriteString("\n")
 	
 	sb.WriteString(fmt.Sprintf("Current Price: $%.2f\n", analysis.Stock.Price))
@@ -295,175 +572,66 @@ func (s *StockAnalyzerServer) formatStockAnalysis(analysis *models.StockAnalysis
 }
 
 
-func (s *StockAnalyzerServer) handleAnalyzeStockWithReliability(args map[string]interface{}) (*models.CallToolResponse, error) {
-	symbolInterface, ok := args["symbol"]
-	if !ok {
-		return nil, fmt.Errorf("symbol parameter is required")
-	}
+func (s *StockAnalyzerServer) handleAnalyzeStockWithReliability(ctx context.Context, in SymbolInput) (string, error) {
+	symbol := strings.ToUpper(in.Symbol)
 
-	symbol, ok := symbolInterface.(string)
-	if !ok {
-		return nil, fmt.Errorf("symbol must be a string")
-	}
-
-	symbol = strings.ToUpper(symbol)
-
-	timeframe := "1M"
-	if tf, exists := args["timeframe"]; exists {
-		if tfStr, ok := tf.(string); ok {
-			timeframe = tfStr
-		}
-	}
-
-	analysis, err := s.enhancedAnalyzer.AnalyzeStockWithReliability(symbol, timeframe)
+	analysis, err := s.enhancedAnalyzer.AnalyzeStockWithReliability(ctx, symbol, in.Timeframe)
 	if err != nil {
-		return &models.CallToolResponse{
-			Content: []models.Content{
-				{Type: "text", Text: fmt.Sprintf("Error analyzing %s: %v", symbol, err)},
-			},
-			IsError: true,
-		}, nil
+		s.server.LogContext(ctx, mcp.LevelError, "Stock analysis failed", map[string]interface{}{"symbol": symbol, "error": err.Error()})
+		return "", fmt.Errorf("Error analyzing %s: %v", symbol, err)
 	}
 
-	response := s.formatEnhancedStockAnalysis(analysis)
-	
-	return &models.CallToolResponse{
-		Content: []models.Content{
-			{Type: "text", Text: response},
-		},
-	}, nil
+	return s.formatEnhancedStockAnalysis(analysis), nil
 }
 
-func (s *StockAnalyzerServer) handleAnalyzePortfolioAdvanced(args map[string]interface{}) (*models.CallToolResponse, error) {
-	symbolsInterface, ok := args["symbols"]
-	if !ok {
-		return nil, fmt.Errorf("symbols parameter is required")
-	}
-
-	symbolsSlice, ok := symbolsInterface.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("symbols must be an array")
-	}
-
-	symbols := make([]string, len(symbolsSlice))
-	for i, sym := range symbolsSlice {
-		symbol, ok := sym.(string)
-		if !ok {
-			return nil, fmt.Errorf("all symbols must be strings")
-		}
-		symbols[i] = strings.ToUpper(symbol)
-	}
+func (s *StockAnalyzerServer) handleAnalyzePortfolioAdvanced(ctx context.Context, in PortfolioInput) (string, error) {
+	symbols := upperSymbols(in.Symbols)
 
-	timeframe := "1M"
-	if tf, exists := args["timeframe"]; exists {
-		if tfStr, ok := tf.(string); ok {
-			timeframe = tfStr
+	analyses := make([]*models.StockAnalysis, 0, len(symbols))
+	for i, symbol := range symbols {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
 		}
-	}
 
-	analyses := make([]*models.StockAnalysis, 0, len(symbols))
-	for _, symbol := range symbols {
-		analysis, err := s.enhancedAnalyzer.AnalyzeStockWithReliability(symbol, timeframe)
+		analysis, err := s.enhancedAnalyzer.AnalyzeStockWithReliability(ctx, symbol, in.Timeframe)
 		if err != nil {
+			s.server.LogContext(ctx, mcp.LevelWarning, "Skipping symbol in portfolio analysis", map[string]interface{}{"symbol": symbol, "error": err.Error()})
+			mcp.ReportProgress(ctx, float64(i+1), float64(len(symbols)), fmt.Sprintf("%s failed: %v", symbol, err))
 			continue
 		}
 		analyses = append(analyses, analysis)
+		mcp.ReportProgress(ctx, float64(i+1), float64(len(symbols)), fmt.Sprintf("Analyzed %s", symbol))
 	}
 
 	if len(analyses) == 0 {
-		return &models.CallToolResponse{
-			Content: []models.Content{
-				{Type: "text", Text: "No valid stock analyses could be completed"},
-			},
-			IsError: true,
-		}, nil
+		return "", fmt.Errorf("No valid stock analyses could be completed")
 	}
 
-	response := s.formatEnhancedPortfolioAnalysis(analyses)
-	
-	return &models.CallToolResponse{
-		Content: []models.Content{
-			{Type: "text", Text: response},
-		},
-	}, nil
+	return s.formatEnhancedPortfolioAnalysis(analyses), nil
 }
 
-func (s *StockAnalyzerServer) handleGetPricePrediction(args map[string]interface{}) (*models.CallToolResponse, error) {
-	symbolInterface, ok := args["symbol"]
-	if !ok {
-		return nil, fmt.Errorf("symbol parameter is required")
-	}
-
-	symbol, ok := symbolInterface.(string)
-	if !ok {
-		return nil, fmt.Errorf("symbol must be a string")
-	}
-
-	symbol = strings.ToUpper(symbol)
-
-	timeframe := "1M"
-	if tf, exists := args["timeframe"]; exists {
-		if tfStr, ok := tf.(string); ok {
-			timeframe = tfStr
-		}
-	}
+func (s *StockAnalyzerServer) handleGetPricePrediction(ctx context.Context, in SymbolInput) (string, error) {
+	symbol := strings.ToUpper(in.Symbol)
 
-	analysis, err := s.enhancedAnalyzer.AnalyzeStockWithReliability(symbol, timeframe)
+	analysis, err := s.enhancedAnalyzer.AnalyzeStockWithReliability(ctx, symbol, in.Timeframe)
 	if err != nil {
-		return &models.CallToolResponse{
-			Content: []models.Content{
-				{Type: "text", Text: fmt.Sprintf("Error getting predictions for %s: %v", symbol, err)},
-			},
-			IsError: true,
-		}, nil
+		return "", fmt.Errorf("Error getting predictions for %s: %v", symbol, err)
 	}
 
-	response := s.formatPricePrediction(analysis)
-	
-	return &models.CallToolResponse{
-		Content: []models.Content{
-			{Type: "text", Text: response},
-		},
-	}, nil
+	return s.formatPricePrediction(analysis), nil
 }
 
-func (s *StockAnalyzerServer) handleAnalyzeHistoricalTrends(args map[string]interface{}) (*models.CallToolResponse, error) {
-	symbolInterface, ok := args["symbol"]
-	if !ok {
-		return nil, fmt.Errorf("symbol parameter is required")
-	}
+func (s *StockAnalyzerServer) handleAnalyzeHistoricalTrends(ctx context.Context, in TrendsInput) (string, error) {
+	symbol := strings.ToUpper(in.Symbol)
 
-	symbol, ok := symbolInterface.(string)
-	if !ok {
-		return nil, fmt.Errorf("symbol must be a string")
-	}
-
-	symbol = strings.ToUpper(symbol)
-
-	timeframe := "3M"
-	if tf, exists := args["timeframe"]; exists {
-		if tfStr, ok := tf.(string); ok {
-			timeframe = tfStr
-		}
-	}
-
-	analysis, err := s.enhancedAnalyzer.AnalyzeStockWithReliability(symbol, timeframe)
+	analysis, err := s.enhancedAnalyzer.AnalyzeStockWithReliability(ctx, symbol, in.Timeframe)
 	if err != nil {
-		return &models.CallToolResponse{
-			Content: []models.Content{
-				{Type: "text", Text: fmt.Sprintf("Error analyzing trends for %s: %v", symbol, err)},
-			},
-			IsError: true,
-		}, nil
+		return "", fmt.Errorf("Error analyzing trends for %s: %v", symbol, err)
 	}
+	// The analysis has already accepted the timeframe.
+	frame, _ := stock.ParseTimeframe(in.Timeframe)
 
-	response := s.formatHistoricalTrends(analysis)
-	
-	return &models.CallToolResponse{
-		Content: []models.Content{
-			{Type: "text", Text: response},
-		},
-	}, nil
+	return s.formatHistoricalTrends(analysis, frame), nil
 }
 
 func formatNumber(num int64) string {
@@ -486,9 +654,19 @@ func (s *StockAnalyzerServer) Run() error {
 	return s.server.Run()
 }
 
-// RunOnPort starts the server listening on a TCP port
-func (s *StockAnalyzerServer) RunOnPort(port int) error {
-	return s.server.RunOnPort(port)
+// Shutdown drains in-flight calls and closes every connection
+func (s *StockAnalyzerServer) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// ListenAndServe serves tcp:// on address, or tls:// when tlsConfig is set
+func (s *StockAnalyzerServer) ListenAndServe(address string, tlsConfig *tls.Config) error {
+	return s.server.ListenAndServe(address, tlsConfig)
+}
+
+// RunHTTP serves the Streamable HTTP transport at address
+func (s *StockAnalyzerServer) RunHTTP(address string) error {
+	return s.server.RunHTTP(address)
 }
 
 
@@ -498,10 +676,7 @@ func (s *StockAnalyzerServer) formatEnhancedStockAnalysis(analysis *models.Stock
 	sb.WriteString(fmt.Sprintf("🚀 ENHANCED STOCK ANALYSIS: %s\n", analysis.Stock.Symbol))
 	sb.WriteString("=" + strings.Repeat("=", 40) + "\n")
 	
-	apiKey := os.Getenv("ALPHA_VANTAGE_API_KEY")
-	if apiKey == "" || apiKey == "demo" {
-		sb.WriteString("DEMO DATA - Set ALPHA_VANTAGE_API_KEY for real-time data\n")
-	}
+	sb.WriteString(s.dataNotice)
 	sb.WriteString("\n")
 
 	sb.WriteString(fmt.Sprintf("Current Price: $%.2f\n", analysis.Stock.Price))
@@ -562,10 +737,7 @@ func (s *StockAnalyzerServer) formatEnhancedPortfolioAnalysis(analyses []*models
 	sb.WriteString("ENHANCED PORTFOLIO ANALYSIS\n")
 	sb.WriteString("=" + strings.Repeat("=", 45) + "\n")
 	
-	apiKey := os.Getenv("ALPHA_VANTAGE_API_KEY")
-	if apiKey == "" || apiKey == "demo" {
-		sb.WriteString("DEMO DATA - Set ALPHA_VANTAGE_API_KEY for real-time data\n")
-	}
+	sb.WriteString(s.dataNotice)
 	sb.WriteString("\n")
 
 	totalValue := 0.0
@@ -646,10 +818,7 @@ func (s *StockAnalyzerServer) formatPricePrediction(analysis *models.StockAnalys
 	sb.WriteString(fmt.Sprintf("PRICE PREDICTION: %s\n", analysis.Stock.Symbol))
 	sb.WriteString("=" + strings.Repeat("=", 30) + "\n")
 	
-	apiKey := os.Getenv("ALPHA_VANTAGE_API_KEY")
-	if apiKey == "" || apiKey == "demo" {
-		sb.WriteString("DEMO DATA - Set ALPHA_VANTAGE_API_KEY for real-time data\n")
-	}
+	sb.WriteString(s.dataNotice)
 	sb.WriteString("\n")
 
 	sb.WriteString(fmt.Sprintf("Current Price: $%.2f\n", analysis.Stock.Price))
@@ -691,16 +860,15 @@ func (s *StockAnalyzerServer) formatPricePrediction(analysis *models.StockAnalys
 	return sb.String()
 }
 
-func (s *StockAnalyzerServer) formatHistoricalTrends(analysis *models.StockAnalysis) string {
+// formatHistoricalTrends labels the moving averages in bars of frame, which
+// are days only for the daily timeframes.
+func (s *StockAnalyzerServer) formatHistoricalTrends(analysis *models.StockAnalysis, frame stock.Timeframe) string {
 	var sb strings.Builder
 	
 	sb.WriteString(fmt.Sprintf("TREND ANALYSIS: %s\n", analysis.Stock.Symbol))
 	sb.WriteString("=" + strings.Repeat("=", 35) + "\n")
 	
-	apiKey := os.Getenv("ALPHA_VANTAGE_API_KEY")
-	if apiKey == "" || apiKey == "demo" {
-		sb.WriteString("DEMO DATA - Set ALPHA_VANTAGE_API_KEY for real-time data\n")
-	}
+	sb.WriteString(s.dataNotice)
 	sb.WriteString("\n")
 
 	sb.WriteString("TREND SUMMARY:\n")
@@ -710,16 +878,17 @@ func (s *StockAnalyzerServer) formatHistoricalTrends(analysis *models.StockAnaly
 
 	sb.WriteString("MOVING AVERAGE TRENDS:\n")
 	if analysis.TechnicalIndicators.SMA20 > 0 && analysis.TechnicalIndicators.SMA50 > 0 {
+		bars := frame.BarName()
 		if analysis.Stock.Price > analysis.TechnicalIndicators.SMA20 {
-			sb.WriteString("  Short-term (20-day): 🟢 BULLISH\n")
+			sb.WriteString(fmt.Sprintf("  Short-term (20 %s bars): 🟢 BULLISH\n", bars))
 		} else {
-			sb.WriteString("  Short-term (20-day): BEARISH\n")
+			sb.WriteString(fmt.Sprintf("  Short-term (20 %s bars): BEARISH\n", bars))
 		}
 		
 		if analysis.TechnicalIndicators.SMA20 > analysis.TechnicalIndicators.SMA50 {
-			sb.WriteString("  Medium-term trend: 🟢 UPTREND\n")
+			sb.WriteString(fmt.Sprintf("  Medium-term (20 vs 50 %s bars): 🟢 UPTREND\n", bars))
 		} else {
-			sb.WriteString("  Medium-term trend: DOWNTREND\n")
+			sb.WriteString(fmt.Sprintf("  Medium-term (20 vs 50 %s bars): DOWNTREND\n", bars))
 		}
 	}
 	sb.WriteString("\n")
@@ -757,27 +926,196 @@ func min(a, b int) int {
 
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	configPath := flag.String("config", config.DefaultPath, "Path to the YAML configuration file")
+	flag.Duration("watch-interval", 0, "How often subscribed quotes are re-fetched (overrides server.watchInterval)")
+	flag.Int("port", 0, "Listen on this TCP port instead of stdin/stdout (overrides server.port)")
+	flag.String("http", "", "Serve Streamable HTTP at this address, e.g. :8080 (endpoint /mcp)")
+	flag.String("listen", "", "Serve at tcp://host:port or tls://host:port (overrides server.host/port/transport)")
+	flag.String("tls-cert", "", "Server certificate (PEM) for tls:// (overrides server.tls.certFile)")
+	flag.String("tls-key", "", "Server private key (PEM) for tls:// (overrides server.tls.keyFile)")
+	flag.String("tls-client-ca", "", "CA bundle clients must present certificates from (overrides server.tls.clientCAFile)")
+	flag.String("data-source", "", "live, record:DIR, replay:DIR or synthetic[:SEED] (overrides apis.dataSource)")
+	flag.String("providers", "", "Comma-separated market data providers in fallback order, e.g. twelveData,csv (overrides apis.providers)")
+	flag.Bool("no-cache", false, "Fetch market data on every call (overrides apis.cache.enabled)")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	if err := applyFlags(cfg); err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	if err := config.ValidateServer(cfg); err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+
+	server := NewStockAnalyzerServer(cfg)
+	server.server.EnableSubscriptions(cfg.Server.WatchInterval)
+	if cfg.Server.Transport != "stdio" {
+		if err := configureAuth(server.server, cfg.Server.Auth); err != nil {
+			log.Fatalf("Auth error: %v", err)
+		}
+		server.server.SetLimits(limitsFromConfig(cfg.Server.Limits))
+	}
 	
-	server := NewStockAnalyzerServer()
-	
-	// Check for port argument
-	if len(os.Args) > 1 {
-		// Running with port argument: ./stock-analyzer 8080
-		portStr := os.Args[1]
-		port, err := strconv.Atoi(portStr)
+	address := net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port))
+	var serve func() error
+	switch cfg.Server.Transport {
+	case "tcp":
+		serve = func() error { return server.ListenAndServe(address, nil) }
+	case "tls":
+		tlsConfig, err := mcp.ServerTLSOptions{
+			CertFile:     cfg.Server.TLS.CertFile,
+			KeyFile:      cfg.Server.TLS.KeyFile,
+			ClientCAFile: cfg.Server.TLS.ClientCAFile,
+		}.Config()
 		if err != nil {
-			log.Fatalf("Invalid port number: %s", portStr)
+			log.Fatalf("TLS error: %v", err)
 		}
-		
-		log.Printf("Starting MCP server on port %d", port)
-		if err := server.RunOnPort(port); err != nil {
-			log.Fatalf("Server error: %v", err)
-		}
-	} else {
+		serve = func() error { return server.ListenAndServe(address, tlsConfig) }
+	case "http":
+		serve = func() error { return server.RunHTTP(address) }
+	default:
 		// Running in stdin/stdout mode (default)
 		log.Println("Starting MCP server in stdin/stdout mode")
-		if err := server.Run(); err != nil {
+		serve = server.Run
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() { errs <- serve() }()
+
+	select {
+	case err := <-errs:
+		if err != nil {
 			log.Fatalf("Server error: %v", err)
 		}
+	case <-ctx.Done():
+		stop()
+		log.Printf("Signal received, waiting up to %s for in-flight calls (signal again to exit now)", cfg.Server.ShutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Shutdown: %v", err)
+		}
+	}
+}
+
+// configureAuth turns on bearer-token authentication when tokens are
+// configured, appending decisions to the audit log file if one is given.
+func configureAuth(server *mcp.Server, auth models.ServerAuthConfig) error {
+	if auth.AuditLog != "" {
+		file, err := os.OpenFile(auth.AuditLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		server.SetAuditLog(file)
+		server.RegisterOnShutdown(func(ctx context.Context) error {
+			return file.Close()
+		})
+	}
+
+	if len(auth.Tokens) == 0 {
+		log.Println("Warning: no server.auth.tokens configured; any client can call every tool")
+		return nil
+	}
+
+	tokens := make(map[string]mcp.Identity, len(auth.Tokens))
+	for _, token := range auth.Tokens {
+		tokens[token.Token] = mcp.Identity{
+			Name:      token.Name,
+			Tools:     token.Tools,
+			Resources: token.Resources,
+			Prompts:   token.Prompts,
+		}
+	}
+	server.SetAuthenticator(mcp.NewTokenAuthenticator(tokens))
+	log.Printf("Authentication enabled for %d client token(s)", len(tokens))
+	return nil
+}
+
+func limitsFromConfig(cfg models.LimitsConfig) mcp.Limits {
+	limits := mcp.Limits{
+		MaxConnections:    cfg.MaxConnections,
+		RequestsPerMinute: cfg.RequestsPerMinute,
+		Burst:             cfg.Burst,
+		ToolQuotas:        make(map[string]mcp.Quota, len(cfg.ToolQuotas)),
+	}
+	for tool, quota := range cfg.ToolQuotas {
+		limits.ToolQuotas[tool] = mcp.Quota{Calls: quota.Calls, Per: quota.Per}
 	}
-}
\ No newline at end of file
+	return limits
+}
+
+// applyFlags layers explicitly given flags over the configuration. A port,
+// either -port or the legacy positional argument (./stock-analyzer 8080),
+// switches the server to TCP on server.host.
+func applyFlags(cfg *models.Config) error {
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch f.Name {
+		case "watch-interval":
+			cfg.Server.WatchInterval = f.Value.(flag.Getter).Get().(time.Duration)
+		case "port":
+			cfg.Server.Port = f.Value.(flag.Getter).Get().(int)
+			cfg.Server.Transport = "tcp"
+		case "http":
+			err = setListenAddress(cfg, "http", f.Value.String())
+		case "listen":
+			scheme, address, found := strings.Cut(f.Value.String(), "://")
+			if !found || (scheme != "tcp" && scheme != "tls") {
+				err = fmt.Errorf("invalid -listen %q: use tcp://host:port or tls://host:port", f.Value.String())
+				return
+			}
+			err = setListenAddress(cfg, scheme, address)
+		case "tls-cert":
+			cfg.Server.TLS.CertFile = f.Value.String()
+		case "tls-key":
+			cfg.Server.TLS.KeyFile = f.Value.String()
+		case "tls-client-ca":
+			cfg.Server.TLS.ClientCAFile = f.Value.String()
+		case "data-source":
+			cfg.APIs.DataSource = f.Value.String()
+		case "providers":
+			cfg.APIs.Providers = config.SplitList(f.Value.String())
+		case "no-cache":
+			cfg.APIs.Cache.Enabled = !f.Value.(flag.Getter).Get().(bool)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if flag.NArg() > 0 {
+		portStr := flag.Arg(0)
+		cfg.Server.Port, err = strconv.Atoi(portStr)
+		if err != nil {
+			return fmt.Errorf("invalid port number: %s", portStr)
+		}
+		cfg.Server.Transport = "tcp"
+	}
+	return nil
+}
+
+func setListenAddress(cfg *models.Config, transport, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid %s address %q: %w", transport, address, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid %s port: %s", transport, portStr)
+	}
+
+	cfg.Server.Host = host
+	cfg.Server.Port = port
+	cfg.Server.Transport = transport
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"proyecto-mcp-bolsa/internal/mcp"
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// registerPrompts publishes ready-made analysis prompts. Each one embeds the
+// stock:// resources it talks about so the model works from the same data
+// the tools use.
+func (s *StockAnalyzerServer) registerPrompts() {
+	s.server.RegisterPrompt(models.Prompt{
+		Name:        "explain_recommendation",
+		Description: "Explain the analyzer's recommendation for a stock in plain language",
+		Arguments: []models.PromptArgument{
+			{Name: "symbol", Description: "Stock symbol (e.g. AAPL)", Required: true},
+		},
+	}, mcp.PromptHandlerFunc(s.promptExplainRecommendation))
+
+	s.server.RegisterPrompt(models.Prompt{
+		Name:        "compare_stocks",
+		Description: "Compare several stocks side by side",
+		Arguments: []models.PromptArgument{
+			{Name: "symbols", Description: "Comma-separated symbols (e.g. AAPL,MSFT,GOOGL)", Required: true},
+		},
+	}, mcp.PromptHandlerFunc(s.promptCompareStocks))
+
+	s.server.RegisterPrompt(models.Prompt{
+		Name:        "risk_review",
+		Description: "Review the risk of a portfolio, optionally weighted",
+		Arguments: []models.PromptArgument{
+			{Name: "portfolio", Description: "Comma-separated symbols with optional weights (e.g. AAPL:40,MSFT:60)", Required: true},
+		},
+	}, mcp.PromptHandlerFunc(s.promptRiskReview))
+}
+
+func (s *StockAnalyzerServer) promptExplainRecommendation(ctx context.Context, args map[string]string) (*models.GetPromptResponse, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(args["symbol"]))
+
+	analysis, err := s.enhancedAnalyzer.AnalyzeStockWithReliability(ctx, symbol, "3M")
+	if err != nil {
+		return nil, fmt.Errorf("Error analyzing %s: %v", symbol, err)
+	}
+
+	messages, err := s.embedStockResources(ctx, []string{symbol}, "quote", "indicators")
+	if err != nil {
+		return nil, err
+	}
+
+	summary := fmt.Sprintf(`The analyzer rates %s as %s (score %.1f/100, reliability %.1f%%, %s confidence, %s risk).
+Reasons given:
+- %s
+
+Using the quote and indicators above, explain this recommendation to a non-expert investor. Point out which indicators support it, which ones contradict it, and what would have to change for the rating to flip.`,
+		symbol, analysis.Recommendation, analysis.Score, analysis.Reliability, analysis.Confidence, analysis.RiskLevel,
+		strings.Join(analysis.Reasons, "\n- "))
+
+	return &models.GetPromptResponse{
+		Description: fmt.Sprintf("Explain the recommendation for %s", symbol),
+		Messages:    append(messages, textMessage(summary)),
+	}, nil
+}
+
+func (s *StockAnalyzerServer) promptCompareStocks(ctx context.Context, args map[string]string) (*models.GetPromptResponse, error) {
+	symbols := upperSymbols(strings.Split(args["symbols"], ","))
+	if len(symbols) < 2 {
+		return nil, fmt.Errorf("compare_stocks needs at least two symbols")
+	}
+
+	messages, err := s.embedStockResources(ctx, symbols, "quote", "indicators")
+	if err != nil {
+		return nil, err
+	}
+
+	instruction := fmt.Sprintf(`Compare %s using the quotes and technical indicators above.
+Cover recent performance, momentum (RSI, MACD), trend (price versus SMA20/SMA50) and volatility. Finish with a short table ranking them from most to least attractive right now and one sentence justifying each rank.`,
+		strings.Join(symbols, ", "))
+
+	return &models.GetPromptResponse{
+		Description: fmt.Sprintf("Compare %s", strings.Join(symbols, ", ")),
+		Messages:    append(messages, textMessage(instruction)),
+	}, nil
+}
+
+func (s *StockAnalyzerServer) promptRiskReview(ctx context.Context, args map[string]string) (*models.GetPromptResponse, error) {
+	symbols, weights, err := parsePortfolio(args["portfolio"])
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.embedStockResources(ctx, symbols, "quote", "indicators")
+	if err != nil {
+		return nil, err
+	}
+
+	allocation := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		allocation[i] = fmt.Sprintf("- %s: %.1f%%", symbol, weights[i])
+	}
+
+	instruction := fmt.Sprintf(`Review the risk of this portfolio:
+%s
+
+Using the data above, assess concentration, volatility of each position weighted by allocation, positions with overbought or oversold readings, and how correlated the holdings are likely to be. End with concrete rebalancing suggestions.`,
+		strings.Join(allocation, "\n"))
+
+	return &models.GetPromptResponse{
+		Description: fmt.Sprintf("Risk review of %s", strings.Join(symbols, ", ")),
+		Messages:    append(messages, textMessage(instruction)),
+	}, nil
+}
+
+// embedStockResources embeds stock://SYMBOL/<kind> for every symbol and kind.
+// A symbol whose data cannot be fetched is noted instead of failing the whole
+// prompt, unless nothing at all could be fetched.
+func (s *StockAnalyzerServer) embedStockResources(ctx context.Context, symbols []string, kinds ...string) ([]models.PromptMessage, error) {
+	messages := make([]models.PromptMessage, 0, len(symbols)*len(kinds))
+	var failures []string
+
+	for _, symbol := range symbols {
+		for _, kind := range kinds {
+			uri := fmt.Sprintf("stock://%s/%s", symbol, kind)
+			content, err := s.server.EmbedResource(ctx, uri)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", uri, err))
+				continue
+			}
+			messages = append(messages, models.PromptMessage{Role: "user", Content: content})
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no market data available: %s", strings.Join(failures, "; "))
+	}
+
+	if len(failures) > 0 {
+		messages = append(messages, textMessage("Some data could not be fetched and is missing:\n- "+strings.Join(failures, "\n- ")))
+	}
+
+	return messages, nil
+}
+
+func textMessage(text string) models.PromptMessage {
+	return models.PromptMessage{
+		Role:    "user",
+		Content: models.Content{Type: "text", Text: text},
+	}
+}
+
+// parsePortfolio reads "AAPL:40,MSFT:60" or "AAPL,MSFT". Weights are
+// normalized to percentages; without weights the portfolio is equal-weighted.
+func parsePortfolio(spec string) ([]string, []float64, error) {
+	var symbols []string
+	var weights []float64
+	total := 0.0
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		symbol, rawWeight, hasWeight := strings.Cut(entry, ":")
+		weight := 1.0
+		if hasWeight {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(rawWeight), 64)
+			if err != nil || parsed < 0 {
+				return nil, nil, fmt.Errorf("invalid weight for %s: %s", symbol, rawWeight)
+			}
+			weight = parsed
+		}
+
+		symbols = append(symbols, strings.ToUpper(strings.TrimSpace(symbol)))
+		weights = append(weights, weight)
+		total += weight
+	}
+
+	if len(symbols) == 0 {
+		return nil, nil, fmt.Errorf("portfolio is empty")
+	}
+	if total == 0 {
+		return nil, nil, fmt.Errorf("portfolio weights add up to zero")
+	}
+
+	for i := range weights {
+		weights[i] = weights[i] / total * 100
+	}
+
+	return symbols, weights, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"proyecto-mcp-bolsa/internal/mcp"
+	"proyecto-mcp-bolsa/pkg/models"
+)
+
+// registerResources publishes raw market data as JSON so hosts can pull it
+// into context without going through the formatted tool reports.
+func (s *StockAnalyzerServer) registerResources() {
+	templates := []struct {
+		template models.ResourceTemplate
+		handler  mcp.ResourceHandlerFunc
+	}{
+		{
+			template: models.ResourceTemplate{
+				URITemplate: "stock://{symbol}/quote",
+				Name:        "Stock quote",
+				Description: "Latest quote for a symbol: price, change, volume",
+				MimeType:    "application/json",
+			},
+			handler: s.readQuote,
+		},
+		{
+			template: models.ResourceTemplate{
+				URITemplate: "stock://{symbol}/history/daily",
+				Name:        "Daily price history",
+				Description: "Daily OHLCV bars for a symbol, oldest first",
+				MimeType:    "application/json",
+			},
+			handler: s.readDailyHistory,
+		},
+		{
+			template: models.ResourceTemplate{
+				URITemplate: "stock://{symbol}/indicators",
+				Name:        "Technical indicators",
+				Description: "RSI, moving averages, MACD, volatility and Bollinger bands computed from daily closes",
+				MimeType:    "application/json",
+			},
+			handler: s.readIndicators,
+		},
+	}
+
+	for _, entry := range templates {
+		if err := s.server.RegisterResourceTemplate(entry.template, entry.handler); err != nil {
+			log.Fatalf("Failed to register resource %s: %v", entry.template.URITemplate, err)
+		}
+	}
+}
+
+func (s *StockAnalyzerServer) readQuote(ctx context.Context, uri string, params map[string]string) ([]models.ResourceContents, error) {
+	quote, err := s.marketData.GetQuote(ctx, strings.ToUpper(params["symbol"]))
+	if err != nil {
+		return nil, err
+	}
+	return mcp.JSONResource(uri, quote)
+}
+
+func (s *StockAnalyzerServer) readDailyHistory(ctx context.Context, uri string, params map[string]string) ([]models.ResourceContents, error) {
+	symbol := strings.ToUpper(params["symbol"])
+
+	bars, err := s.marketData.GetDailyBars(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.JSONResource(uri, map[string]interface{}{
+		"symbol":   symbol,
+		"interval": "daily",
+		"bars":     bars,
+	})
+}
+
+func (s *StockAnalyzerServer) readIndicators(ctx context.Context, uri string, params map[string]string) ([]models.ResourceContents, error) {
+	indicators, err := s.analyzer.GetTechnicalIndicators(ctx, strings.ToUpper(params["symbol"]), "3M")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.JSONResource(uri, indicators)
+}